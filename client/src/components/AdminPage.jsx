@@ -6,6 +6,13 @@ const API_URL = import.meta.env.VITE_API_URL || 'http://localhost:3000';
 function AdminPage() {
   const [message, setMessage] = useState('');
   const [loading, setLoading] = useState(false);
+  // Kept for the tab's lifetime only
+  const [apiKey, setApiKey] = useState(() => sessionStorage.getItem('adminKey') || '');
+
+  const updateApiKey = (value) => {
+    setApiKey(value);
+    sessionStorage.setItem('adminKey', value);
+  };
 
   const clearCache = async () => {
     if (!confirm('Are you sure you want to clear the FastF1 cache? This will force re-downloading of all race data.')) {
@@ -16,7 +23,10 @@ function AdminPage() {
     setMessage('');
     
     try {
-      const response = await axios.post(`${API_URL}/api/clear-cache`);
+      // The key goes in a header, which other sites can't make the browser send
+      const response = await axios.post(`${API_URL}/api/clear-cache`, null, {
+        headers: { Authorization: `Bearer ${apiKey}` },
+      });
       setMessage(`✅ ${response.data.message || 'Cache cleared successfully'}`);
     } catch (err) {
       console.error("Error clearing cache:", err);
@@ -34,6 +44,19 @@ function AdminPage() {
           Use this page to manage the F1 Analytics backend services.
         </p>
 
+        <div className="admin-section">
+          <h2>API Key</h2>
+          <p>Admin actions need one of the server's ADMIN_KEYS.</p>
+          <input
+            type="password"
+            className="admin-input"
+            value={apiKey}
+            onChange={(e) => updateApiKey(e.target.value)}
+            placeholder="Admin API key"
+            autoComplete="off"
+          />
+        </div>
+
         <div className="admin-section">
           <h2>Cache Management</h2>
           <p>Clear the FastF1 cache if race data appears corrupted or incomplete.</p>
@@ -41,7 +64,7 @@ function AdminPage() {
           <button 
             className="admin-btn danger"
             onClick={clearCache}
-            disabled={loading}
+            disabled={loading || !apiKey}
           >
             {loading ? 'Clearing Cache...' : 'Clear FastF1 Cache'}
           </button>
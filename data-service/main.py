@@ -1,8 +1,10 @@
-from fastapi import FastAPI, Response
+from fastapi import FastAPI, Request, Response
 import fastf1
 import pandas as pd
 import os
 import gc
+import logging
+import time
 
 # Enable FastF1 cache (file-based only, no in-memory cache for Railway's limited RAM)
 cache_dir = os.path.join(os.path.dirname(__file__), '.fastf1_cache')
@@ -30,6 +32,20 @@ app.add_middleware(
     allow_headers=["*"],
 )
 
+logging.basicConfig(level=logging.INFO)
+logger = logging.getLogger("data-service")
+
+@app.middleware("http")
+async def log_requests(request: Request, call_next):
+    # The Go gateway sends its X-Request-Id so requests can be traced end to end
+    request_id = request.headers.get("x-request-id", "-")
+    start = time.monotonic()
+    response = await call_next(request)
+    logger.info("request_id=%s %s %s %d %.0fms", request_id, request.method,
+                request.url.path, response.status_code, (time.monotonic() - start) * 1000)
+    response.headers["X-Request-Id"] = request_id
+    return response
+
 @app.get("/")
 def read_root():
     return {"message": "F1 Data Service"}
@@ -216,6 +232,184 @@ def get_race_data(year: int, race_name: str, response: Response):
         print(f"Error in get_race_data: {error_detail}")
         return error_detail
 
+def format_time(val):
+    """Formats a timedelta as FastF1 prints it, without the "0 days" prefix."""
+    return str(val).replace("0 days ", "") if pd.notnull(val) else ""
+
+def load_weekend_session(year: int, race_name: str, session_type: str):
+    """Loads one session of a race weekend by round number or name. Returns
+    None if the weekend doesn't have that session."""
+    identifier = int(race_name) if race_name.isdigit() else race_name
+    try:
+        session = fastf1.get_session(year, identifier, session_type)
+    except ValueError:
+        return None
+    session.load(telemetry=False, weather=False, messages=False)
+    return session
+
+def session_error(response: Response, handler: str, e: Exception):
+    """Answers an unexpected failure with a 500 naming the error. The traceback
+    is logged rather than sent to the client."""
+    logger.exception("%s failed", handler)
+    response.status_code = 500
+    return {"error": str(e), "type": type(e).__name__}
+
+@app.get("/api/qualifying/{year}/{race_name}")
+def get_qualifying_data(year: int, race_name: str, response: Response):
+    try:
+        session = load_weekend_session(year, race_name, 'Q')
+        if session is None:
+            response.status_code = 404
+            return {"error": f"No qualifying session for {race_name} in {year}"}
+
+        results_list = []
+        for _, row in session.results.iterrows():
+            results_list.append({
+                "Position": row['Position'] if pd.notna(row['Position']) else None,
+                "Abbreviation": row['Abbreviation'],
+                "TeamName": row['TeamName'],
+                "Q1": format_time(row['Q1']),
+                "Q2": format_time(row['Q2']),
+                "Q3": format_time(row['Q3']),
+            })
+
+        result = {
+            "race_name": session.event['EventName'],
+            "session_date": session.date.isoformat() if pd.notnull(session.date) else None,
+            "results": results_list
+        }
+        del session
+        gc.collect()
+        # Only a successful load is final; errors may be retried
+        response.headers["Cache-Control"] = "public, max-age=86400, immutable"  # 24 hours
+        return result
+    except Exception as e:
+        return session_error(response, "get_qualifying_data", e)
+
+@app.get("/api/sprint/{year}/{race_name}")
+def get_sprint_data(year: int, race_name: str, response: Response):
+    try:
+        session = load_weekend_session(year, race_name, 'S')
+        if session is None:
+            response.status_code = 404
+            return {"error": f"No sprint at {race_name} in {year}"}
+
+        results_list = []
+        for _, row in session.results.iterrows():
+            results_list.append({
+                "Position": row['Position'] if pd.notna(row['Position']) else None,
+                "Abbreviation": row['Abbreviation'],
+                "TeamName": row['TeamName'],
+                "Status": row['Status'],
+                "GridPosition": row['GridPosition'] if pd.notna(row['GridPosition']) else None,
+                "Points": row['Points'] if pd.notna(row['Points']) else 0,
+                "Time": format_time(row['Time']),
+            })
+
+        result = {
+            "race_name": session.event['EventName'],
+            "session_date": session.date.isoformat() if pd.notnull(session.date) else None,
+            "results": results_list
+        }
+        del session
+        gc.collect()
+        response.headers["Cache-Control"] = "public, max-age=86400, immutable"  # 24 hours
+        return result
+    except Exception as e:
+        return session_error(response, "get_sprint_data", e)
+
+@app.get("/api/laps/{year}/{race_name}/{driver}")
+def get_driver_laps(year: int, race_name: str, driver: str, response: Response):
+    """Every lap a driver completed in a race, by abbreviation (VER) or car
+    number. The gateway pages and trims the list."""
+    try:
+        session = load_weekend_session(year, race_name, 'R')
+        if session is None:
+            response.status_code = 404
+            return {"error": f"No race for {race_name} in {year}"}
+
+        laps = session.laps.pick_drivers(driver.upper())
+        if laps.empty:
+            response.status_code = 404
+            return {"error": f"No laps for {driver} at {race_name} in {year}"}
+
+        laps_list = []
+        for _, lap in laps.sort_values('LapNumber').iterrows():
+            laps_list.append({
+                "LapNumber": int(lap['LapNumber']),
+                "LapTime": format_time(lap['LapTime']),
+                "Sector1Time": format_time(lap['Sector1Time']),
+                "Sector2Time": format_time(lap['Sector2Time']),
+                "Sector3Time": format_time(lap['Sector3Time']),
+                "Compound": lap['Compound'] if pd.notnull(lap['Compound']) else None,
+                "TyreLife": int(lap['TyreLife']) if pd.notnull(lap['TyreLife']) else None,
+                "Stint": int(lap['Stint']) if pd.notnull(lap['Stint']) else None,
+                "Position": int(lap['Position']) if pd.notnull(lap['Position']) else None,
+                "PitIn": pd.notnull(lap['PitInTime']),
+                "PitOut": pd.notnull(lap['PitOutTime']),
+            })
+
+        result = {
+            "race_name": session.event['EventName'],
+            "driver": laps.iloc[0]['Driver'],
+            "laps": laps_list
+        }
+        del session, laps
+        gc.collect()
+        response.headers["Cache-Control"] = "public, max-age=86400, immutable"  # 24 hours
+        return result
+    except Exception as e:
+        return session_error(response, "get_driver_laps", e)
+
+WEATHER_SESSIONS = {"FP1", "FP2", "FP3", "SQ", "S", "Q", "R"}
+
+@app.get("/api/weather/{year}/{race_name}")
+def get_weather(year: int, race_name: str, response: Response, session: str = "R"):
+    """Weather samples (about one a minute) over a session's timeline. Time is
+    seconds from the start of the session's data, as lap times are counted."""
+    session_type = session.upper()
+    if session_type not in WEATHER_SESSIONS:
+        response.status_code = 400
+        return {"error": f"session must be one of {', '.join(sorted(WEATHER_SESSIONS))}"}
+    try:
+        identifier = int(race_name) if race_name.isdigit() else race_name
+        try:
+            weekend_session = fastf1.get_session(year, identifier, session_type)
+        except ValueError:
+            response.status_code = 404
+            return {"error": f"No {session_type} session for {race_name} in {year}"}
+        weekend_session.load(laps=False, telemetry=False, weather=True, messages=False)
+        weather = weekend_session.weather_data
+        if weather is None or weather.empty:
+            response.status_code = 404
+            return {"error": f"No weather data for {race_name} in {year}"}
+
+        samples = []
+        for _, row in weather.iterrows():
+            samples.append({
+                "Time": round(row['Time'].total_seconds(), 3),
+                "AirTemp": float(row['AirTemp']) if pd.notnull(row['AirTemp']) else None,
+                "TrackTemp": float(row['TrackTemp']) if pd.notnull(row['TrackTemp']) else None,
+                "Humidity": float(row['Humidity']) if pd.notnull(row['Humidity']) else None,
+                "Pressure": float(row['Pressure']) if pd.notnull(row['Pressure']) else None,
+                "Rainfall": bool(row['Rainfall']) if pd.notnull(row['Rainfall']) else None,
+                "WindSpeed": float(row['WindSpeed']) if pd.notnull(row['WindSpeed']) else None,
+                "WindDirection": int(row['WindDirection']) if pd.notnull(row['WindDirection']) else None,
+            })
+
+        result = {
+            "race_name": weekend_session.event['EventName'],
+            "session": session_type,
+            "session_date": weekend_session.date.isoformat() if pd.notnull(weekend_session.date) else None,
+            "samples": samples
+        }
+        del weekend_session, weather
+        gc.collect()
+        response.headers["Cache-Control"] = "public, max-age=86400, immutable"  # 24 hours
+        return result
+    except Exception as e:
+        return session_error(response, "get_weather", e)
+
 @app.get("/api/analytics/{year}/{race_name}")
 def get_race_analytics(year: int, race_name: str, response: Response):
     # Set cache headers - analytics are historical data
@@ -0,0 +1,302 @@
+// Package abuse spots clients probing the API and bans them temporarily.
+package abuse
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/ekjyotshinh/f1-server/jsoncodec"
+	"github.com/ekjyotshinh/f1-server/respcache"
+	"github.com/gin-gonic/gin"
+)
+
+// Policy decides when a client is banned. A client is banned for Ban after
+// NotFound requests to nonexistent routes, or TooMany requests answered with
+// 429 (i.e. it keeps going after being told to slow down), within Window.
+// A zero threshold disables that check.
+type Policy struct {
+	Window   time.Duration
+	NotFound int
+	TooMany  int
+	Ban      time.Duration
+}
+
+// Ban is a client currently locked out.
+type Ban struct {
+	IP     string    `json:"ip"`
+	Reason string    `json:"reason"`
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until"`
+}
+
+type strikes struct {
+	start    time.Time
+	notFound int
+	tooMany  int
+}
+
+// bansKey is where the active bans are kept in the store, as one list. The
+// store must not be the response cache's own backend, or clearing the cache
+// would lift every ban; see respcache.Separate.
+const bansKey = "abuse:bans"
+
+// storeTimeout bounds each store call made while answering a request.
+const storeTimeout = 2 * time.Second
+
+// Detector tracks strikes per client IP. Bans are written through to a
+// response cache backend and read back by Sweep, so that with Redis they
+// survive restarts and apply on every replica. Two replicas banning at the
+// same moment can lose one of the bans; the client is banned again on its
+// next strikes.
+type Detector struct {
+	store respcache.Backend // nil keeps bans in this process only
+
+	mu      sync.Mutex
+	policy  Policy
+	strikes map[string]*strikes
+	bans    map[string]Ban
+	now     func() time.Time
+}
+
+// New creates a Detector enforcing p, keeping bans in store when it isn't
+// nil.
+func New(p Policy, store respcache.Backend) *Detector {
+	return &Detector{
+		store:   store,
+		policy:  p,
+		strikes: make(map[string]*strikes),
+		bans:    make(map[string]Ban),
+		now:     time.Now,
+	}
+}
+
+// Middleware rejects banned clients and counts strikes against everyone else.
+// Requests with no client IP, such as those over a Unix socket, are let
+// through: banning "" would lock out every client at once.
+func (d *Detector) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		if ip == "" {
+			c.Next()
+			return
+		}
+		if ban, ok := d.Banned(ip); ok {
+			c.Header("Retry-After", strconv.Itoa(int(time.Until(ban.Until).Seconds())+1))
+			apierror.Banned.Abort(c, "Temporarily banned")
+			return
+		}
+		c.Next()
+		// A 404 from a route that exists is a season or driver the
+		// upstream doesn't have, not a scan
+		if c.Writer.Status() == http.StatusNotFound && c.FullPath() != "" {
+			return
+		}
+		d.Observe(ip, c.Writer.Status())
+	}
+}
+
+// Observe records a response sent to ip and bans it if it crossed a threshold.
+// A 404 is taken to be for a nonexistent route.
+func (d *Detector) Observe(ip string, status int) {
+	if status != http.StatusNotFound && status != http.StatusTooManyRequests {
+		return
+	}
+
+	d.mu.Lock()
+	now := d.now()
+	s, ok := d.strikes[ip]
+	if !ok || now.Sub(s.start) > d.policy.Window {
+		s = &strikes{start: now}
+		d.strikes[ip] = s
+	}
+
+	var reason string
+	switch status {
+	case http.StatusNotFound:
+		s.notFound++
+		if d.policy.NotFound > 0 && s.notFound >= d.policy.NotFound {
+			reason = "scanning nonexistent routes"
+		}
+	case http.StatusTooManyRequests:
+		s.tooMany++
+		if d.policy.TooMany > 0 && s.tooMany >= d.policy.TooMany {
+			reason = "ignoring rate limits"
+		}
+	}
+	if reason == "" {
+		d.mu.Unlock()
+		return
+	}
+	ban := Ban{IP: ip, Reason: reason, Since: now, Until: now.Add(d.policy.Ban)}
+	d.bans[ip] = ban
+	delete(d.strikes, ip)
+	d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	d.update(ctx, func(bans map[string]Ban) bool {
+		bans[ip] = ban
+		return true
+	})
+}
+
+// Banned reports whether ip is currently banned.
+func (d *Detector) Banned(ip string) (Ban, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ban, ok := d.bans[ip]
+	if ok && !d.now().Before(ban.Until) {
+		delete(d.bans, ip)
+		return Ban{}, false
+	}
+	return ban, ok
+}
+
+// Bans lists active bans, most recent first.
+func (d *Detector) Bans() []Ban {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	out := make([]Ban, 0, len(d.bans))
+	for ip, ban := range d.bans {
+		if !now.Before(ban.Until) {
+			delete(d.bans, ip)
+			continue
+		}
+		out = append(out, ban)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Since.After(out[j].Since) })
+	return out
+}
+
+// Clear lifts the ban on ip, or every ban when ip is empty. It reports
+// whether anything was cleared.
+func (d *Detector) Clear(ctx context.Context, ip string) bool {
+	d.mu.Lock()
+	delete(d.strikes, ip)
+	d.mu.Unlock()
+
+	return d.update(ctx, func(bans map[string]Ban) bool {
+		if ip == "" {
+			n := len(bans)
+			clear(bans)
+			return n > 0
+		}
+		_, ok := bans[ip]
+		delete(bans, ip)
+		return ok
+	})
+}
+
+// Sweep forgets strikes whose window has passed and bans that have
+// expired, so clients seen once don't stay in memory, then takes the bans
+// from the store, picking up those of other replicas. Bans the store has
+// lost, e.g. to eviction or a cache purge, are written back.
+func (d *Detector) Sweep(ctx context.Context) error {
+	d.mu.Lock()
+	now := d.now()
+	for ip, s := range d.strikes {
+		if now.Sub(s.start) > d.policy.Window {
+			delete(d.strikes, ip)
+		}
+	}
+	for ip, ban := range d.bans {
+		if !now.Before(ban.Until) {
+			delete(d.bans, ip)
+		}
+	}
+	d.mu.Unlock()
+
+	if d.store == nil {
+		return nil
+	}
+	if stored, ok := d.load(ctx); ok {
+		d.mu.Lock()
+		d.bans = stored
+		d.mu.Unlock()
+		return nil
+	}
+	d.mu.Lock()
+	local := make(map[string]Ban, len(d.bans))
+	for ip, ban := range d.bans {
+		local[ip] = ban
+	}
+	d.mu.Unlock()
+	if len(local) > 0 {
+		d.save(ctx, local)
+	}
+	return ctx.Err()
+}
+
+// update applies change to the active bans, starting from the store's when
+// it has them, and writes the result back. change reports whether it
+// changed anything.
+func (d *Detector) update(ctx context.Context, change func(bans map[string]Ban) bool) bool {
+	bans, ok := d.load(ctx)
+	if !ok {
+		d.mu.Lock()
+		bans = make(map[string]Ban, len(d.bans))
+		for ip, ban := range d.bans {
+			bans[ip] = ban
+		}
+		d.mu.Unlock()
+	}
+	changed := change(bans)
+	d.mu.Lock()
+	d.bans = bans
+	d.mu.Unlock()
+	if changed {
+		d.save(ctx, bans)
+	}
+	return changed
+}
+
+// load reads the unexpired bans from the store.
+func (d *Detector) load(ctx context.Context) (map[string]Ban, bool) {
+	if d.store == nil {
+		return nil, false
+	}
+	entry, ok := d.store.Load(ctx, bansKey)
+	if !ok {
+		return nil, false
+	}
+	var list []Ban
+	if err := jsoncodec.Default.Unmarshal(entry.Body, &list); err != nil {
+		return nil, false
+	}
+	now := d.now()
+	bans := make(map[string]Ban, len(list))
+	for _, ban := range list {
+		if now.Before(ban.Until) {
+			bans[ban.IP] = ban
+		}
+	}
+	return bans, true
+}
+
+// save writes bans to the store, kept until the last of them ends. An empty
+// list is kept too, for a while, so other replicas see bans lifted.
+func (d *Detector) save(ctx context.Context, bans map[string]Ban) {
+	if d.store == nil {
+		return
+	}
+	list := make([]Ban, 0, len(bans))
+	keep := d.policy.Ban
+	now := d.now()
+	for _, ban := range bans {
+		list = append(list, ban)
+		keep = max(keep, ban.Until.Sub(now))
+	}
+	body, err := jsoncodec.Default.Marshal(list)
+	if err != nil {
+		return
+	}
+	d.store.Save(ctx, bansKey, respcache.Entry{Body: body, Stored: now}, keep)
+}
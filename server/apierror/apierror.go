@@ -0,0 +1,118 @@
+// Package apierror defines the machine-readable error codes the API returns.
+// Every error response is a JSON object with a human-readable "error", one of
+// these codes in "code" and the request's "request_id"; /api/errors publishes
+// the catalog.
+package apierror
+
+import "github.com/gin-gonic/gin"
+
+// Code is one kind of error. Status is the HTTP status it is normally sent
+// with; upstream errors pass the data service's status through instead.
+type Code struct {
+	Code        string `json:"code"`
+	Status      int    `json:"status"`
+	Description string `json:"description"`
+}
+
+var catalog []Code
+
+func define(code string, status int, description string) Code {
+	c := Code{Code: code, Status: status, Description: description}
+	catalog = append(catalog, c)
+	return c
+}
+
+var (
+	InvalidParameter = define("invalid_parameter", 400,
+		"A path or query parameter is malformed or out of range.")
+	InvalidCalendar = define("invalid_calendar", 400,
+		"The uploaded calendar isn't an ICS file of at most 2 MB.")
+	NotFound = define("not_found", 404,
+		"No resource exists at this path.")
+	UnknownTeam = define("unknown_team", 404,
+		"The team is not in the lineage table.")
+	UnknownDriver = define("unknown_driver", 404,
+		"The driver has no rating yet.")
+	NotBanned = define("not_banned", 404,
+		"The IP address has no active ban.")
+	NotCached = define("not_cached", 404,
+		"The key has no entry in the response cache.")
+	NotQuarantined = define("not_quarantined", 404,
+		"The key has no response held in quarantine.")
+	DemoUnavailable = define("demo_unavailable", 404,
+		"The route or race isn't part of the demo data set.")
+	Unauthenticated = define("invalid_signature", 401,
+		"A signed request had an unknown client, stale timestamp, bad signature or was replayed.")
+	AdminUnauthenticated = define("admin_unauthenticated", 401,
+		"An admin route was called without an API key; send Authorization: Bearer <key> or X-API-Key.")
+	AdminForbidden = define("admin_forbidden", 403,
+		"The API key isn't one of the configured admin keys.")
+	CSRFRejected = define("csrf_rejected", 403,
+		"A state-changing request relied on cookies; send the API key in Authorization or X-API-Key.")
+	Banned = define("banned", 403,
+		"The client is temporarily banned for abusive traffic; see Retry-After.")
+	JSONPTooLarge = define("jsonp_too_large", 413,
+		"The response is too large to deliver as JSONP; use CORS instead.")
+	RateLimited = define("rate_limited", 429,
+		"The client exceeded its request rate for this group of routes; see Retry-After.")
+	Internal = define("internal", 500,
+		"The gateway failed unexpectedly.")
+	UpstreamUnreachable = define("upstream_unreachable", 500,
+		"The FastF1 data service could not be reached.")
+	UpstreamError = define("upstream_error", 502,
+		"The FastF1 data service returned an error; its status code is passed through.")
+	UpstreamInvalid = define("upstream_invalid", 502,
+		"The FastF1 data service returned a body that isn't valid JSON.")
+	UpstreamSuspect = define("upstream_suspect", 502,
+		"The FastF1 data service returned a response that looks truncated; it was quarantined rather than served.")
+	UpstreamUnavailable = define("upstream_unavailable", 503,
+		"The FastF1 data service kept failing, so it isn't being called for now; see Retry-After.")
+	UpstreamBusy = define("upstream_busy", 503,
+		"Too many requests are already waiting on this FastF1 data service endpoint; see Retry-After.")
+	ExportFailed = define("export_failed", 502,
+		"Google Sheets rejected or failed the export; check the spreadsheet is shared with the service account.")
+	ExportUnavailable = define("export_unavailable", 503,
+		"Google Sheets export isn't configured on this server.")
+	HistoryUnavailable = define("history_unavailable", 502,
+		"The historical data provider (Jolpica) could not be reached or is rate limiting us.")
+)
+
+// Catalog lists every code in definition order.
+func Catalog() []Code {
+	return append([]Code(nil), catalog...)
+}
+
+// RequestIDKey is the context key of the request ID, which error bodies echo
+// so users can quote it when reporting a problem.
+const RequestIDKey = "request_id"
+
+// Error is an error response, recorded in the context's c.Errors so that
+// middleware can log and report it.
+type Error struct {
+	Code    Code
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Code.Code + ": " + e.Message
+}
+
+// Body is the JSON error object for message in response to c.
+func (e Code) Body(c *gin.Context, message string) gin.H {
+	c.Error(&Error{Code: e, Message: message})
+	body := gin.H{"error": message, "code": e.Code}
+	if id := c.GetString(RequestIDKey); id != "" {
+		body["request_id"] = id
+	}
+	return body
+}
+
+// Respond writes the error with the code's status.
+func (e Code) Respond(c *gin.Context, message string) {
+	c.JSON(e.Status, e.Body(c, message))
+}
+
+// Abort writes the error and stops the handler chain.
+func (e Code) Abort(c *gin.Context, message string) {
+	c.AbortWithStatusJSON(e.Status, e.Body(c, message))
+}
@@ -0,0 +1,120 @@
+// Package budget tracks calls to rate-limited upstreams over a rolling hour
+// and holds back background work so user requests always have headroom.
+package budget
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Priority says whether a caller can wait for budget to free up.
+type Priority int
+
+const (
+	// Urgent calls serve a user who is waiting; they are never deferred.
+	Urgent Priority = iota
+	// Background calls (refresh jobs, warming) are deferred near the limit.
+	Background
+)
+
+type priorityKey struct{}
+
+// WithPriority marks calls made with ctx as p. Unmarked calls are Urgent.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityOf returns the priority ctx was marked with.
+func PriorityOf(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityKey{}).(Priority)
+	return p
+}
+
+// ErrDeferred is returned to background callers when the remaining budget is
+// reserved for user requests.
+var ErrDeferred = errors.New("upstream budget reserved for user requests")
+
+// Budget is the hourly allowance of one upstream.
+type Budget struct {
+	name    string
+	limit   int
+	reserve float64
+
+	mu       sync.Mutex
+	calls    []time.Time // within the last hour, oldest first
+	deferred int
+	now      func() time.Time
+}
+
+// New creates a Budget allowing perHour calls, keeping the reserve fraction
+// of them for Urgent callers. perHour <= 0 tracks calls without a limit.
+func New(name string, perHour int, reserve float64) *Budget {
+	return &Budget{name: name, limit: perHour, reserve: reserve, now: time.Now}
+}
+
+// Take records a call about to be made with ctx, or returns ErrDeferred if
+// it is background work and the budget is down to its reserve. A nil Budget
+// allows everything.
+func (b *Budget) Take(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.prune(now)
+	if b.limit > 0 && PriorityOf(ctx) == Background &&
+		float64(len(b.calls)) >= float64(b.limit)*(1-b.reserve) {
+		b.deferred++
+		return ErrDeferred
+	}
+	b.calls = append(b.calls, now)
+	return nil
+}
+
+// Status is a snapshot of a Budget for the admin API.
+type Status struct {
+	Upstream  string     `json:"upstream"`
+	Limit     int        `json:"limit_per_hour"` // 0 when unlimited
+	Used      int        `json:"used"`
+	Remaining int        `json:"remaining"`
+	Reserved  int        `json:"reserved_for_users"`
+	Deferred  int        `json:"deferred_background_calls"`
+	ResetsAt  *time.Time `json:"next_release_at,omitempty"` // when the oldest call leaves the window
+}
+
+// Status reports usage over the last hour.
+func (b *Budget) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.prune(b.now())
+	st := Status{
+		Upstream: b.name,
+		Limit:    b.limit,
+		Used:     len(b.calls),
+		Deferred: b.deferred,
+	}
+	if b.limit > 0 {
+		st.Remaining = max(0, b.limit-st.Used)
+		st.Reserved = int(float64(b.limit) * b.reserve)
+	}
+	if len(b.calls) > 0 {
+		at := b.calls[0].Add(time.Hour)
+		st.ResetsAt = &at
+	}
+	return st
+}
+
+// prune forgets calls older than an hour. Callers hold b.mu.
+func (b *Budget) prune(now time.Time) {
+	cutoff := now.Add(-time.Hour)
+	i := 0
+	for i < len(b.calls) && !b.calls[i].After(cutoff) {
+		i++
+	}
+	b.calls = b.calls[i:]
+}
@@ -0,0 +1,290 @@
+// Command backfill builds a local archive of past seasons: it fetches every
+// race's datasets through the gateway, as clients would see them, and writes
+// them under an output directory with a sha256sum file beside each. Progress
+// is checkpointed after each dataset, so an interrupted or partly failed run
+// picks up where it left off when started again.
+//
+//	go run ./cmd/backfill -from 2018 -to 2024 -out archive
+//
+// With -verify it archives nothing and instead checks each archived dataset
+// against its checksum and against a fresh fetch, writing what differs to
+// verify.json.
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/config"
+	"github.com/ekjyotshinh/f1-server/server"
+)
+
+// datasets are the per-race routes that can be archived, by name.
+var datasets = map[string]string{
+	"race":      "/api/race/%d/%d",
+	"analytics": "/api/analytics/%d/%d",
+	"telemetry": "/api/telemetry/%d/%d",
+}
+
+func main() {
+	from := flag.Int("from", 2018, "first season")
+	to := flag.Int("to", time.Now().Year(), "last season")
+	out := flag.String("out", "archive", "directory to write the archive to")
+	names := flag.String("datasets", "race,analytics,telemetry", "datasets to fetch per race")
+	interval := flag.Duration("interval", 5*time.Second, "minimum time between data service fetches")
+	verify := flag.Bool("verify", false, "check the archive for corruption and drift instead of extending it")
+	flag.Parse()
+
+	for _, name := range strings.Split(*names, ",") {
+		if _, ok := datasets[name]; !ok {
+			log.Fatalf("Unknown dataset %q", name)
+		}
+	}
+
+	loaded, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	cfg := loaded.Server
+	// Only fetch; -interval does the pacing
+	cfg.StreaksRefresh = 0
+	cfg.RatingsRefresh = 0
+	cfg.WarmInterval = 0
+	cfg.ScheduleCheck = 0
+	cfg.ProbeInterval = 0
+	cfg.UpstreamDNSRefresh = 0
+	cfg.RateLimits = nil
+	srv, err := server.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to build server: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	b := &backfill{
+		handler:    srv,
+		out:        *out,
+		datasets:   strings.Split(*names, ","),
+		checkpoint: filepath.Join(*out, "checkpoint.json"),
+		pace:       time.NewTicker(*interval),
+	}
+	if err := b.load(); err != nil {
+		log.Fatalf("Failed to read checkpoint: %v", err)
+	}
+	if *verify {
+		problems, err := b.verify(ctx, *from, *to)
+		srv.Close()
+		if err != nil {
+			log.Fatalf("Verification failed: %v", err)
+		}
+		if problems > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	for year := *from; year <= *to && ctx.Err() == nil; year++ {
+		if err := b.season(ctx, year); err != nil {
+			log.Printf("%d: %v", year, err)
+		}
+	}
+	srv.Close()
+	log.Printf("Done: %d datasets archived, %d failed", len(b.state.Done), len(b.state.Failed))
+	if len(b.state.Failed) > 0 || ctx.Err() != nil {
+		os.Exit(1)
+	}
+}
+
+// state is the checkpoint file. Failed entries are retried on the next run.
+type state struct {
+	Done   map[string]bool   `json:"done"`
+	Failed map[string]string `json:"failed"`
+}
+
+type backfill struct {
+	handler    http.Handler
+	out        string
+	datasets   []string
+	checkpoint string
+	pace       *time.Ticker
+	fetched    bool
+	state      state
+}
+
+func (b *backfill) load() error {
+	b.state = state{Done: map[string]bool{}, Failed: map[string]string{}}
+	data, err := os.ReadFile(b.checkpoint)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &b.state)
+}
+
+func (b *backfill) save() error {
+	data, err := json.MarshalIndent(b.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(b.checkpoint, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// season archives the schedule of year and every race already run.
+func (b *backfill) season(ctx context.Context, year int) error {
+	path := filepath.Join(b.out, fmt.Sprint(year), "schedule.json")
+	if err := b.archive(ctx, fmt.Sprintf("/api/schedule/%d", year), path); err != nil {
+		return fmt.Errorf("schedule: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var events []struct {
+		RoundNumber int
+		EventDate   string
+	}
+	if err := json.Unmarshal(data, &events); err != nil {
+		return fmt.Errorf("schedule: %w", err)
+	}
+
+	for _, event := range events {
+		// Round 0 is pre-season testing
+		date, err := time.Parse("2006-01-02T15:04:05", event.EventDate)
+		if event.RoundNumber < 1 || err != nil || date.After(time.Now()) {
+			continue
+		}
+		for _, name := range b.datasets {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			key := fmt.Sprintf("%d/%d/%s", year, event.RoundNumber, name)
+			if b.state.Done[key] {
+				continue
+			}
+			route := fmt.Sprintf(datasets[name], year, event.RoundNumber)
+			err := b.archive(ctx, route, filepath.Join(b.out, key+".json"))
+			if err != nil {
+				log.Printf("%s: %v", key, err)
+				b.state.Failed[key] = err.Error()
+			} else {
+				log.Printf("%s: archived", key)
+				b.state.Done[key] = true
+				delete(b.state.Failed, key)
+			}
+			if err := b.save(); err != nil {
+				return fmt.Errorf("checkpoint: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// archive fetches route to path and records its checksum beside it.
+func (b *backfill) archive(ctx context.Context, route, path string) error {
+	h := sha256.New()
+	err := writeFile(path, func(w io.Writer) error {
+		return b.fetch(ctx, route, io.MultiWriter(w, h))
+	})
+	if err != nil {
+		return err
+	}
+	return writeFile(path+".sha256", func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "%x  %s\n", h.Sum(nil), filepath.Base(path))
+		return err
+	})
+}
+
+// fetch requests route from the gateway, after waiting for its turn, and
+// copies a successful body to w. The response cache is bypassed so the
+// body is what the data service says now.
+func (b *backfill) fetch(ctx context.Context, route string, w io.Writer) error {
+	if b.fetched {
+		select {
+		case <-b.pace.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	b.fetched = true
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, route, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Cache-Control", "no-cache")
+	rw := &responseWriter{header: http.Header{}, body: w}
+	b.handler.ServeHTTP(rw, req)
+	if rw.status != http.StatusOK {
+		return fmt.Errorf("%s: status %d", route, rw.status)
+	}
+	return rw.err
+}
+
+// writeFile writes path atomically, leaving any previous file alone if
+// write fails.
+func writeFile(path string, write func(w io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".backfill-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// responseWriter streams a response body to a file, so telemetry isn't held
+// in memory.
+type responseWriter struct {
+	header http.Header
+	status int
+	body   io.Writer
+	err    error
+}
+
+func (w *responseWriter) Header() http.Header { return w.header }
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.status != http.StatusOK {
+		return len(p), nil
+	}
+	n, err := w.body.Write(p)
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	return n, err
+}
+
+func (w *responseWriter) Flush() {}
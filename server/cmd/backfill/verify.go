@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// discrepancy is an archived dataset that no longer matches.
+type discrepancy struct {
+	Dataset string `json:"dataset"`
+	// Problem is "missing", "no_checksum", "corrupt" (the file doesn't match
+	// its checksum) or "drifted" (the data service now returns something
+	// else).
+	Problem string `json:"problem"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+type report struct {
+	CheckedAt     time.Time     `json:"checked_at"`
+	Checked       int           `json:"checked"`
+	Discrepancies []discrepancy `json:"discrepancies"`
+}
+
+// verify checks every archived dataset of the seasons from..to and writes
+// verify.json. It returns how many discrepancies it found.
+func (b *backfill) verify(ctx context.Context, from, to int) (int, error) {
+	var keys []string
+	for key := range b.state.Done {
+		var year, round int
+		var name string
+		if _, err := fmt.Sscanf(key, "%d/%d/%s", &year, &round, &name); err != nil {
+			continue
+		}
+		if year >= from && year <= to && slices.Contains(b.datasets, name) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	rep := report{CheckedAt: time.Now().UTC(), Discrepancies: []discrepancy{}}
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		rep.Checked++
+		if d, ok := b.check(ctx, key); !ok {
+			log.Printf("%s: %s %s", key, d.Problem, d.Detail)
+			rep.Discrepancies = append(rep.Discrepancies, d)
+		}
+	}
+	log.Printf("Verified %d datasets, %d discrepancies", rep.Checked, len(rep.Discrepancies))
+
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	err = writeFile(filepath.Join(b.out, "verify.json"), func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	return len(rep.Discrepancies), err
+}
+
+// check compares one dataset with its checksum, then with a fresh fetch.
+func (b *backfill) check(ctx context.Context, key string) (discrepancy, bool) {
+	path := filepath.Join(b.out, key+".json")
+	d := discrepancy{Dataset: key}
+
+	stored, err := hashFile(path)
+	if err != nil {
+		d.Problem, d.Detail = "missing", err.Error()
+		return d, false
+	}
+	sum, err := os.ReadFile(path + ".sha256")
+	if err != nil {
+		d.Problem, d.Detail = "no_checksum", err.Error()
+		return d, false
+	}
+	recorded, _, _ := bytes.Cut(sum, []byte(" "))
+	if string(recorded) != stored {
+		d.Problem, d.Detail = "corrupt", fmt.Sprintf("sha256 %s, recorded %s", stored, recorded)
+		return d, false
+	}
+
+	var year, round int
+	var name string
+	fmt.Sscanf(key, "%d/%d/%s", &year, &round, &name)
+	h := sha256.New()
+	if err := b.fetch(ctx, fmt.Sprintf(datasets[name], year, round), h); err != nil {
+		// Not a discrepancy in the archive; the next run checks again
+		log.Printf("%s: fresh fetch failed: %v", key, err)
+		return d, true
+	}
+	if fresh := fmt.Sprintf("%x", h.Sum(nil)); fresh != stored {
+		d.Problem, d.Detail = "drifted", fmt.Sprintf("archived sha256 %s, data service now %s", stored, fresh)
+		return d, false
+	}
+	return d, true
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
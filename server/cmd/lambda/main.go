@@ -0,0 +1,115 @@
+// Command lambda runs the API on AWS Lambda behind an API Gateway HTTP API
+// (payload format 2.0), for serverless deployments during the off-season.
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/ekjyotshinh/f1-server/config"
+	"github.com/ekjyotshinh/f1-server/jsoncodec"
+	"github.com/ekjyotshinh/f1-server/server"
+)
+
+func main() {
+	loaded, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	slog.SetDefault(loaded.Logger())
+	jsoncodec.Default = loaded.Codec()
+	cfg := loaded.Server
+	// Lambda instances come and go, so keep nothing on local disk or in
+	// memory and leave background refreshes to on-demand computation
+	cfg.Stateless = true
+	cfg.StreaksRefresh = 0
+	cfg.RatingsRefresh = 0
+	cfg.WarmInterval = 0
+	cfg.ScheduleCheck = 0
+	cfg.ProbeInterval = 0
+	cfg.UpstreamDNSRefresh = 0
+
+	srv, err := server.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to build server: %v", err)
+	}
+	lambda.Start(adapter(srv))
+}
+
+// adapter translates API Gateway events to and from plain HTTP requests.
+func adapter(h http.Handler) func(context.Context, events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	return func(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		req, err := toRequest(ctx, event)
+		if err != nil {
+			return events.APIGatewayV2HTTPResponse{StatusCode: http.StatusBadRequest}, nil
+		}
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return toResponse(rec), nil
+	}
+}
+
+func toRequest(ctx context.Context, event events.APIGatewayV2HTTPRequest) (*http.Request, error) {
+	body := event.Body
+	if event.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, err
+		}
+		body = string(decoded)
+	}
+
+	target := event.RawPath
+	if event.RawQueryString != "" {
+		target += "?" + event.RawQueryString
+	}
+	req, err := http.NewRequestWithContext(ctx, event.RequestContext.HTTP.Method, target, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range event.Headers {
+		req.Header.Set(k, v)
+	}
+	if len(event.Cookies) > 0 {
+		req.Header.Set("Cookie", strings.Join(event.Cookies, "; "))
+	}
+	req.Host = event.RequestContext.DomainName
+	// gin reads the client IP from RemoteAddr, which must carry a port
+	req.RemoteAddr = net.JoinHostPort(event.RequestContext.HTTP.SourceIP, "0")
+	return req, nil
+}
+
+func toResponse(rec *httptest.ResponseRecorder) events.APIGatewayV2HTTPResponse {
+	resp := events.APIGatewayV2HTTPResponse{
+		StatusCode:        rec.Code,
+		MultiValueHeaders: map[string][]string{},
+	}
+	for k, v := range rec.Header() {
+		if k == "Set-Cookie" {
+			resp.Cookies = append(resp.Cookies, v...)
+			continue
+		}
+		resp.MultiValueHeaders[k] = v
+	}
+
+	// Binary payloads (e.g. compressed bodies) must be base64 encoded
+	body := rec.Body.Bytes()
+	if utf8.Valid(body) {
+		resp.Body = string(body)
+	} else {
+		resp.Body = base64.StdEncoding.EncodeToString(body)
+		resp.IsBase64Encoded = true
+	}
+	return resp
+}
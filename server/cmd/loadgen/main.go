@@ -0,0 +1,256 @@
+// Command loadgen replays realistic traffic against a gateway, usually a
+// local instance, and reports latency percentiles, status codes and cache
+// hits per endpoint, so performance changes can be measured before they are
+// deployed.
+//
+//	go run ./cmd/loadgen -target http://localhost:3000 -rate 50 -duration 1m
+//
+// Requests follow a built-in race weekend mix by default, or with -replay
+// the paths of the gateway's JSON request logs, each as often as it was
+// logged. Arrivals are open loop: requests go out at -rate whether or not
+// earlier ones have finished, up to -concurrency in flight; beyond that they
+// are counted as dropped, a sign the gateway can't keep up. Each request
+// comes from one of -clients addresses, via X-Forwarded-For, so per-client
+// rate limits apply as in production once the gateway trusts the load
+// generator with TRUSTED_PROXIES=127.0.0.1.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+func main() {
+	target := flag.String("target", "http://localhost:3000", "gateway to load")
+	rate := flag.Float64("rate", 20, "requests per second")
+	duration := flag.Duration("duration", 30*time.Second, "how long to send requests for")
+	concurrency := flag.Int("concurrency", 64, "most requests in flight at once")
+	clients := flag.Int("clients", 100, "distinct client addresses to spread requests over")
+	year := flag.Int("year", time.Now().Year()-1, "season the built-in mix asks for")
+	rounds := flag.Int("rounds", 24, "rounds of -year the built-in mix asks for")
+	replay := flag.String("replay", "", "gateway JSON log to take the request mix from")
+	seed := flag.Int64("seed", 1, "random seed, for repeatable runs")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	if *rate <= 0 || *concurrency < 1 || *clients < 1 || *rounds < 1 {
+		log.Fatal("-rate, -concurrency, -clients and -rounds must be positive")
+	}
+	entries := defaultMix
+	if *replay != "" {
+		f, err := os.Open(*replay)
+		if err != nil {
+			log.Fatal(err)
+		}
+		entries, err = replayMix(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: %v", *replay, err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+
+	l := &loader{
+		target:  *target,
+		mix:     newMix(entries, *year, *rounds),
+		clients: *clients,
+		slots:   make(chan struct{}, *concurrency),
+		client: &http.Client{
+			Timeout:   2 * time.Minute,
+			Transport: &http.Transport{MaxIdleConnsPerHost: *concurrency},
+		},
+		groups: map[string]*groupStats{},
+	}
+	rep := l.report(l.run(ctx, rand.New(rand.NewSource(*seed)), *rate))
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(rep)
+		return
+	}
+	rep.print(os.Stdout)
+}
+
+// loader sends the requests and collects their results.
+type loader struct {
+	target  string
+	mix     *mix
+	clients int
+	slots   chan struct{}
+	client  *http.Client
+
+	mu      sync.Mutex
+	groups  map[string]*groupStats
+	dropped int
+}
+
+type groupStats struct {
+	latencies []time.Duration
+	statuses  map[int]int // 0 for transport errors
+	hits      int
+}
+
+// run sends requests with exponentially distributed gaps averaging 1/rate
+// until ctx is done, then waits for those in flight. It returns how long it
+// was sending for.
+func (l *loader) run(ctx context.Context, rng *rand.Rand, rate float64) time.Duration {
+	var wg sync.WaitGroup
+	start := time.Now()
+	// Arrivals are scheduled from the start, so time spent sending doesn't
+	// lower the rate
+	next := start
+	for {
+		next = next.Add(time.Duration(rng.ExpFloat64() / rate * float64(time.Second)))
+		select {
+		case <-ctx.Done():
+			sending := time.Since(start)
+			wg.Wait()
+			return sending
+		case <-time.After(time.Until(next)):
+		}
+		path := l.mix.pick(rng)
+		id := rng.Intn(l.clients)
+		client := fmt.Sprintf("10.%d.%d.%d", id>>16&255, id>>8&255, id&255)
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			l.mu.Lock()
+			l.dropped++
+			l.mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-l.slots }()
+			l.send(path, client)
+		}()
+	}
+}
+
+// send makes one request. It isn't tied to the run's context, so requests
+// in flight at the end still finish and count.
+func (l *loader) send(path, client string) {
+	req, err := http.NewRequest(http.MethodGet, l.target+path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("X-Forwarded-For", client)
+	start := time.Now()
+	status, hit := 0, false
+	if resp, err := l.client.Do(req); err == nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		status, hit = resp.StatusCode, resp.Header.Get("X-Cache") == "HIT"
+	}
+	elapsed := time.Since(start)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	g, ok := l.groups[group(path)]
+	if !ok {
+		g = &groupStats{statuses: map[int]int{}}
+		l.groups[group(path)] = g
+	}
+	g.latencies = append(g.latencies, elapsed)
+	g.statuses[status]++
+	if hit {
+		g.hits++
+	}
+}
+
+// groupReport is one endpoint's results. Latencies are in milliseconds.
+type groupReport struct {
+	Group    string         `json:"group"`
+	Requests int            `json:"requests"`
+	Statuses map[string]int `json:"statuses"`
+	HitRatio float64        `json:"cache_hit_ratio"`
+	P50      float64        `json:"p50_ms"`
+	P90      float64        `json:"p90_ms"`
+	P99      float64        `json:"p99_ms"`
+	Max      float64        `json:"max_ms"`
+}
+
+type report struct {
+	Seconds  float64       `json:"seconds"`
+	Requests int           `json:"requests"`
+	Dropped  int           `json:"dropped"`
+	Groups   []groupReport `json:"groups"`
+}
+
+// report summarises the run; elapsed is how long requests were sent for.
+func (l *loader) report(elapsed time.Duration) report {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rep := report{Seconds: elapsed.Seconds(), Dropped: l.dropped, Groups: []groupReport{}}
+	for name, g := range l.groups {
+		sort.Slice(g.latencies, func(i, j int) bool { return g.latencies[i] < g.latencies[j] })
+		n := len(g.latencies)
+		gr := groupReport{
+			Group:    name,
+			Requests: n,
+			Statuses: map[string]int{},
+			HitRatio: float64(g.hits) / float64(n),
+			P50:      ms(g.latencies[n*50/100]),
+			P90:      ms(g.latencies[n*90/100]),
+			P99:      ms(g.latencies[n*99/100]),
+			Max:      ms(g.latencies[n-1]),
+		}
+		for status, count := range g.statuses {
+			name := strconv.Itoa(status)
+			if status == 0 {
+				name = "error"
+			}
+			gr.Statuses[name] = count
+		}
+		rep.Requests += n
+		rep.Groups = append(rep.Groups, gr)
+	}
+	sort.Slice(rep.Groups, func(i, j int) bool { return rep.Groups[i].Requests > rep.Groups[j].Requests })
+	return rep
+}
+
+func (r report) print(w io.Writer) {
+	fmt.Fprintf(w, "%d requests in %.1fs (%.1f/s), %d dropped\n\n", r.Requests, r.Seconds, float64(r.Requests)/r.Seconds, r.Dropped)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "endpoint\trequests\thits\tp50 ms\tp90 ms\tp99 ms\tmax ms\tstatuses\t")
+	for _, g := range r.Groups {
+		codes := make([]string, 0, len(g.Statuses))
+		for code := range g.Statuses {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		statuses := ""
+		for i, code := range codes {
+			if i > 0 {
+				statuses += " "
+			}
+			statuses += fmt.Sprintf("%s:%d", code, g.Statuses[code])
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.0f%%\t%.1f\t%.1f\t%.1f\t%.1f\t%s\t\n",
+			g.Group, g.Requests, g.HitRatio*100, g.P50, g.P90, g.P99, g.Max, statuses)
+	}
+	tw.Flush()
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
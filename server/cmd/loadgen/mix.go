@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// weighted is one kind of request and how often it is sent relative to
+// the others.
+type weighted struct {
+	weight int
+	path   string
+}
+
+// defaultMix approximates a race weekend's traffic: mostly results and
+// analytics of recent rounds, some standings, and a little telemetry.
+// Placeholders are filled per request.
+var defaultMix = []weighted{
+	{25, "/api/race/{year}/{round}"},
+	{12, "/api/analytics/{year}/{round}"},
+	{12, "/api/schedule/{year}"},
+	{10, "/api/qualifying/{year}/{round}"},
+	{8, "/api/laps/{year}/{round}/{driver}"},
+	{6, "/api/compare/{year}/{round}?drivers={driver},{driver}"},
+	{6, "/api/standings/drivers/{year}"},
+	{4, "/api/standings/constructors/{year}"},
+	{4, "/api/pitstops/{year}/{round}"},
+	{4, "/api/season/{year}/summary"},
+	{4, "/api/telemetry/{year}/{round}/chunk/{chunk}"},
+	{3, "/api/weather/{year}/{round}"},
+	{2, "/api/years"},
+}
+
+var drivers = []string{"VER", "NOR", "LEC", "PIA", "SAI", "HAM", "RUS", "PER", "ALO", "GAS"}
+
+// mix picks request paths at random by weight.
+type mix struct {
+	entries []weighted
+	total   int
+	year    int
+	rounds  int
+}
+
+func newMix(entries []weighted, year, rounds int) *mix {
+	m := &mix{entries: entries, year: year, rounds: rounds}
+	for _, e := range entries {
+		m.total += e.weight
+	}
+	return m
+}
+
+func (m *mix) pick(rng *rand.Rand) string {
+	n := rng.Intn(m.total)
+	for _, e := range m.entries {
+		if n < e.weight {
+			return m.fill(rng, e.path)
+		}
+		n -= e.weight
+	}
+	return m.fill(rng, m.entries[len(m.entries)-1].path)
+}
+
+// fill replaces placeholders. Rounds favour the latest, which gets the
+// traffic after a race: round r of n is picked with weight 1/(n-r+1).
+func (m *mix) fill(rng *rand.Rand, path string) string {
+	path = strings.ReplaceAll(path, "{year}", strconv.Itoa(m.year))
+	if strings.Contains(path, "{round}") {
+		path = strings.ReplaceAll(path, "{round}", strconv.Itoa(m.round(rng)))
+	}
+	// Drivers in one path differ, as a comparison needs
+	for i, perm := 0, rng.Perm(len(drivers)); strings.Contains(path, "{driver}"); i++ {
+		path = strings.Replace(path, "{driver}", drivers[perm[i]], 1)
+	}
+	return strings.ReplaceAll(path, "{chunk}", strconv.Itoa(rng.Intn(10)))
+}
+
+func (m *mix) round(rng *rand.Rand) int {
+	var total float64
+	for r := 1; r <= m.rounds; r++ {
+		total += 1 / float64(m.rounds-r+1)
+	}
+	x := rng.Float64() * total
+	for r := 1; r <= m.rounds; r++ {
+		if x -= 1 / float64(m.rounds-r+1); x < 0 {
+			return r
+		}
+	}
+	return m.rounds
+}
+
+// skipped are logged paths not worth replaying.
+var skipped = []string{"/metrics", "/healthz", "/readyz", "/api/admin", "/api/clear-cache"}
+
+// replayMix builds a mix from the gateway's JSON request logs: each GET path
+// is sent as often as it was logged.
+func replayMix(r io.Reader) ([]weighted, error) {
+	counts := map[string]int{}
+	var order []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var line struct {
+			Msg    string `json:"msg"`
+			Method string `json:"method"`
+			Path   string `json:"path"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &line) != nil || line.Msg != "request" || line.Method != "GET" {
+			continue
+		}
+		if skip(line.Path) {
+			continue
+		}
+		if counts[line.Path] == 0 {
+			order = append(order, line.Path)
+		}
+		counts[line.Path]++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(order) == 0 {
+		return nil, fmt.Errorf("no GET requests in the log")
+	}
+	entries := make([]weighted, len(order))
+	for i, path := range order {
+		entries[i] = weighted{weight: counts[path], path: path}
+	}
+	return entries, nil
+}
+
+func skip(path string) bool {
+	for _, p := range skipped {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// group is the part of a path results are reported by, e.g. "/api/race".
+func group(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	parts := strings.SplitN(path, "/", 4)
+	if len(parts) < 3 {
+		return path
+	}
+	return "/" + parts[1] + "/" + parts[2]
+}
@@ -0,0 +1,497 @@
+// Package config assembles the server's configuration from defaults, an
+// optional YAML file and environment variables, in increasing precedence, so
+// one binary can run against production, staging or a local data service.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/jsoncodec"
+	"github.com/ekjyotshinh/f1-server/ratelimit"
+	"github.com/ekjyotshinh/f1-server/server"
+	"github.com/ekjyotshinh/f1-server/upstream"
+	"github.com/goccy/go-yaml"
+)
+
+// Config is everything needed to run the server.
+type Config struct {
+	// Port is the TCP port to listen on. Railway and most platforms set PORT.
+	Port int
+	// Socket, when set, is a Unix socket path to listen on instead of Port.
+	Socket string
+	// ShutdownTimeout is how long in-flight requests get to finish after
+	// SIGTERM. FastF1 loads a session from scratch in a couple of minutes.
+	ShutdownTimeout time.Duration
+	// LogFormat is "json" for log collectors or "text" for reading locally.
+	LogFormat string
+	// JSONCodec is "jsoniter", or "std" for encoding/json.
+	JSONCodec string
+	Server    server.Config
+}
+
+// Addr returns the listen address.
+func (c Config) Addr() string {
+	return fmt.Sprintf(":%d", c.Port)
+}
+
+// Logger returns a logger writing to stderr in the configured format.
+func (c Config) Logger() *slog.Logger {
+	if c.LogFormat == "text" {
+		return slog.New(slog.NewTextHandler(os.Stderr, nil))
+	}
+	return slog.New(slog.NewJSONHandler(os.Stderr, nil))
+}
+
+// Codec returns the configured JSON codec, or nil for an unknown name.
+func (c Config) Codec() jsoncodec.Codec {
+	codec, _ := jsoncodec.ByName(c.JSONCodec)
+	return codec
+}
+
+// Load reads the configuration. CONFIG_FILE may name a YAML file mapping the
+// same variable names to values, e.g.
+//
+//	PYTHON_SERVICE_URL: http://localhost:8000
+//	CORS_ORIGINS: [http://localhost:5173]
+//
+// Environment variables override the file. Every invalid value is reported,
+// not just the first.
+func Load() (Config, error) {
+	l := loader{}
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		vars, err := readFile(path)
+		if err != nil {
+			return Config{}, err
+		}
+		l.file = vars
+	}
+
+	cfg := Config{
+		Port:            3000,
+		LogFormat:       "json",
+		JSONCodec:       "jsoniter",
+		ShutdownTimeout: 150 * time.Second,
+		Server:          server.DefaultConfig(),
+	}
+	sc := &cfg.Server
+
+	l.int("PORT", &cfg.Port)
+	l.string("LISTEN_SOCKET", &cfg.Socket)
+	l.string("LOG_FORMAT", &cfg.LogFormat)
+	l.string("JSON_CODEC", &cfg.JSONCodec)
+	l.duration("SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout)
+	l.url("PYTHON_SERVICE_URL", &sc.PythonServiceURL)
+	// Sidecar data service on the same host, reached without TCP
+	l.string("PYTHON_SERVICE_SOCKET", &sc.UpstreamSocket)
+	l.url("HISTORY_URL", &sc.HistoryURL) // Ergast-compatible historical data provider
+	l.bool("DEMO", &sc.Demo)
+
+	// Dashboard origins, allowed on the frontend and admin routes
+	if l.list("CORS_ORIGINS", &sc.CORS.Frontend.Origins) {
+		sc.CORS.Admin.Origins = sc.CORS.Frontend.Origins
+	}
+
+	l.int("COMPRESS_MIN_SIZE", &sc.CompressMinSize)
+	l.int("COMPUTED_CACHE_SIZE", &sc.ComputedCacheSize)
+	l.float("SLO_TARGET", &sc.SLO.Target)
+	l.duration("SLO_LATENCY", &sc.SLO.Latency)
+	l.duration("LATENCY_RETENTION", &sc.LatencyRetention)
+	l.string("LATENCY_LOG", &sc.LatencyLog)   // persist upstream latency samples
+	l.string("STATIC_DIR", &sc.StaticDir)     // serve client/dist from this process
+	l.string("STATIC_BASE", &sc.StaticBase)   // Vite's base option
+	l.string("RATINGS_FILE", &sc.RatingsFile) // persist driver Elo ratings
+	l.string("REDIS_URL", &sc.CacheRedisURL)  // share the response cache between replicas
+	l.string("CACHE_DIR", &sc.CacheDir)       // keep finished races across restarts
+	l.int("CACHE_DIR_MAX_MB", &sc.CacheDirMaxMB)
+	l.string("SECURITY_CONTACT", &sc.SecurityContact)
+	l.string("SENTRY_DSN", &sc.SentryDSN)
+	l.string("SENTRY_ENVIRONMENT", &sc.SentryEnvironment)
+
+	// Mutual TLS to a private data service
+	l.string("UPSTREAM_CLIENT_CERT", &sc.UpstreamClientCert)
+	l.string("UPSTREAM_CLIENT_KEY", &sc.UpstreamClientKey)
+	l.string("UPSTREAM_CA", &sc.UpstreamCA)
+	// Connection pool and timeouts for the data service
+	l.int("UPSTREAM_MAX_IDLE_PER_HOST", &sc.UpstreamMaxIdlePerHost)
+	l.duration("UPSTREAM_DIAL_TIMEOUT", &sc.UpstreamDialTimeout)
+	l.duration("UPSTREAM_TIMEOUT", &sc.UpstreamTimeout)
+	// Replacing the pool after an age, after failures in a row or when DNS
+	// moves the service; 0 turns each off
+	l.duration("UPSTREAM_MAX_CONN_AGE", &sc.UpstreamMaxConnAge)
+	l.int("UPSTREAM_MAX_FAILURES", &sc.UpstreamMaxFailures)
+	l.duration("UPSTREAM_DNS_REFRESH", &sc.UpstreamDNSRefresh)
+	// Per-route overrides as "route=duration", e.g. "/api/years=30s"
+	var timeouts map[string]string
+	if l.pairs("UPSTREAM_ROUTE_TIMEOUTS", "=", &timeouts) {
+		sc.UpstreamRouteTimeouts = make(map[string]time.Duration, len(timeouts))
+		for route, v := range timeouts {
+			d, err := time.ParseDuration(v)
+			l.check(err == nil && d > 0, "UPSTREAM_ROUTE_TIMEOUTS: %s=%s is not a positive duration", route, v)
+			sc.UpstreamRouteTimeouts[route] = d
+		}
+	}
+	// Retries of transient data service failures
+	l.int("UPSTREAM_RETRIES", &sc.UpstreamRetry.Attempts)
+	l.duration("UPSTREAM_RETRY_BACKOFF", &sc.UpstreamRetry.Backoff)
+	l.duration("UPSTREAM_RETRY_MAX_BACKOFF", &sc.UpstreamRetry.MaxBackoff) // 0 for no cap
+	l.float("UPSTREAM_RETRY_JITTER", &sc.UpstreamRetry.Jitter)
+	// Failing fast once the data service keeps failing; 0 failures disables it
+	l.int("UPSTREAM_BREAKER_FAILURES", &sc.UpstreamBreaker.Failures)
+	l.duration("UPSTREAM_BREAKER_COOLDOWN", &sc.UpstreamBreaker.Cooldown)
+	// Schedule and results from the historical data provider while it's down
+	l.bool("UPSTREAM_FALLBACK", &sc.UpstreamFallback)
+	// Calendars from the historical data provider, without the data service
+	l.bool("NATIVE_SCHEDULE", &sc.NativeSchedule)
+	// How often to load newly finished sessions into the cache; 0 disables it
+	l.duration("CACHE_WARM_INTERVAL", &sc.WarmInterval)
+	// How often to check the calendar for changed races; 0 disables it
+	l.duration("SCHEDULE_CHECK_INTERVAL", &sc.ScheduleCheck)
+	// Statistics recomputed in the background, from the seasons given; a
+	// refresh of 0 computes them on demand only
+	l.duration("STREAKS_REFRESH", &sc.StreaksRefresh)
+	l.int("STREAKS_POINTS_FROM", &sc.StreaksPointsFrom)
+	l.duration("RATINGS_REFRESH", &sc.RatingsRefresh)
+	l.int("RATINGS_FROM", &sc.RatingsFrom)
+	// How long each route's responses are cached as "route=duration", e.g.
+	// "/api/race/:year/:race_name=2h", 0 to not cache it; routes not listed
+	// keep their defaults
+	var ttls map[string]string
+	if l.pairs("CACHE_TTLS", "=", &ttls) {
+		for route, v := range ttls {
+			d, err := time.ParseDuration(v)
+			l.check(err == nil && d >= 0, "CACHE_TTLS: %s=%s is not a duration such as 30m", route, v)
+			sc.CacheTTLs[route] = d
+		}
+	}
+	l.int("CACHE_SIZE", &sc.CacheSize)
+	l.duration("CACHE_MAX_STALE", &sc.CacheMaxStale)
+	// Upstream calls allowed per hour, 0 for unlimited, and the fraction kept
+	// back from background refreshes
+	l.int("DATA_SERVICE_BUDGET", &sc.DataServiceBudget)
+	l.int("HISTORY_BUDGET", &sc.HistoryBudget)
+	l.float("BUDGET_RESERVE", &sc.BudgetReserve)
+	l.float("SIZE_ANOMALY_RATIO", &sc.SizeAnomalyRatio)
+	// How suspect responses are answered, "warn" or "hide", and how long
+	// they are held for inspection
+	l.string("QUARANTINE_MODE", &sc.QuarantineMode)
+	l.duration("QUARANTINE_KEEP", &sc.QuarantineKeep)
+	// Data service calls in flight per route as "route=n", e.g.
+	// "/api/telemetry/:year/:race_name=2"; routes not listed keep their defaults
+	var concurrency map[string]string
+	if l.pairs("UPSTREAM_CONCURRENCY", "=", &concurrency) {
+		for route, v := range concurrency {
+			n, err := strconv.Atoi(v)
+			l.check(err == nil && n >= 0, "UPSTREAM_CONCURRENCY: %s=%s is not a non-negative number", route, v)
+			sc.UpstreamConcurrency.Limits[route] = n
+		}
+	}
+	l.int("UPSTREAM_QUEUE_SIZE", &sc.UpstreamConcurrency.QueueSize)
+	l.duration("UPSTREAM_QUEUE_TIMEOUT", &sc.UpstreamConcurrency.QueueTimeout)
+	// Explicit egress proxy; HTTPS_PROXY and NO_PROXY are honored without it
+	l.string("OUTBOUND_PROXY", &sc.OutboundProxy)
+
+	// Per-client request rates as "group=rate:burst", e.g. "data=2:20", with
+	// rate per second; groups not listed keep their defaults
+	var limits map[string]string
+	if l.pairs("RATE_LIMITS", "=", &limits) {
+		for group, v := range limits {
+			rate, burst, _ := strings.Cut(v, ":")
+			r, rerr := strconv.ParseFloat(rate, 64)
+			b, berr := strconv.Atoi(burst)
+			if rerr != nil || berr != nil || b < 1 {
+				l.check(false, "RATE_LIMITS: %s=%s is not of the form group=rate:burst", group, v)
+				continue
+			}
+			sc.RateLimits[group] = ratelimit.Limit{Rate: r, Burst: b}
+		}
+	}
+	// Fields hidden from public deployments as "route=path", e.g.
+	// "/api/race/:year/:race_name=results.*.Time"; a route may repeat
+	var redactions []string
+	if l.list("REDACT_FIELDS", &redactions) {
+		sc.Redactions = make(map[string][]string)
+		for _, pair := range redactions {
+			route, path, _ := strings.Cut(pair, "=")
+			l.check(route != "" && path != "", "REDACT_FIELDS: %q is not of the form route=path", pair)
+			sc.Redactions[route] = append(sc.Redactions[route], path)
+		}
+	}
+	// Deprecated routes as "route=since/sunset", e.g.
+	// "/api/years=2026-06-01/2027-01-01", or "route=since" while the sunset
+	// is undecided
+	var deprecations map[string]string
+	if l.pairs("DEPRECATIONS", "=", &deprecations) {
+		sc.Deprecations = make(map[string]server.Deprecation)
+		for route, dates := range deprecations {
+			since, sunset, hasSunset := strings.Cut(dates, "/")
+			dep := server.Deprecation{}
+			var err error
+			if dep.Since, err = time.Parse(time.DateOnly, since); err == nil && hasSunset {
+				dep.Sunset, err = time.Parse(time.DateOnly, sunset)
+			}
+			if err != nil || !strings.HasPrefix(route, "/") {
+				l.check(false, "DEPRECATIONS: %s=%s is not of the form route=YYYY-MM-DD/YYYY-MM-DD", route, dates)
+				continue
+			}
+			l.check(dep.Sunset.IsZero() || dep.Sunset.After(dep.Since), "DEPRECATIONS: %s has its sunset before it was deprecated", route)
+			sc.Deprecations[route] = dep
+		}
+	}
+	// Proxies whose X-Forwarded-For is trusted, e.g. "10.0.0.0/8"
+	l.list("TRUSTED_PROXIES", &sc.TrustedProxies)
+	// Header the platform's edge sets to the client IP, e.g. X-Real-IP on Railway
+	l.string("TRUSTED_PLATFORM", &sc.TrustedPlatform)
+	// Bans for clients with ABUSE_NOT_FOUND 404s or ABUSE_TOO_MANY 429s
+	// within ABUSE_WINDOW; 0 turns a threshold off
+	l.duration("ABUSE_WINDOW", &sc.Abuse.Window)
+	l.int("ABUSE_NOT_FOUND", &sc.Abuse.NotFound)
+	l.int("ABUSE_TOO_MANY", &sc.Abuse.TooMany)
+	l.duration("ABUSE_BAN", &sc.Abuse.Ban)
+	// Security headers, replacing the defaults; an HSTS max age of 0 drops it
+	l.duration("HSTS_MAX_AGE", &sc.Headers.HSTSMaxAge)
+	l.string("REFERRER_POLICY", &sc.Headers.ReferrerPolicy)
+	l.string("CONTENT_SECURITY_POLICY", &sc.Headers.ContentSecurityPolicy)
+
+	// Routes the self-probe job requests, e.g. "/api/years,/api/race/2024/1"
+	l.list("PROBE_ROUTES", &sc.ProbeRoutes)
+	l.duration("PROBE_INTERVAL", &sc.ProbeInterval)
+	l.int("SAMPLES_PER_ROUTE", &sc.SamplesPerRoute) // kept by the debugging sampler
+
+	// Google Sheets export: a service account key file and the spreadsheet id
+	l.string("GOOGLE_SHEETS_CREDENTIALS", &sc.SheetsCredentials)
+	l.string("GOOGLE_SHEETS_ID", &sc.SheetsSpreadsheetID)
+	// Admin API keys as "name:key,name:key"; the name goes in the audit log
+	l.pairs("ADMIN_KEYS", ":", &sc.AdminKeys)
+	// Server-to-server clients as "id:secret,id:secret"
+	l.pairs("SIGNING_SECRETS", ":", &sc.SigningSecrets)
+	l.duration("SIGNING_SKEW", &sc.SigningSkew) // how far off their clocks may be
+	// Multi-region data service as "us=https://...,eu=https://...", with
+	// optional pins such as "telemetry=eu"
+	var regions []string
+	if l.list("PYTHON_SERVICE_REGIONS", &regions) {
+		for _, pair := range regions {
+			name, u, _ := strings.Cut(pair, "=")
+			l.check(name != "" && validURL(u), "PYTHON_SERVICE_REGIONS: %q is not of the form name=URL", pair)
+			sc.Regions = append(sc.Regions, upstream.Region{Name: name, URL: u})
+		}
+	}
+	l.pairs("PYTHON_REGION_PINS", "=", &sc.RegionPins)
+	l.duration("REGION_PROBE_INTERVAL", &sc.RegionProbeInterval)
+
+	l.check(cfg.Port > 0 && cfg.Port < 65536, "PORT: must be between 1 and 65535")
+	l.check(cfg.LogFormat == "json" || cfg.LogFormat == "text", "LOG_FORMAT: must be json or text")
+	l.check(cfg.Codec() != nil, "JSON_CODEC: must be jsoniter or std")
+	l.check(sc.UpstreamSocket == "" || len(sc.Regions) == 0, "PYTHON_SERVICE_SOCKET: can't be combined with PYTHON_SERVICE_REGIONS")
+	l.check(sc.SheetsCredentials == "" || sc.SheetsSpreadsheetID != "", "GOOGLE_SHEETS_ID: required with GOOGLE_SHEETS_CREDENTIALS")
+	l.check(sc.UpstreamTimeout > 0, "UPSTREAM_TIMEOUT: must be positive")
+	l.check(sc.UpstreamMaxIdlePerHost > 0, "UPSTREAM_MAX_IDLE_PER_HOST: must be positive")
+	l.check(sc.UpstreamConcurrency.QueueSize >= 0, "UPSTREAM_QUEUE_SIZE: must not be negative")
+	l.check(sc.UpstreamConcurrency.QueueTimeout > 0, "UPSTREAM_QUEUE_TIMEOUT: must be positive")
+	l.check(sc.UpstreamRetry.Jitter >= 0 && sc.UpstreamRetry.Jitter <= 1, "UPSTREAM_RETRY_JITTER: must be between 0 and 1")
+	l.check(sc.UpstreamRetry.MaxBackoff == 0 || sc.UpstreamRetry.MaxBackoff >= sc.UpstreamRetry.Backoff,
+		"UPSTREAM_RETRY_MAX_BACKOFF: must not be below UPSTREAM_RETRY_BACKOFF")
+	l.check(sc.UpstreamMaxConnAge >= 0 && sc.UpstreamMaxFailures >= 0 && sc.UpstreamDNSRefresh >= 0,
+		"UPSTREAM_MAX_CONN_AGE, UPSTREAM_MAX_FAILURES, UPSTREAM_DNS_REFRESH: must not be negative")
+	l.check(len(sc.Regions) < 2 || sc.RegionProbeInterval > 0, "REGION_PROBE_INTERVAL: must be positive")
+	l.check(sc.SigningSkew > 0, "SIGNING_SKEW: must be positive")
+	l.check(sc.LatencyRetention > 0, "LATENCY_RETENTION: must be positive")
+	l.check(sc.StreaksRefresh >= 0 && sc.RatingsRefresh >= 0, "STREAKS_REFRESH, RATINGS_REFRESH: must not be negative")
+	l.check(sc.StreaksPointsFrom >= 1950, "STREAKS_POINTS_FROM: must be a season from 1950")
+	l.check(sc.RatingsFrom >= 1950, "RATINGS_FROM: must be a season from 1950")
+	l.check(sc.SLO.Target > 0 && sc.SLO.Target <= 1, "SLO_TARGET: must be in (0, 1]")
+	l.check(sc.CacheSize > 0, "CACHE_SIZE: must be positive")
+	l.check(sc.CacheMaxStale >= 0, "CACHE_MAX_STALE: must not be negative")
+	l.check(sc.CacheDirMaxMB >= 0, "CACHE_DIR_MAX_MB: must not be negative")
+	l.check(sc.DataServiceBudget >= 0, "DATA_SERVICE_BUDGET: must not be negative")
+	l.check(sc.HistoryBudget >= 0, "HISTORY_BUDGET: must not be negative")
+	l.check(sc.BudgetReserve >= 0 && sc.BudgetReserve < 1, "BUDGET_RESERVE: must be in [0, 1)")
+	l.check(sc.SizeAnomalyRatio >= 0 && sc.SizeAnomalyRatio <= 1, "SIZE_ANOMALY_RATIO: must be between 0 and 1")
+	l.check(sc.QuarantineMode == server.QuarantineWarn || sc.QuarantineMode == server.QuarantineHide, "QUARANTINE_MODE: must be warn or hide")
+	l.check(sc.QuarantineKeep > 0, "QUARANTINE_KEEP: must be positive")
+	l.check(sc.Abuse.Window > 0, "ABUSE_WINDOW: must be positive")
+	l.check(sc.Abuse.NotFound >= 0 && sc.Abuse.TooMany >= 0, "ABUSE_NOT_FOUND, ABUSE_TOO_MANY: must not be negative")
+	l.check(sc.Abuse.Ban > 0, "ABUSE_BAN: must be positive")
+	l.check(sc.Headers.HSTSMaxAge >= 0, "HSTS_MAX_AGE: must not be negative")
+	l.check(sc.SamplesPerRoute >= 0, "SAMPLES_PER_ROUTE: must not be negative")
+	for _, route := range sc.ProbeRoutes {
+		l.check(strings.HasPrefix(route, "/"), "PROBE_ROUTES: %q is not a path", route)
+	}
+	for class, pinned := range sc.RegionPins {
+		known := false
+		for _, region := range sc.Regions {
+			known = known || region.Name == pinned
+		}
+		l.check(known, "PYTHON_REGION_PINS: %s is pinned to unknown region %s", class, pinned)
+	}
+	return cfg, errors.Join(l.errs...)
+}
+
+// readFile loads a YAML file of variable names to values. Lists become
+// comma-separated values.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	vars := make(map[string]string, len(raw))
+	for k, v := range raw {
+		if list, ok := v.([]any); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = fmt.Sprint(item)
+			}
+			vars[k] = strings.Join(items, ",")
+			continue
+		}
+		vars[k] = fmt.Sprint(v)
+	}
+	return vars, nil
+}
+
+// loader reads variables, collecting errors as it goes. Each setter leaves
+// the default alone when the variable is unset or empty and reports whether
+// it changed anything.
+type loader struct {
+	file map[string]string
+	errs []error
+}
+
+func (l *loader) lookup(key string) (string, bool) {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v, true
+	}
+	v := strings.TrimSpace(l.file[key])
+	return v, v != ""
+}
+
+func (l *loader) check(ok bool, format string, args ...any) {
+	if !ok {
+		l.errs = append(l.errs, fmt.Errorf(format, args...))
+	}
+}
+
+func (l *loader) string(key string, dst *string) bool {
+	v, ok := l.lookup(key)
+	if ok {
+		*dst = v
+	}
+	return ok
+}
+
+func (l *loader) url(key string, dst *string) bool {
+	v, ok := l.lookup(key)
+	if !ok {
+		return false
+	}
+	if !validURL(v) {
+		l.check(false, "%s: %q is not an http(s) URL", key, v)
+		return false
+	}
+	*dst = strings.TrimSuffix(v, "/")
+	return true
+}
+
+func (l *loader) int(key string, dst *int) bool {
+	v, ok := l.lookup(key)
+	if !ok {
+		return false
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		l.check(false, "%s: %q is not an integer", key, v)
+		return false
+	}
+	*dst = n
+	return true
+}
+
+func (l *loader) float(key string, dst *float64) bool {
+	v, ok := l.lookup(key)
+	if !ok {
+		return false
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		l.check(false, "%s: %q is not a number", key, v)
+		return false
+	}
+	*dst = f
+	return true
+}
+
+func (l *loader) bool(key string, dst *bool) bool {
+	v, ok := l.lookup(key)
+	if !ok {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		l.check(false, "%s: %q is not true or false", key, v)
+		return false
+	}
+	*dst = b
+	return true
+}
+
+func (l *loader) duration(key string, dst *time.Duration) bool {
+	v, ok := l.lookup(key)
+	if !ok {
+		return false
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		l.check(false, "%s: %q is not a duration such as 30s or 5m", key, v)
+		return false
+	}
+	*dst = d
+	return true
+}
+
+func (l *loader) list(key string, dst *[]string) bool {
+	v, ok := l.lookup(key)
+	if !ok {
+		return false
+	}
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+	return true
+}
+
+func (l *loader) pairs(key, sep string, dst *map[string]string) bool {
+	v, ok := l.lookup(key)
+	if !ok {
+		return false
+	}
+	pairs := make(map[string]string)
+	for _, pair := range strings.Split(v, ",") {
+		k, val, found := strings.Cut(strings.TrimSpace(pair), sep)
+		if !found || k == "" || val == "" {
+			l.check(false, "%s: %q is not of the form key%svalue", key, pair, sep)
+			continue
+		}
+		pairs[k] = val
+	}
+	*dst = pairs
+	return true
+}
+
+func validURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,81 @@
+// Package demo embeds a small sample season so the server can run fully
+// offline for demos, screenshots, and frontend development. The data is
+// representative of the 2021 season but is not an authoritative record.
+package demo
+
+import (
+	"embed"
+	"encoding/json"
+	"path"
+	"strconv"
+	"strings"
+)
+
+//go:embed data
+var files embed.FS
+
+// Lookup returns the embedded response for an API path such as
+// "/api/race/2021/22" or "/api/analytics/2021/Abu Dhabi". Races may be
+// addressed by round number or by event name, location, or country.
+func Lookup(apiPath string) ([]byte, bool) {
+	parts := strings.Split(strings.Trim(apiPath, "/"), "/")
+	if len(parts) < 2 || parts[0] != "api" {
+		return nil, false
+	}
+
+	switch {
+	case len(parts) == 2 && parts[1] == "years":
+		return read("years.json")
+	case len(parts) == 3 && parts[1] == "schedule":
+		return read(path.Join("schedule", parts[2]+".json"))
+	case len(parts) == 4 && (parts[1] == "race" || parts[1] == "analytics"):
+		round, ok := resolveRound(parts[2], parts[3])
+		if !ok {
+			return nil, false
+		}
+		return read(path.Join(parts[1], parts[2], strconv.Itoa(round)+".json"))
+	}
+	return nil, false
+}
+
+type event struct {
+	RoundNumber int
+	Country     string
+	Location    string
+	EventName   string
+}
+
+// resolveRound maps a race identifier to its round number using the
+// embedded schedule, mirroring how the data service accepts either form.
+func resolveRound(year, race string) (int, bool) {
+	if round, err := strconv.Atoi(race); err == nil {
+		return round, true
+	}
+
+	data, ok := read(path.Join("schedule", year+".json"))
+	if !ok {
+		return 0, false
+	}
+	var events []event
+	if err := json.Unmarshal(data, &events); err != nil {
+		return 0, false
+	}
+
+	race = strings.ToLower(race)
+	for _, e := range events {
+		for _, name := range []string{e.EventName, e.Location, e.Country} {
+			if strings.Contains(strings.ToLower(name), race) {
+				return e.RoundNumber, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func read(name string) ([]byte, bool) {
+	data, err := files.ReadFile(path.Join("data", name))
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
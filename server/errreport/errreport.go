@@ -0,0 +1,127 @@
+// Package errreport sends panics and failed requests to Sentry (or any
+// service that accepts Sentry's protocol, such as GlitchTip), tagged with the
+// release from the binary's build info. Without a DSN nothing is sent.
+package errreport
+
+import (
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Reporter forwards events to the error tracker. A nil Reporter does nothing.
+type Reporter struct {
+	client *sentry.Client
+}
+
+// New creates a Reporter for dsn, tagging events with environment. It
+// returns nil when dsn is empty.
+func New(dsn, environment string) (*Reporter, error) {
+	if dsn == "" {
+		return nil, nil
+	}
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:              dsn,
+		Environment:      environment,
+		Release:          release(),
+		AttachStacktrace: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Reporter{client: client}, nil
+}
+
+// Panic reports value, recovered from a panic while serving req. It must be
+// called from the deferred function that recovered, so the stack trace
+// still shows where the panic happened.
+func (r *Reporter) Panic(req *http.Request, requestID string, value any) {
+	if r == nil {
+		return
+	}
+	hub := sentry.NewHub(r.client, sentry.NewScope())
+	hub.Scope().SetRequest(req)
+	hub.Scope().SetTag("request_id", requestID)
+	hub.RecoverWithContext(req.Context(), value)
+}
+
+// Failure describes a request that ended in a server error.
+type Failure struct {
+	Request   *http.Request
+	RequestID string
+	// Route is the gin route pattern, which groups failures of one endpoint
+	Route   string
+	Status  int
+	Code    string // apierror code
+	Message string
+	// Causes are the underlying errors, when the response message hides them
+	Causes []string
+	// Tags add context such as the upstream status or cache state
+	Tags map[string]string
+}
+
+// Failure reports f. Failures with the same route and code are grouped.
+func (r *Reporter) Failure(f Failure) {
+	if r == nil {
+		return
+	}
+	hub := sentry.NewHub(r.client, sentry.NewScope())
+	scope := hub.Scope()
+	scope.SetRequest(f.Request)
+	scope.SetFingerprint([]string{f.Route, f.Code})
+	scope.SetTag("request_id", f.RequestID)
+	scope.SetTag("route", f.Route)
+	scope.SetTag("status", strconv.Itoa(f.Status))
+	scope.SetTag("code", f.Code)
+	for k, v := range f.Tags {
+		scope.SetTag(k, v)
+	}
+	if len(f.Causes) > 0 {
+		scope.SetContext("causes", sentry.Context{"errors": f.Causes})
+	}
+	scope.SetLevel(sentry.LevelError)
+	hub.CaptureMessage(f.Code + ": " + f.Message)
+}
+
+// Flush waits up to timeout for queued events to be sent.
+func (r *Reporter) Flush(timeout time.Duration) {
+	if r == nil {
+		return
+	}
+	r.client.Flush(timeout)
+}
+
+// release names the running build after its VCS revision, as stamped by go
+// build, so errors can be matched to the deploy that caused them.
+func release() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	var revision string
+	dirty := false
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			dirty = setting.Value == "true"
+		}
+	}
+	if revision == "" {
+		if info.Main.Version == "" || info.Main.Version == "(devel)" {
+			return ""
+		}
+		return "f1-server@" + info.Main.Version
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if dirty {
+		revision += "-dirty"
+	}
+	return "f1-server@" + revision
+}
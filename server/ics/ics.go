@@ -0,0 +1,454 @@
+// Package ics reads the events of an iCalendar (RFC 5545) file, as exported
+// by Google Calendar, Outlook and Apple Calendar. It understands enough to
+// tell when someone is busy: event times in UTC, in a named time zone or
+// floating, all-day events, and the common recurrence rules.
+package ics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Calendar is a parsed iCalendar file.
+type Calendar struct {
+	// TimeZone is the calendar's own zone (X-WR-TIMEZONE), if it names one.
+	TimeZone string
+	Events   []Event
+}
+
+// Event is a VEVENT. End is exclusive; all-day events start and end at
+// midnight.
+type Event struct {
+	Summary string
+	Start   time.Time
+	End     time.Time
+	AllDay  bool
+	// Rule repeats the event; nil for a one-off. Except lists the starts of
+	// cancelled occurrences.
+	Rule   *Rule
+	Except []time.Time
+}
+
+// Rule is the part of an RRULE this package follows. BYDAY is only applied
+// to weekly rules; other BY* parts are ignored.
+type Rule struct {
+	Freq     string // DAILY, WEEKLY, MONTHLY or YEARLY
+	Interval int
+	Count    int       // 0 for no limit
+	Until    time.Time // zero for no limit
+	ByDay    []time.Weekday
+}
+
+// maxEvents and maxRecurring bound the events of a calendar, and how many of
+// them repeat, which is far more than years of a busy calendar holds.
+const (
+	maxEvents    = 20000
+	maxRecurring = 500
+)
+
+// ErrTooLarge is returned for a calendar with more events than Parse takes.
+var ErrTooLarge = fmt.Errorf("ics: more than %d events or %d recurring events", maxEvents, maxRecurring)
+
+// Parse reads a calendar. Floating times, which name no zone, are read in the
+// calendar's own zone or else in loc. Events that can't be understood are
+// skipped rather than failing the whole file; an error means it isn't
+// iCalendar at all, or is too large to expand.
+func Parse(r io.Reader, loc *time.Location) (*Calendar, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 || !strings.EqualFold(lines[0], "BEGIN:VCALENDAR") {
+		return nil, errors.New("ics: not an iCalendar file")
+	}
+
+	cal := &Calendar{}
+	floating := loc
+	var props [][]property // the properties of each VEVENT
+	depth := 0             // nesting inside a VEVENT (alarms are components too)
+	for _, line := range lines[1:] {
+		p, ok := parseProperty(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case p.name == "BEGIN" && (depth > 0 || strings.EqualFold(p.value, "VEVENT")):
+			if depth == 0 {
+				if len(props) == maxEvents {
+					return nil, ErrTooLarge
+				}
+				props = append(props, nil)
+			}
+			depth++
+		case p.name == "END" && depth > 0:
+			depth--
+		case depth == 1:
+			props[len(props)-1] = append(props[len(props)-1], p)
+		case depth == 0 && p.name == "X-WR-TIMEZONE":
+			if loc, err := time.LoadLocation(p.value); err == nil {
+				cal.TimeZone, floating = p.value, loc
+			}
+		}
+	}
+
+	recurring := 0
+	for _, ps := range props {
+		if e, ok := buildEvent(ps, floating); ok {
+			if e.Rule != nil {
+				if recurring++; recurring > maxRecurring {
+					return nil, ErrTooLarge
+				}
+			}
+			cal.Events = append(cal.Events, e)
+		}
+	}
+	return cal, nil
+}
+
+// maxPeriods bounds the expansion of one recurring event: 50 years of days.
+const maxPeriods = 50 * 366
+
+// Occurrences returns the [start, end) intervals of e that overlap [from, to).
+func (e Event) Occurrences(from, to time.Time) [][2]time.Time {
+	length := e.End.Sub(e.Start)
+	var out [][2]time.Time
+	add := func(start time.Time) {
+		if start.Before(to) && start.Add(length).After(from) && !e.excepted(start) {
+			out = append(out, [2]time.Time{start, start.Add(length)})
+		}
+	}
+	if e.Rule == nil {
+		add(e.Start)
+		return out
+	}
+
+	// A COUNT has to be counted from the first occurrence; other rules can
+	// start at from
+	first, n := 0, 0
+	if e.Rule.Count == 0 {
+		first = e.Rule.skip(e.Start, from.Add(-length))
+	}
+	var starts []time.Time
+	for period := first; period < first+maxPeriods; period++ {
+		var base time.Time
+		base, starts = e.Rule.period(e.Start, period, starts[:0])
+		// A week's BYDAY starts can fall a few days before its base
+		if base.AddDate(0, 0, -7).After(to) {
+			break
+		}
+		for _, start := range starts {
+			if start.Before(e.Start) {
+				continue
+			}
+			if !e.Rule.Until.IsZero() && start.After(e.Rule.Until) || e.Rule.Count > 0 && n >= e.Rule.Count {
+				return out
+			}
+			n++
+			add(start)
+		}
+	}
+	return out
+}
+
+func (e Event) excepted(start time.Time) bool {
+	for _, t := range e.Except {
+		if t.Equal(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// skip is how many of the rule's periods after first end before t, give or
+// take one, so that expanding from there misses none that reach t.
+func (r *Rule) skip(first, t time.Time) int {
+	if !t.After(first) {
+		return 0
+	}
+	var n int
+	switch r.Freq {
+	case "DAILY":
+		n = int(t.Sub(first) / (24 * time.Hour))
+	case "WEEKLY":
+		n = int(t.Sub(first) / (7 * 24 * time.Hour))
+	case "MONTHLY":
+		n = 12*(t.Year()-first.Year()) + int(t.Month()-first.Month())
+	default: // YEARLY
+		n = t.Year() - first.Year()
+	}
+	// A period back covers days changed by DST and BYDAY starts before
+	// their week's base
+	return max(0, n/r.Interval-1)
+}
+
+// period returns the rule's n-th period after first: first moved on by n
+// intervals, and the starts generated in that period, in order, appended to
+// starts.
+func (r *Rule) period(first time.Time, n int, starts []time.Time) (time.Time, []time.Time) {
+	step := n * r.Interval
+	switch r.Freq {
+	case "DAILY":
+		base := first.AddDate(0, 0, step)
+		return base, append(starts, base)
+	case "WEEKLY":
+		base := first.AddDate(0, 0, 7*step)
+		if len(r.ByDay) == 0 {
+			return base, append(starts, base)
+		}
+		// Weeks start on Monday, as RFC 5545 defaults
+		monday := base.AddDate(0, 0, -((int(base.Weekday()) + 6) % 7))
+		for i := range 7 {
+			day := monday.AddDate(0, 0, i)
+			for _, wd := range r.ByDay {
+				if day.Weekday() == wd {
+					starts = append(starts, day)
+				}
+			}
+		}
+		return base, starts
+	case "MONTHLY":
+		base := first.AddDate(0, step, 0)
+		// Months without the day, such as the 31st, are skipped
+		if base.Day() != first.Day() {
+			return base, starts
+		}
+		return base, append(starts, base)
+	default: // YEARLY
+		base := first.AddDate(step, 0, 0)
+		if base.Day() != first.Day() {
+			return base, starts
+		}
+		return base, append(starts, base)
+	}
+}
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// unfold joins folded continuation lines.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			if n := len(lines); n > 0 {
+				lines[n-1] += line[1:]
+			}
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, sc.Err()
+}
+
+// parseProperty splits NAME;PARAM=VALUE;...:VALUE, allowing quoted
+// parameter values to contain colons.
+func parseProperty(line string) (property, bool) {
+	quoted := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return property{}, false
+	}
+	parts := strings.Split(line[:colon], ";")
+	p := property{name: strings.ToUpper(parts[0]), params: map[string]string{}, value: line[colon+1:]}
+	for _, param := range parts[1:] {
+		k, v, _ := strings.Cut(param, "=")
+		p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+	}
+	return p, true
+}
+
+func buildEvent(props []property, floating *time.Location) (Event, bool) {
+	var e Event
+	var duration time.Duration
+	hasEnd, hasDuration := false, false
+	for _, p := range props {
+		switch p.name {
+		case "SUMMARY":
+			e.Summary = unescape(p.value)
+		case "DTSTART":
+			t, allDay, err := parseTime(p, floating)
+			if err != nil {
+				return Event{}, false
+			}
+			e.Start, e.AllDay = t, allDay
+		case "DTEND":
+			t, _, err := parseTime(p, floating)
+			if err != nil {
+				return Event{}, false
+			}
+			e.End, hasEnd = t, true
+		case "DURATION":
+			d, err := parseDuration(p.value)
+			if err != nil {
+				return Event{}, false
+			}
+			duration, hasDuration = d, true
+		case "RRULE":
+			rule, err := parseRule(p.value, floating)
+			if err != nil {
+				return Event{}, false
+			}
+			e.Rule = rule
+		case "EXDATE":
+			for _, v := range strings.Split(p.value, ",") {
+				if t, _, err := parseTime(property{params: p.params, value: v}, floating); err == nil {
+					e.Except = append(e.Except, t)
+				}
+			}
+		case "STATUS":
+			if strings.EqualFold(p.value, "CANCELLED") {
+				return Event{}, false
+			}
+		case "TRANSP":
+			// Events marked free don't block time
+			if strings.EqualFold(p.value, "TRANSPARENT") {
+				return Event{}, false
+			}
+		}
+	}
+	if e.Start.IsZero() {
+		return Event{}, false
+	}
+	switch {
+	case hasEnd:
+	case hasDuration:
+		e.End = e.Start.Add(duration)
+	case e.AllDay:
+		e.End = e.Start.AddDate(0, 0, 1)
+	default:
+		e.End = e.Start
+	}
+	return e, !e.End.Before(e.Start)
+}
+
+// parseTime reads a DATE or DATE-TIME value: UTC with a Z suffix, in its
+// TZID, or floating.
+func parseTime(p property, floating *time.Location) (time.Time, bool, error) {
+	loc := floating
+	if tzid := p.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	v := strings.TrimSpace(p.value)
+	if p.params["VALUE"] == "DATE" || len(v) == 8 {
+		t, err := time.ParseInLocation("20060102", v, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(v, "Z") {
+		t, err := time.Parse("20060102T150405Z", v)
+		return t, false, err
+	}
+	t, err := time.ParseInLocation("20060102T150405", v, loc)
+	return t, false, err
+}
+
+// parseDuration reads durations such as PT1H30M, P1D or P2W.
+func parseDuration(v string) (time.Duration, error) {
+	rest, ok := strings.CutPrefix(strings.TrimPrefix(v, "+"), "P")
+	if !ok {
+		return 0, fmt.Errorf("ics: bad duration %q", v)
+	}
+	var d time.Duration
+	inTime := false
+	num := ""
+	for _, r := range rest {
+		switch {
+		case r >= '0' && r <= '9':
+			num += string(r)
+		case r == 'T':
+			inTime = true
+		default:
+			n, err := strconv.Atoi(num)
+			if err != nil {
+				return 0, fmt.Errorf("ics: bad duration %q", v)
+			}
+			num = ""
+			unit := map[rune]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour}[r]
+			if inTime {
+				unit = map[rune]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}[r]
+			}
+			if unit == 0 {
+				return 0, fmt.Errorf("ics: bad duration %q", v)
+			}
+			d += time.Duration(n) * unit
+		}
+	}
+	return d, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// maxInterval bounds an RRULE's INTERVAL; past it not even a daily rule
+// repeats within a few years.
+const maxInterval = 1000
+
+func parseRule(v string, floating *time.Location) (*Rule, error) {
+	r := &Rule{Interval: 1}
+	for _, part := range strings.Split(v, ";") {
+		k, val, _ := strings.Cut(part, "=")
+		var err error
+		switch strings.ToUpper(k) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(val)
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(val)
+		case "COUNT":
+			r.Count, err = strconv.Atoi(val)
+		case "UNTIL":
+			r.Until, _, err = parseTime(property{value: val}, floating)
+			if err == nil && len(val) == 8 {
+				// A date includes the whole day
+				r.Until = r.Until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(val, ",") {
+				// Ordinals such as 1MO only matter to monthly rules
+				if wd, ok := weekdays[strings.ToUpper(day[max(0, len(day)-2):])]; ok {
+					r.ByDay = append(r.ByDay, wd)
+				}
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ics: bad RRULE %q", v)
+		}
+	}
+	switch r.Freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		return nil, fmt.Errorf("ics: unsupported RRULE %q", v)
+	}
+	if r.Interval < 1 || r.Interval > maxInterval || r.Count < 0 {
+		return nil, fmt.Errorf("ics: bad RRULE %q", v)
+	}
+	return r, nil
+}
+
+// unescape undoes TEXT escaping.
+func unescape(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
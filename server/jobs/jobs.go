@@ -0,0 +1,89 @@
+// Package jobs runs the server's periodic background tasks.
+package jobs
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// A Locker lets replicas take turns at a task: Lock takes name for ttl
+// unless another replica holds it, and reports whether this one got it.
+type Locker interface {
+	Lock(ctx context.Context, name string, ttl time.Duration) (bool, error)
+}
+
+// Scheduler runs named tasks on fixed intervals until stopped.
+type Scheduler struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	locker Locker
+}
+
+// New creates an idle Scheduler whose Shared tasks take turns through
+// locker. With a nil locker, this replica runs them all.
+func New(locker Locker) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{ctx: ctx, cancel: cancel, locker: locker}
+}
+
+// Every runs fn immediately and then every interval. Errors are logged and the
+// task keeps its schedule. A non-positive interval does nothing.
+func (s *Scheduler) Every(name string, interval time.Duration, fn func(ctx context.Context) error) {
+	if interval <= 0 {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := fn(s.ctx); err != nil && s.ctx.Err() == nil {
+				log.Printf("job %s: %v", name, err)
+			}
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Shared is Every for work one replica can do for all of them, such as
+// filling a shared cache. Each run takes name's lock until just before the
+// next, so one replica does the work per interval; the others run follow
+// instead when it isn't nil, e.g. to pick up what was done. If the lock
+// can't be checked, fn runs anyway.
+func (s *Scheduler) Shared(name string, interval time.Duration, fn, follow func(ctx context.Context) error) {
+	if s.locker == nil {
+		s.Every(name, interval, fn)
+		return
+	}
+	ttl := interval - interval/10
+	s.Every(name, interval, func(ctx context.Context) error {
+		ok, err := s.locker.Lock(ctx, "job:"+name, ttl)
+		if err != nil {
+			log.Printf("job %s: lock: %v", name, err)
+			ok = true
+		}
+		switch {
+		case ok:
+			return fn(ctx)
+		case follow != nil:
+			return follow(ctx)
+		}
+		return nil
+	})
+}
+
+// Stop cancels running tasks and waits for them to return.
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
@@ -0,0 +1,147 @@
+// Package jolpica is a client for the Jolpica-F1 API, the community-run
+// successor to Ergast. It is the server's historical data provider: season
+// entries, standings and results going back to 1950.
+package jolpica
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/budget"
+)
+
+// DefaultBaseURL is the public Ergast-compatible endpoint.
+const DefaultBaseURL = "https://api.jolpi.ca/ergast/f1"
+
+// Jolpica allows a handful of requests per second; stay politely under it.
+const minInterval = 300 * time.Millisecond
+
+// Client fetches and caches Jolpica responses. Data for past seasons never
+// changes, so it is cached for the life of the process; the current season is
+// refreshed after CurrentTTL.
+type Client struct {
+	BaseURL    string
+	HTTP       *http.Client
+	CurrentTTL time.Duration
+	// Budget, when set, counts upstream calls; cache hits are free.
+	Budget *budget.Budget
+
+	mu    sync.Mutex
+	cache map[string]cached
+
+	throttle sync.Mutex
+	last     time.Time
+}
+
+type cached struct {
+	body    []byte
+	expires time.Time // zero for permanent entries
+}
+
+// New creates a Client for baseURL.
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    baseURL,
+		HTTP:       &http.Client{Timeout: 30 * time.Second},
+		CurrentTTL: time.Hour,
+		cache:      make(map[string]cached),
+	}
+}
+
+// get fetches path (relative to BaseURL, without ".json") and decodes the
+// MRData object into out. season is used to decide how long to cache.
+func (c *Client) get(ctx context.Context, path string, season int, out any) error {
+	body, err := c.fetch(ctx, path, season)
+	if err != nil {
+		return err
+	}
+
+	var envelope struct {
+		MRData json.RawMessage `json:"MRData"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("jolpica %s: %w", path, err)
+	}
+	if err := json.Unmarshal(envelope.MRData, out); err != nil {
+		return fmt.Errorf("jolpica %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Client) fetch(ctx context.Context, path string, season int) ([]byte, error) {
+	url := c.BaseURL + path
+	if entry, ok := c.lookup(url); ok {
+		return entry, nil
+	}
+
+	if err := c.Budget.Take(ctx); err != nil {
+		return nil, fmt.Errorf("jolpica %s: %w", path, err)
+	}
+	c.wait()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("jolpica %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jolpica %s: status %d", path, resp.StatusCode)
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("jolpica %s: %w", path, err)
+	}
+	c.store(url, raw, season)
+	return raw, nil
+}
+
+func (c *Client) lookup(url string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.cache[url]
+	if !ok || (!entry.expires.IsZero() && time.Now().After(entry.expires)) {
+		return nil, false
+	}
+	return entry.body, true
+}
+
+func (c *Client) store(url string, body []byte, season int) {
+	entry := cached{body: body}
+	if season == 0 || season >= time.Now().Year() {
+		entry.expires = time.Now().Add(c.CurrentTTL)
+	}
+	c.mu.Lock()
+	c.cache[url] = entry
+	c.mu.Unlock()
+}
+
+// wait spaces out upstream calls to respect the public rate limit.
+func (c *Client) wait() {
+	c.throttle.Lock()
+	defer c.throttle.Unlock()
+	if d := minInterval - time.Since(c.last); d > 0 {
+		time.Sleep(d)
+	}
+	c.last = time.Now()
+}
+
+// atoi parses the numeric strings Ergast uses everywhere, returning 0 on error.
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// atof parses numeric strings such as points ("25", "12.5").
+func atof(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
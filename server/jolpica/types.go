@@ -0,0 +1,347 @@
+package jolpica
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Driver is a driver record as returned by Jolpica.
+type Driver struct {
+	DriverID        string `json:"driverId"`
+	PermanentNumber string `json:"permanentNumber"`
+	Code            string `json:"code"`
+	GivenName       string `json:"givenName"`
+	FamilyName      string `json:"familyName"`
+	DateOfBirth     string `json:"dateOfBirth"`
+	Nationality     string `json:"nationality"`
+}
+
+// Name returns "Given Family".
+func (d Driver) Name() string {
+	return d.GivenName + " " + d.FamilyName
+}
+
+// Constructor is a constructor record as returned by Jolpica.
+type Constructor struct {
+	ConstructorID string `json:"constructorId"`
+	Name          string `json:"name"`
+	Nationality   string `json:"nationality"`
+}
+
+// DriverStanding is one row of a season's drivers' championship. Constructors
+// lists every team the driver drove for that season, in order.
+type DriverStanding struct {
+	Position     string        `json:"position"`
+	Points       string        `json:"points"`
+	Wins         string        `json:"wins"`
+	Driver       Driver        `json:"Driver"`
+	Constructors []Constructor `json:"Constructors"`
+}
+
+// PositionInt returns the numeric championship position (0 if unclassified).
+func (s DriverStanding) PositionInt() int { return atoi(s.Position) }
+
+// PointsFloat returns the points total.
+func (s DriverStanding) PointsFloat() float64 { return atof(s.Points) }
+
+type standingsTable struct {
+	StandingsTable struct {
+		StandingsLists []struct {
+			Season          string           `json:"season"`
+			Round           string           `json:"round"`
+			DriverStandings []DriverStanding `json:"DriverStandings"`
+		} `json:"StandingsLists"`
+	} `json:"StandingsTable"`
+}
+
+// DriverStandings returns the drivers' championship for a season, as of the
+// latest completed round.
+func (c *Client) DriverStandings(ctx context.Context, season int) ([]DriverStanding, error) {
+	var table standingsTable
+	if err := c.get(ctx, fmt.Sprintf("/%d/driverstandings.json?limit=100", season), season, &table); err != nil {
+		return nil, err
+	}
+	lists := table.StandingsTable.StandingsLists
+	if len(lists) == 0 {
+		return nil, nil
+	}
+	return lists[0].DriverStandings, nil
+}
+
+type seasonTable struct {
+	SeasonTable struct {
+		Seasons []struct {
+			Season string `json:"season"`
+		} `json:"Seasons"`
+	} `json:"SeasonTable"`
+}
+
+// DriverSeasons returns every season a driver took part in, oldest first.
+func (c *Client) DriverSeasons(ctx context.Context, driverID string) ([]int, error) {
+	var table seasonTable
+	if err := c.get(ctx, fmt.Sprintf("/drivers/%s/seasons.json?limit=100", driverID), 0, &table); err != nil {
+		return nil, err
+	}
+	seasons := make([]int, 0, len(table.SeasonTable.Seasons))
+	for _, s := range table.SeasonTable.Seasons {
+		seasons = append(seasons, atoi(s.Season))
+	}
+	return seasons, nil
+}
+
+// ConstructorStanding is one row of a season's constructors' championship.
+type ConstructorStanding struct {
+	Position    string      `json:"position"`
+	Points      string      `json:"points"`
+	Wins        string      `json:"wins"`
+	Constructor Constructor `json:"Constructor"`
+}
+
+// PointsFloat returns the points total.
+func (s ConstructorStanding) PointsFloat() float64 { return atof(s.Points) }
+
+type positionTable struct {
+	StandingsTable struct {
+		StandingsLists []struct {
+			Season               string                `json:"season"`
+			DriverStandings      []DriverStanding      `json:"DriverStandings"`
+			ConstructorStandings []ConstructorStanding `json:"ConstructorStandings"`
+		} `json:"StandingsLists"`
+	} `json:"StandingsTable"`
+}
+
+type constructorStandingsTable struct {
+	StandingsTable struct {
+		StandingsLists []struct {
+			ConstructorStandings []ConstructorStanding `json:"ConstructorStandings"`
+		} `json:"StandingsLists"`
+	} `json:"StandingsTable"`
+}
+
+// ConstructorStandings returns the constructors' championship for a season,
+// as of the latest completed round.
+func (c *Client) ConstructorStandings(ctx context.Context, season int) ([]ConstructorStanding, error) {
+	var table constructorStandingsTable
+	if err := c.get(ctx, fmt.Sprintf("/%d/constructorstandings.json?limit=100", season), season, &table); err != nil {
+		return nil, err
+	}
+	lists := table.StandingsTable.StandingsLists
+	if len(lists) == 0 {
+		return nil, nil
+	}
+	return lists[0].ConstructorStandings, nil
+}
+
+// DriversAtPosition returns, for every season, the driver classified at
+// position in the final (or current) drivers' standings.
+func (c *Client) DriversAtPosition(ctx context.Context, position int) (map[int]DriverStanding, error) {
+	var table positionTable
+	if err := c.get(ctx, fmt.Sprintf("/driverstandings/%d.json?limit=100", position), 0, &table); err != nil {
+		return nil, err
+	}
+	out := make(map[int]DriverStanding)
+	for _, list := range table.StandingsTable.StandingsLists {
+		if len(list.DriverStandings) > 0 {
+			out[atoi(list.Season)] = list.DriverStandings[0]
+		}
+	}
+	return out, nil
+}
+
+// ConstructorsAtPosition returns, for every season since the constructors'
+// championship began in 1958, the team classified at position.
+func (c *Client) ConstructorsAtPosition(ctx context.Context, position int) (map[int]ConstructorStanding, error) {
+	var table positionTable
+	if err := c.get(ctx, fmt.Sprintf("/constructorstandings/%d.json?limit=100", position), 0, &table); err != nil {
+		return nil, err
+	}
+	out := make(map[int]ConstructorStanding)
+	for _, list := range table.StandingsTable.StandingsLists {
+		if len(list.ConstructorStandings) > 0 {
+			out[atoi(list.Season)] = list.ConstructorStandings[0]
+		}
+	}
+	return out, nil
+}
+
+// Result is one driver's classification in a race.
+type Result struct {
+	Number      string      `json:"number"`
+	Position    string      `json:"position"`
+	Points      string      `json:"points"`
+	Grid        string      `json:"grid"`
+	Laps        string      `json:"laps"`
+	Status      string      `json:"status"`
+	Driver      Driver      `json:"Driver"`
+	Constructor Constructor `json:"Constructor"`
+	// Time is the race time, or the gap to the winner; only finishers on
+	// the lead lap have one.
+	Time *struct {
+		Time string `json:"time"`
+	} `json:"Time"`
+	// FastestLap is only reported since 2004.
+	FastestLap *struct {
+		Rank string `json:"rank"`
+		Time struct {
+			Time string `json:"time"`
+		} `json:"Time"`
+	} `json:"FastestLap"`
+}
+
+// PositionInt returns the finishing position.
+func (r Result) PositionInt() int { return atoi(r.Position) }
+
+// Finished reports whether the driver saw the flag, laps down or not.
+func (r Result) Finished() bool {
+	return r.Status == "Finished" || strings.HasPrefix(r.Status, "+")
+}
+
+// FastestLapRank returns where the driver's fastest lap ranked, or 0 when
+// it isn't known.
+func (r Result) FastestLapRank() int {
+	if r.FastestLap == nil {
+		return 0
+	}
+	return atoi(r.FastestLap.Rank)
+}
+
+// PointsFloat returns the points scored.
+func (r Result) PointsFloat() float64 { return atof(r.Points) }
+
+// Circuit is where a race is held.
+type Circuit struct {
+	CircuitID   string `json:"circuitId"`
+	CircuitName string `json:"circuitName"`
+	Location    struct {
+		Locality string `json:"locality"`
+		Country  string `json:"country"`
+	} `json:"Location"`
+}
+
+// Race is a grand prix with (some of) its results.
+type Race struct {
+	Season   string   `json:"season"`
+	Round    string   `json:"round"`
+	RaceName string   `json:"raceName"`
+	Date     string   `json:"date"`
+	Circuit  Circuit  `json:"Circuit"`
+	Results  []Result `json:"Results"`
+	// SprintResults is only filled by SeasonSprints.
+	SprintResults []Result `json:"SprintResults"`
+	// QualifyingResults is only filled by SeasonPoles.
+	QualifyingResults []Result `json:"QualifyingResults"`
+}
+
+// SeasonInt returns the season year.
+func (r Race) SeasonInt() int { return atoi(r.Season) }
+
+// RoundInt returns the round number.
+func (r Race) RoundInt() int { return atoi(r.Round) }
+
+type raceTable struct {
+	Total     string `json:"total"`
+	RaceTable struct {
+		Races []Race `json:"Races"`
+	} `json:"RaceTable"`
+}
+
+// pageSize is the largest page Jolpica serves.
+const pageSize = 100
+
+// races fetches every page of a results query, merging races split across
+// page boundaries. path must not carry limit/offset parameters.
+func (c *Client) races(ctx context.Context, path string, season int) ([]Race, error) {
+	var races []Race
+	for offset := 0; ; offset += pageSize {
+		var table raceTable
+		if err := c.get(ctx, fmt.Sprintf("%s?limit=%d&offset=%d", path, pageSize, offset), season, &table); err != nil {
+			return nil, err
+		}
+		for _, race := range table.RaceTable.Races {
+			if n := len(races); n > 0 && races[n-1].Season == race.Season && races[n-1].Round == race.Round {
+				races[n-1].Results = append(races[n-1].Results, race.Results...)
+				races[n-1].SprintResults = append(races[n-1].SprintResults, race.SprintResults...)
+				races[n-1].QualifyingResults = append(races[n-1].QualifyingResults, race.QualifyingResults...)
+				continue
+			}
+			races = append(races, race)
+		}
+		if offset+pageSize >= atoi(table.Total) {
+			return races, nil
+		}
+	}
+}
+
+// Winners returns every championship race since 1950 with only its winner.
+func (c *Client) Winners(ctx context.Context) ([]Race, error) {
+	return c.races(ctx, "/results/1.json", 0)
+}
+
+// SeasonResults returns every race of a season with full classifications.
+func (c *Client) SeasonResults(ctx context.Context, season int) ([]Race, error) {
+	return c.races(ctx, fmt.Sprintf("/%d/results.json", season), season)
+}
+
+// SeasonSprints returns every sprint of a season with full classifications
+// in SprintResults.
+func (c *Client) SeasonSprints(ctx context.Context, season int) ([]Race, error) {
+	return c.races(ctx, fmt.Sprintf("/%d/sprint.json", season), season)
+}
+
+// SeasonPoles returns every qualifying of a season with only its fastest
+// driver in QualifyingResults. Jolpica has qualifying since 1994.
+func (c *Client) SeasonPoles(ctx context.Context, season int) ([]Race, error) {
+	return c.races(ctx, fmt.Sprintf("/%d/qualifying/1.json", season), season)
+}
+
+// RaceResults returns one race with its full classification, or nil if the
+// round hasn't been run.
+func (c *Client) RaceResults(ctx context.Context, season, round int) (*Race, error) {
+	races, err := c.races(ctx, fmt.Sprintf("/%d/%d/results.json", season, round), season)
+	if err != nil || len(races) == 0 {
+		return nil, err
+	}
+	return &races[0], nil
+}
+
+// Session is the date and UTC time of one session of a race weekend.
+type Session struct {
+	Date string `json:"date"`
+	Time string `json:"time"`
+}
+
+// ScheduledRace is a calendar entry. Optional sessions are nil when the
+// weekend doesn't have them.
+type ScheduledRace struct {
+	Season           string   `json:"season"`
+	Round            string   `json:"round"`
+	RaceName         string   `json:"raceName"`
+	Date             string   `json:"date"`
+	Time             string   `json:"time"`
+	FirstPractice    *Session `json:"FirstPractice"`
+	SecondPractice   *Session `json:"SecondPractice"`
+	ThirdPractice    *Session `json:"ThirdPractice"`
+	Qualifying       *Session `json:"Qualifying"`
+	Sprint           *Session `json:"Sprint"`
+	SprintQualifying *Session `json:"SprintQualifying"`
+	Circuit          Circuit  `json:"Circuit"`
+}
+
+// RoundInt returns the round number.
+func (r ScheduledRace) RoundInt() int { return atoi(r.Round) }
+
+type scheduleTable struct {
+	RaceTable struct {
+		Races []ScheduledRace `json:"Races"`
+	} `json:"RaceTable"`
+}
+
+// Schedule returns a season's calendar.
+func (c *Client) Schedule(ctx context.Context, season int) ([]ScheduledRace, error) {
+	var table scheduleTable
+	if err := c.get(ctx, fmt.Sprintf("/%d.json?limit=100", season), season, &table); err != nil {
+		return nil, err
+	}
+	return table.RaceTable.Races, nil
+}
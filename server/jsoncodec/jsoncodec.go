@@ -0,0 +1,148 @@
+// Package jsoncodec is the JSON implementation on the gateway's hot paths:
+// transforming proxied responses, computed analyses and Redis cache entries.
+// Telemetry payloads run to tens of megabytes, where encoding/json is slow,
+// so jsoniter is used by default; Standard stays available should the two
+// ever disagree.
+package jsoncodec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Codec encodes and decodes JSON the way encoding/json does: map keys
+// sorted and HTML characters escaped.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+	// UnmarshalNumber decodes a document with numbers kept as json.Number,
+	// so they are written back exactly as they were.
+	UnmarshalNumber(data []byte) (any, error)
+	Valid(data []byte) bool
+}
+
+// Standard is encoding/json.
+var Standard Codec = standard{}
+
+// Iterator is jsoniter in its encoding/json compatible mode.
+var Iterator Codec = iterator{
+	api: jsoniter.ConfigCompatibleWithStandardLibrary,
+	numbers: jsoniter.Config{
+		EscapeHTML:             true,
+		SortMapKeys:            true,
+		ValidateJsonRawMessage: true,
+		UseNumber:              true,
+	}.Froze(),
+}
+
+// Default is the codec in use, chosen at startup with JSON_CODEC.
+var Default = Iterator
+
+// ByName returns "std" or "jsoniter".
+func ByName(name string) (Codec, bool) {
+	switch name {
+	case "std":
+		return Standard, true
+	case "jsoniter":
+		return Iterator, true
+	}
+	return nil, false
+}
+
+type standard struct{}
+
+func (standard) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (standard) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (standard) Valid(data []byte) bool             { return json.Valid(data) }
+
+func (standard) UnmarshalNumber(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	err := dec.Decode(&v)
+	return v, err
+}
+
+type iterator struct {
+	api     jsoniter.API
+	numbers jsoniter.API
+}
+
+func (c iterator) Marshal(v any) ([]byte, error)      { return c.api.Marshal(v) }
+func (c iterator) Unmarshal(data []byte, v any) error { return c.api.Unmarshal(data, v) }
+
+// Valid uses encoding/json's scanner, which checks without building anything
+// and runs about twice as fast as jsoniter's (BenchmarkValid).
+func (iterator) Valid(data []byte) bool { return json.Valid(data) }
+
+func (c iterator) UnmarshalNumber(data []byte) (any, error) {
+	var v any
+	err := c.numbers.Unmarshal(data, &v)
+	return v, err
+}
+
+// Encode writes a decoded document to w with c. Arrays at the top of
+// the document, or directly under its top-level keys, are written an
+// element at a time, so the encoded copy of a large payload is never held
+// in memory whole.
+func Encode(c Codec, w io.Writer, doc any) error {
+	bw := bufio.NewWriterSize(w, 32<<10)
+	if err := encode(c, bw, doc, 1); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func encode(c Codec, w *bufio.Writer, v any, depth int) error {
+	switch v := v.(type) {
+	case []any:
+		w.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := write(c, w, item); err != nil {
+				return err
+			}
+		}
+		return w.WriteByte(']')
+	case map[string]any:
+		if depth == 0 {
+			break
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		w.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := write(c, w, k); err != nil {
+				return err
+			}
+			w.WriteByte(':')
+			if err := encode(c, w, v[k], depth-1); err != nil {
+				return err
+			}
+		}
+		return w.WriteByte('}')
+	}
+	return write(c, w, v)
+}
+
+func write(c Codec, w *bufio.Writer, v any) error {
+	data, err := c.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,153 @@
+package jsoncodec_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ekjyotshinh/f1-server/demo"
+	"github.com/ekjyotshinh/f1-server/jsoncodec"
+	jsoniter "github.com/json-iterator/go"
+)
+
+var codecs = []struct {
+	name  string
+	codec jsoncodec.Codec
+}{
+	{"std", jsoncodec.Standard},
+	{"jsoniter", jsoncodec.Iterator},
+}
+
+// payloads are typical responses: race results and analytics from the
+// data service, and driver standings as Jolpica sends them.
+func payloads(tb testing.TB) map[string][]byte {
+	race, ok := demo.Lookup("/api/race/2021/22")
+	if !ok {
+		tb.Fatal("no demo race")
+	}
+	analytics, ok := demo.Lookup("/api/analytics/2021/22")
+	if !ok {
+		tb.Fatal("no demo analytics")
+	}
+	return map[string][]byte{
+		"race":      race,
+		"analytics": analytics,
+		"standings": standings(20),
+	}
+}
+
+func standings(drivers int) []byte {
+	rows := make([]string, drivers)
+	for i := range rows {
+		rows[i] = fmt.Sprintf(`{"position":"%[1]d","positionText":"%[1]d","points":"%[2]d","wins":"%[3]d",`+
+			`"Driver":{"driverId":"driver_%[1]d","permanentNumber":"%[1]d","code":"D%02[1]d","givenName":"Given","familyName":"Family %[1]d","dateOfBirth":"1997-09-30","nationality":"Dutch"},`+
+			`"Constructors":[{"constructorId":"team_%[4]d","name":"Team %[4]d","nationality":"Austrian"}]}`,
+			i+1, 400-i*18, max(0, 10-i), i/2)
+	}
+	return []byte(`{"MRData":{"series":"f1","StandingsTable":{"season":"2021","StandingsLists":[{"season":"2021","round":"22","DriverStandings":[` +
+		strings.Join(rows, ",") + `]}]}}}`)
+}
+
+// TestSameOutput checks that the codecs can stand in for each other: a
+// response decoded by either is written back byte for byte the same by
+// both, whole or streamed.
+func TestSameOutput(t *testing.T) {
+	cases := payloads(t)
+	// Strings encoding/json escapes, and numbers written as they came
+	cases["escapes"] = []byte(`{"name":"<Pérez & Sainz>","note":"line\u2028break\ttab \"quoted\" \ud83c\udfc1",` +
+		`"laps":[1e21,-0,0.1,12345678901234567890,-3.5E-7],"empty":{},"none":null,"flags":[true,false]}`)
+	for name, body := range cases {
+		for _, decoder := range codecs {
+			doc, err := decoder.codec.UnmarshalNumber(body)
+			if err != nil {
+				t.Fatalf("%s: %s decode: %v", name, decoder.name, err)
+			}
+			std, err := jsoncodec.Standard.Marshal(doc)
+			if err != nil {
+				t.Fatalf("%s: std marshal: %v", name, err)
+			}
+			for _, c := range codecs {
+				got, err := c.codec.Marshal(doc)
+				if err != nil {
+					t.Fatalf("%s: %s marshal: %v", name, c.name, err)
+				}
+				if !bytes.Equal(got, std) {
+					t.Errorf("%s decoded by %s: %s Marshal differs from encoding/json:\n%s\n%s", name, decoder.name, c.name, got, std)
+				}
+				var buf bytes.Buffer
+				if err := jsoncodec.Encode(c.codec, &buf, doc); err != nil {
+					t.Fatalf("%s: %s encode: %v", name, c.name, err)
+				}
+				if !bytes.Equal(buf.Bytes(), std) {
+					t.Errorf("%s decoded by %s: %s Encode differs from encoding/json:\n%s\n%s", name, decoder.name, c.name, buf.Bytes(), std)
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkUnmarshalNumber decodes a response the way transform.Parse does.
+func BenchmarkUnmarshalNumber(b *testing.B) {
+	for name, body := range payloads(b) {
+		for _, c := range codecs {
+			b.Run(name+"/"+c.name, func(b *testing.B) {
+				b.SetBytes(int64(len(body)))
+				b.ReportAllocs()
+				for range b.N {
+					if _, err := c.codec.UnmarshalNumber(body); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkEncode writes a decoded response back out the way transform.Write
+// does.
+func BenchmarkEncode(b *testing.B) {
+	for name, body := range payloads(b) {
+		doc, err := jsoncodec.Standard.UnmarshalNumber(body)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, c := range codecs {
+			b.Run(name+"/"+c.name, func(b *testing.B) {
+				b.SetBytes(int64(len(body)))
+				b.ReportAllocs()
+				for range b.N {
+					if err := jsoncodec.Encode(c.codec, io.Discard, doc); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkValid checks a response before it is cached, with encoding/json's
+// scanner, which both codecs use, and with jsoniter's, which they don't.
+func BenchmarkValid(b *testing.B) {
+	validators := []struct {
+		name  string
+		valid func([]byte) bool
+	}{
+		{"std", json.Valid},
+		{"jsoniter", jsoniter.ConfigCompatibleWithStandardLibrary.Valid},
+	}
+	for name, body := range payloads(b) {
+		for _, v := range validators {
+			b.Run(name+"/"+v.name, func(b *testing.B) {
+				b.SetBytes(int64(len(body)))
+				for range b.N {
+					if !v.valid(body) {
+						b.Fatal("invalid")
+					}
+				}
+			})
+		}
+	}
+}
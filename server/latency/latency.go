@@ -0,0 +1,293 @@
+// Package latency records upstream latency samples and reports percentiles
+// over time, optionally persisting samples to disk so history survives restarts.
+package latency
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sample is a single upstream call.
+type Sample struct {
+	At       time.Time `json:"at"`
+	Route    string    `json:"route"`
+	Duration float64   `json:"ms"`
+	Status   int       `json:"status"` // 0 when the upstream could not be reached
+}
+
+// Recorder keeps samples in memory up to a retention window and, when a path
+// is configured, appends every sample to a JSON-lines file.
+type Recorder struct {
+	mu        sync.Mutex
+	samples   []Sample
+	retention time.Duration
+	path      string
+	file      *os.File
+}
+
+// NewRecorder creates a Recorder keeping samples for retention. If path is not
+// empty, previously persisted samples are loaded from it and new ones appended.
+func NewRecorder(path string, retention time.Duration) (*Recorder, error) {
+	rec := &Recorder{retention: retention, path: path}
+	if path == "" {
+		return rec, nil
+	}
+
+	if err := rec.load(path); err != nil {
+		return nil, err
+	}
+	// Rewrite the file with only the retained samples so it doesn't grow forever
+	if err := rec.compact(path); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open latency log: %w", err)
+	}
+	rec.file = f
+	return rec, nil
+}
+
+// Observe records an upstream call to route that took d.
+func (r *Recorder) Observe(route string, status int, d time.Duration) {
+	s := Sample{
+		At:       time.Now().UTC(),
+		Route:    route,
+		Duration: float64(d.Microseconds()) / 1000,
+		Status:   status,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prune(s.At)
+	r.samples = append(r.samples, s)
+	if r.file != nil {
+		if line, err := json.Marshal(s); err == nil {
+			if _, err := r.file.Write(append(line, '\n')); err != nil {
+				log.Printf("latency: failed to persist sample: %v", err)
+			}
+		}
+	}
+}
+
+// Compact rewrites the persisted log with only the retained samples, so it
+// stays within the retention window between restarts too.
+func (r *Recorder) Compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	r.prune(time.Now().UTC())
+	if err := r.compact(r.path); err != nil {
+		return err
+	}
+	// The file being appended to was replaced
+	f, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open latency log: %w", err)
+	}
+	r.file.Close()
+	r.file = f
+	return nil
+}
+
+// Close closes the persisted log, if any.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// Bucket holds the percentiles of one time slice of a report.
+type Bucket struct {
+	Start  time.Time `json:"start"`
+	Count  int       `json:"count"`
+	Errors int       `json:"errors"`
+	P50    float64   `json:"p50_ms"`
+	P90    float64   `json:"p90_ms"`
+	P99    float64   `json:"p99_ms"`
+	Max    float64   `json:"max_ms"`
+}
+
+// Report is the latency history of the routes matching a prefix.
+type Report struct {
+	Route   string   `json:"route"`
+	Window  string   `json:"window"`
+	Bucket  string   `json:"bucket"`
+	Overall Bucket   `json:"overall"`
+	Buckets []Bucket `json:"buckets"`
+}
+
+// MinBucket and MaxBuckets bound the buckets a report can be split into, so
+// callers taking them from requests must check them first.
+const (
+	MinBucket  = time.Minute
+	MaxBuckets = 2000
+)
+
+// Report summarises samples whose route starts with routePrefix over the
+// trailing window, split into buckets of the given size.
+func (r *Recorder) Report(routePrefix string, window, bucket time.Duration) Report {
+	now := time.Now().UTC()
+	since := now.Add(-window)
+
+	r.mu.Lock()
+	var matched []Sample
+	for _, s := range r.samples {
+		if s.At.After(since) && strings.HasPrefix(s.Route, routePrefix) {
+			matched = append(matched, s)
+		}
+	}
+	r.mu.Unlock()
+
+	report := Report{
+		Route:   routePrefix,
+		Window:  FormatDuration(window),
+		Bucket:  FormatDuration(bucket),
+		Overall: summarise(since, matched),
+		Buckets: []Bucket{},
+	}
+	// Samples are in time order, so each bucket is a run of them
+	first := since.Truncate(bucket)
+	for i := 0; i < len(matched); {
+		n := matched[i].At.Sub(first) / bucket
+		j := i + 1
+		for j < len(matched) && matched[j].At.Sub(first)/bucket == n {
+			j++
+		}
+		report.Buckets = append(report.Buckets, summarise(first.Add(n*bucket), matched[i:j]))
+		i = j
+	}
+	return report
+}
+
+func summarise(start time.Time, samples []Sample) Bucket {
+	b := Bucket{Start: start, Count: len(samples)}
+	if len(samples) == 0 {
+		return b
+	}
+	durations := make([]float64, len(samples))
+	for i, s := range samples {
+		durations[i] = s.Duration
+		if s.Status == 0 || s.Status >= 500 {
+			b.Errors++
+		}
+	}
+	sort.Float64s(durations)
+	b.P50 = percentile(durations, 0.50)
+	b.P90 = percentile(durations, 0.90)
+	b.P99 = percentile(durations, 0.99)
+	b.Max = durations[len(durations)-1]
+	return b
+}
+
+// percentile uses the nearest-rank method on sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	idx := int(float64(len(sorted))*p+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// prune drops samples older than the retention window. Callers hold r.mu.
+func (r *Recorder) prune(now time.Time) {
+	cutoff := now.Add(-r.retention)
+	i := sort.Search(len(r.samples), func(i int) bool {
+		return r.samples[i].At.After(cutoff)
+	})
+	if i > 0 {
+		r.samples = append(r.samples[:0], r.samples[i:]...)
+	}
+}
+
+func (r *Recorder) load(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open latency log: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var s Sample
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+			continue // skip a torn final line from an unclean shutdown
+		}
+		r.samples = append(r.samples, s)
+	}
+	sort.SliceStable(r.samples, func(i, j int) bool {
+		return r.samples[i].At.Before(r.samples[j].At)
+	})
+	r.prune(time.Now().UTC())
+	return scanner.Err()
+}
+
+func (r *Recorder) compact(path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("compact latency log: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, s := range r.samples {
+		if err := enc.Encode(s); err != nil {
+			f.Close()
+			return fmt.Errorf("compact latency log: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("compact latency log: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("compact latency log: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// ParseDuration extends time.ParseDuration with a "d" (day) unit, e.g. "7d".
+func ParseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// FormatDuration renders whole days as "Nd" and anything else as time.Duration does.
+func FormatDuration(d time.Duration) string {
+	if d >= 24*time.Hour && d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
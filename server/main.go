@@ -1,159 +1,92 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"io"
+	"log"
+	"log/slog"
+	"net"
 	"net/http"
-	"time"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
-)
-
-const (
-	// Use localhost for development, Railway URL for production
-	//pythonServiceURL = "http://localhost:8000"
-	pythonServiceURL = "https://python-data-service-production.up.railway.app" // Production
-	serverPort       = ":3000"
+	"github.com/ekjyotshinh/f1-server/config"
+	"github.com/ekjyotshinh/f1-server/jsoncodec"
+	"github.com/ekjyotshinh/f1-server/server"
 )
 
 func main() {
-	r := gin.Default()
-
-	// CORS configuration
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"https://ekjyotshinh.github.io", "http://localhost:3000", "http://localhost:5173"}, // Added localhost origins
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
-
-	r.GET("/", func(c *gin.Context) {
-		c.String(http.StatusOK, "F1 Dashboard API (Go/Gin)")
-	})
-
-	// Proxy handler for years
-	r.GET("/api/years", func(c *gin.Context) {
-		targetURL := fmt.Sprintf("%s/api/years", pythonServiceURL)
-		proxyRequest(c, targetURL)
-	})
-
-	// Proxy handler for schedule
-	r.GET("/api/schedule/:year", func(c *gin.Context) {
-		year := c.Param("year")
-		targetURL := fmt.Sprintf("%s/api/schedule/%s", pythonServiceURL, year)
-		proxyRequest(c, targetURL)
-	})
-
-	// Proxy handler for race data
-	r.GET("/api/race/:year/:race_name", func(c *gin.Context) {
-		year := c.Param("year")
-		raceName := c.Param("race_name")
-
-		targetURL := fmt.Sprintf("%s/api/race/%s/%s", pythonServiceURL, year, raceName)
-		proxyRequest(c, targetURL)
-	})
-
-	// Proxy handler for analytics
-	r.GET("/api/analytics/:year/:race_name", func(c *gin.Context) {
-		year := c.Param("year")
-		raceName := c.Param("race_name")
+	demoMode := flag.Bool("demo", false, "serve the embedded sample season instead of the data service")
+	flag.Parse()
 
-		targetURL := fmt.Sprintf("%s/api/analytics/%s/%s", pythonServiceURL, year, raceName)
-		proxyRequest(c, targetURL)
-	})
-
-	// Proxy handler for telemetry (live race replay)
-	r.GET("/api/telemetry/:year/:race_name", func(c *gin.Context) {
-		year := c.Param("year")
-		raceName := c.Param("race_name")
-
-		targetURL := fmt.Sprintf("%s/api/telemetry/%s/%s", pythonServiceURL, year, raceName)
-		proxyRequest(c, targetURL)
-	})
-
-	// Proxy handler for chunked telemetry (progressive loading)
-	r.GET("/api/telemetry/:year/:race_name/chunk/:chunk_num", func(c *gin.Context) {
-		year := c.Param("year")
-		raceName := c.Param("race_name")
-		chunkNum := c.Param("chunk_num")
-
-		targetURL := fmt.Sprintf("%s/api/telemetry/%s/%s/chunk/%s", pythonServiceURL, year, raceName, chunkNum)
-		proxyRequest(c, targetURL)
-	})
-
-
-	// Admin endpoint - clear cache
-	r.POST("/api/clear-cache", func(c *gin.Context) {
-		proxyClearCache(c, pythonServiceURL+"/api/clear-cache")
-	})
-
-	fmt.Printf("Server running on http://localhost%s\n", serverPort)
-	r.Run(serverPort)
-}
-
-func proxyRequest(c *gin.Context, targetURL string) {
-	// Create HTTP client with longer timeout for FastF1 data loading
-	client := &http.Client{
-		Timeout: 600 * time.Second, // 10 minutes for chunked telemetry loading
-	}
-	
-	resp, err := client.Get(targetURL)
+	// Settings come from the environment (and CONFIG_FILE), see package config
+	cfg, err := config.Load()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to reach data service: %v", err)})
-		return
+		log.Fatalf("Invalid configuration:\n%v", err)
 	}
-	defer resp.Body.Close()
+	// log.Printf goes through the structured logger too
+	slog.SetDefault(cfg.Logger())
+	jsoncodec.Default = cfg.Codec()
+	cfg.Server.Demo = cfg.Server.Demo || *demoMode
 
-	if resp.StatusCode != http.StatusOK {
-		c.JSON(resp.StatusCode, gin.H{"error": "Data service returned error"})
-		return
+	srv, err := server.New(cfg.Server)
+	if err != nil {
+		log.Fatalf("Failed to build server: %v", err)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	ln, err := listen(cfg)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read response body"})
-		return
+		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	// Pass through Cache-Control headers from the data service
-	if cacheControl := resp.Header.Get("Cache-Control"); cacheControl != "" {
-		c.Header("Cache-Control", cacheControl)
+	// Requests get a context that is only cancelled once draining gives up,
+	// which stops their upstream calls too
+	base, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hs := &http.Server{
+		Handler:     srv,
+		BaseContext: func(net.Listener) context.Context { return base },
 	}
-
-	c.Data(resp.StatusCode, "application/json", body)
-}
-
-func proxyClearCache(c *gin.Context, targetURL string) {
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	errc := make(chan error, 1)
+	go func() { errc <- hs.Serve(ln) }()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	select {
+	case err := <-errc:
+		log.Fatal(err)
+	case <-ctx.Done():
 	}
-
-	// Create POST request
-	req, err := http.NewRequest("POST", targetURL, nil)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create request: %v", err)})
-		return
+	// A second signal kills the process straight away
+	stop()
+
+	log.Printf("Shutting down, draining requests for up to %s", cfg.ShutdownTimeout)
+	drain, cancelDrain := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelDrain()
+	if err := hs.Shutdown(drain); err != nil {
+		log.Printf("Drain timed out, cancelling remaining requests: %v", err)
+		cancel()
+		hs.Close()
 	}
-
-	// Execute request
-	resp, err := client.Do(req)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to reach data service: %v", err)})
-		return
+	if err := srv.Close(); err != nil {
+		log.Printf("Failed to close server: %v", err)
 	}
-	defer resp.Body.Close()
+}
 
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read response body"})
-		return
+// listen opens the configured TCP port or Unix socket. A socket file left
+// behind by a previous run is replaced.
+func listen(cfg config.Config) (net.Listener, error) {
+	if cfg.Socket == "" {
+		fmt.Printf("Server running on http://localhost%s\n", cfg.Addr())
+		return net.Listen("tcp", cfg.Addr())
 	}
-
-	c.Data(resp.StatusCode, "application/json", body)
+	if info, err := os.Stat(cfg.Socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(cfg.Socket)
+	}
+	fmt.Printf("Server running on unix:%s\n", cfg.Socket)
+	if cfg.Server.TrustedPlatform == "" {
+		log.Print("listen: requests over a Unix socket have no client IP without TRUSTED_PLATFORM, so per-IP rate limits and abuse bans don't apply")
+	}
+	return net.Listen("unix", cfg.Socket)
 }
-
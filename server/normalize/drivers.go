@@ -0,0 +1,123 @@
+// Package normalize holds reference metadata for reconciling and enriching
+// data from different sources: drivers, nationalities, teams and colours.
+package normalize
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Driver is the reference record for a driver, keyed by the three-letter
+// abbreviation used in timing data.
+type Driver struct {
+	Code        string `json:"code"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	Nationality string `json:"nationality"`
+	CountryCode string `json:"country_code"` // three-letter code as shown on F1 graphics
+	ISOCountry  string `json:"iso_country"`  // ISO 3166-1 alpha-2, used for flags
+	DateOfBirth string `json:"date_of_birth"`
+}
+
+// FullName returns "First Last".
+func (d Driver) FullName() string {
+	return d.FirstName + " " + d.LastName
+}
+
+// Flag returns the driver's flag emoji.
+func (d Driver) Flag() string {
+	return Flag(d.ISOCountry)
+}
+
+// drivers covers everyone who has raced in the seasons the dashboard offers.
+var drivers = []Driver{
+	{"AIT", "Jack", "Aitken", "British", "GBR", "GB", "1995-09-23"},
+	{"ALB", "Alexander", "Albon", "Thai", "THA", "TH", "1996-03-23"},
+	{"ALO", "Fernando", "Alonso", "Spanish", "ESP", "ES", "1981-07-29"},
+	{"ANT", "Andrea Kimi", "Antonelli", "Italian", "ITA", "IT", "2006-08-25"},
+	{"BEA", "Oliver", "Bearman", "British", "GBR", "GB", "2005-05-08"},
+	{"BOR", "Gabriel", "Bortoleto", "Brazilian", "BRA", "BR", "2004-10-14"},
+	{"BOT", "Valtteri", "Bottas", "Finnish", "FIN", "FI", "1989-08-28"},
+	{"COL", "Franco", "Colapinto", "Argentine", "ARG", "AR", "2003-05-27"},
+	{"DEV", "Nyck", "de Vries", "Dutch", "NED", "NL", "1995-02-06"},
+	{"DOO", "Jack", "Doohan", "Australian", "AUS", "AU", "2003-01-20"},
+	{"ERI", "Marcus", "Ericsson", "Swedish", "SWE", "SE", "1990-09-02"},
+	{"FIT", "Pietro", "Fittipaldi", "Brazilian", "BRA", "BR", "1996-06-25"},
+	{"GAS", "Pierre", "Gasly", "French", "FRA", "FR", "1996-02-07"},
+	{"GIO", "Antonio", "Giovinazzi", "Italian", "ITA", "IT", "1993-12-14"},
+	{"GRO", "Romain", "Grosjean", "French", "FRA", "FR", "1986-04-17"},
+	{"HAD", "Isack", "Hadjar", "French", "FRA", "FR", "2004-09-28"},
+	{"HAM", "Lewis", "Hamilton", "British", "GBR", "GB", "1985-01-07"},
+	{"HAR", "Brendon", "Hartley", "New Zealander", "NZL", "NZ", "1989-11-10"},
+	{"HUL", "Nico", "Hülkenberg", "German", "GER", "DE", "1987-08-19"},
+	{"KUB", "Robert", "Kubica", "Polish", "POL", "PL", "1984-12-07"},
+	{"KVY", "Daniil", "Kvyat", "Russian", "RUS", "RU", "1994-04-26"},
+	{"LAT", "Nicholas", "Latifi", "Canadian", "CAN", "CA", "1995-06-29"},
+	{"LAW", "Liam", "Lawson", "New Zealander", "NZL", "NZ", "2002-02-11"},
+	{"LEC", "Charles", "Leclerc", "Monegasque", "MON", "MC", "1997-10-16"},
+	{"MAG", "Kevin", "Magnussen", "Danish", "DEN", "DK", "1992-10-05"},
+	{"MAZ", "Nikita", "Mazepin", "Russian", "RUS", "RU", "1999-03-02"},
+	{"NOR", "Lando", "Norris", "British", "GBR", "GB", "1999-11-13"},
+	{"OCO", "Esteban", "Ocon", "French", "FRA", "FR", "1996-09-17"},
+	{"PER", "Sergio", "Pérez", "Mexican", "MEX", "MX", "1990-01-26"},
+	{"PIA", "Oscar", "Piastri", "Australian", "AUS", "AU", "2001-04-06"},
+	{"RAI", "Kimi", "Räikkönen", "Finnish", "FIN", "FI", "1979-10-17"},
+	{"RIC", "Daniel", "Ricciardo", "Australian", "AUS", "AU", "1989-07-01"},
+	{"RUS", "George", "Russell", "British", "GBR", "GB", "1998-02-15"},
+	{"SAI", "Carlos", "Sainz", "Spanish", "ESP", "ES", "1994-09-01"},
+	{"SAR", "Logan", "Sargeant", "American", "USA", "US", "2000-12-31"},
+	{"SCH", "Mick", "Schumacher", "German", "GER", "DE", "1999-03-22"},
+	{"SIR", "Sergey", "Sirotkin", "Russian", "RUS", "RU", "1995-08-27"},
+	{"STR", "Lance", "Stroll", "Canadian", "CAN", "CA", "1998-10-29"},
+	{"TSU", "Yuki", "Tsunoda", "Japanese", "JPN", "JP", "2000-05-11"},
+	{"VAN", "Stoffel", "Vandoorne", "Belgian", "BEL", "BE", "1992-03-26"},
+	{"VER", "Max", "Verstappen", "Dutch", "NED", "NL", "1997-09-30"},
+	{"VET", "Sebastian", "Vettel", "German", "GER", "DE", "1987-07-03"},
+	{"ZHO", "Guanyu", "Zhou", "Chinese", "CHN", "CN", "1999-05-30"},
+}
+
+var driversByCode = func() map[string]Driver {
+	m := make(map[string]Driver, len(drivers))
+	for _, d := range drivers {
+		m[d.Code] = d
+	}
+	return m
+}()
+
+// DriverByCode looks a driver up by abbreviation, e.g. "VER".
+func DriverByCode(code string) (Driver, bool) {
+	d, ok := driversByCode[strings.ToUpper(code)]
+	return d, ok
+}
+
+// Drivers returns every known driver, ordered by code.
+func Drivers() []Driver {
+	return append([]Driver(nil), drivers...)
+}
+
+// DriversBornOn returns the drivers whose birthday is day of month, ordered
+// by code.
+func DriversBornOn(month time.Month, day int) []Driver {
+	suffix := fmt.Sprintf("-%02d-%02d", month, day)
+	var out []Driver
+	for _, d := range drivers {
+		if strings.HasSuffix(d.DateOfBirth, suffix) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Flag converts an ISO 3166-1 alpha-2 code into its flag emoji.
+func Flag(iso string) string {
+	if len(iso) != 2 {
+		return ""
+	}
+	iso = strings.ToUpper(iso)
+	const regionalIndicatorA = 0x1F1E6
+	return string([]rune{
+		rune(regionalIndicatorA + int(iso[0]-'A')),
+		rune(regionalIndicatorA + int(iso[1]-'A')),
+	})
+}
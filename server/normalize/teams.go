@@ -0,0 +1,135 @@
+package normalize
+
+import "strings"
+
+// teamColors maps team names as they appear in timing data to their livery
+// colour (hex, no leading #). Older names keep the colour of their era.
+var teamColors = map[string]string{
+	"Red Bull Racing":   "3671C6",
+	"Mercedes":          "27F4D2",
+	"Ferrari":           "E8002D",
+	"McLaren":           "FF8000",
+	"Aston Martin":      "229971",
+	"Alpine":            "0093CC",
+	"Williams":          "64C4FF",
+	"RB":                "6692FF",
+	"Racing Bulls":      "6692FF",
+	"Kick Sauber":       "52E252",
+	"Haas F1 Team":      "B6BABD",
+	"AlphaTauri":        "5E8FAA",
+	"Alfa Romeo":        "C92D4B",
+	"Alfa Romeo Racing": "900000",
+	"Racing Point":      "F596C8",
+	"Renault":           "FFF500",
+	"Toro Rosso":        "469BFF",
+	"Force India":       "F596C8",
+	"Sauber":            "9B0000",
+}
+
+// TeamColor returns the livery colour for a team name.
+func TeamColor(team string) (string, bool) {
+	if c, ok := teamColors[team]; ok {
+		return c, true
+	}
+	for name, c := range teamColors {
+		if strings.EqualFold(name, team) {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// Era is one name a constructor raced under. To is 0 for the current name.
+type Era struct {
+	Name     string `json:"name"`
+	ErgastID string `json:"ergast_id"`
+	From     int    `json:"from"`
+	To       int    `json:"to,omitempty"`
+}
+
+// Lineage links the successive identities of one team through rebrands and
+// ownership changes, so multi-season stats can follow the entry rather than
+// the name. ID is a stable key; Name is the current identity.
+type Lineage struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Eras []Era  `json:"eras"`
+}
+
+var lineages = []Lineage{
+	{ID: "alpine", Name: "Alpine", Eras: []Era{
+		{"Toleman", "toleman", 1981, 1985},
+		{"Benetton", "benetton", 1986, 2001},
+		{"Renault", "renault", 2002, 2011},
+		{"Lotus F1", "lotus_f1", 2012, 2015},
+		{"Renault", "renault", 2016, 2020},
+		{"Alpine", "alpine", 2021, 0},
+	}},
+	{ID: "aston_martin", Name: "Aston Martin", Eras: []Era{
+		{"Jordan", "jordan", 1991, 2005},
+		{"MF1", "mf1", 2006, 2006},
+		{"Spyker", "spyker", 2007, 2007},
+		{"Force India", "force_india", 2008, 2018},
+		{"Racing Point", "racing_point", 2019, 2020},
+		{"Aston Martin", "aston_martin", 2021, 0},
+	}},
+	{ID: "ferrari", Name: "Ferrari", Eras: []Era{
+		{"Ferrari", "ferrari", 1950, 0},
+	}},
+	{ID: "haas", Name: "Haas F1 Team", Eras: []Era{
+		{"Haas F1 Team", "haas", 2016, 0},
+	}},
+	{ID: "mclaren", Name: "McLaren", Eras: []Era{
+		{"McLaren", "mclaren", 1966, 0},
+	}},
+	{ID: "mercedes", Name: "Mercedes", Eras: []Era{
+		{"Tyrrell", "tyrrell", 1970, 1998},
+		{"BAR", "bar", 1999, 2005},
+		{"Honda", "honda", 2006, 2008},
+		{"Brawn", "brawn", 2009, 2009},
+		{"Mercedes", "mercedes", 2010, 0},
+	}},
+	{ID: "racing_bulls", Name: "Racing Bulls", Eras: []Era{
+		{"Minardi", "minardi", 1985, 2005},
+		{"Toro Rosso", "toro_rosso", 2006, 2019},
+		{"AlphaTauri", "alphatauri", 2020, 2023},
+		{"RB", "rb", 2024, 2024},
+		{"Racing Bulls", "rb", 2025, 0},
+	}},
+	{ID: "red_bull", Name: "Red Bull Racing", Eras: []Era{
+		{"Stewart", "stewart", 1997, 1999},
+		{"Jaguar", "jaguar", 2000, 2004},
+		{"Red Bull Racing", "red_bull", 2005, 0},
+	}},
+	{ID: "sauber", Name: "Kick Sauber", Eras: []Era{
+		{"Sauber", "sauber", 1993, 2005},
+		{"BMW Sauber", "bmw_sauber", 2006, 2010},
+		{"Sauber", "sauber", 2011, 2018},
+		{"Alfa Romeo Racing", "alfa", 2019, 2021},
+		{"Alfa Romeo", "alfa", 2022, 2023},
+		{"Kick Sauber", "sauber", 2024, 0},
+	}},
+	{ID: "williams", Name: "Williams", Eras: []Era{
+		{"Williams", "williams", 1977, 0},
+	}},
+}
+
+// Lineages returns every known team lineage, ordered by ID.
+func Lineages() []Lineage {
+	return append([]Lineage(nil), lineages...)
+}
+
+// LineageOf finds the lineage a team name or Ergast constructor ID belongs to.
+func LineageOf(team string) (Lineage, bool) {
+	for _, l := range lineages {
+		if strings.EqualFold(l.ID, team) {
+			return l, true
+		}
+		for _, e := range l.Eras {
+			if strings.EqualFold(e.Name, team) || strings.EqualFold(e.ErgastID, team) {
+				return l, true
+			}
+		}
+	}
+	return Lineage{}, false
+}
@@ -0,0 +1,116 @@
+// Package ratelimit caps how fast each client may call the API, with a token
+// bucket per client and route group.
+package ratelimit
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/gin-gonic/gin"
+)
+
+// Limit is a token bucket: Burst requests at once, refilled at Rate per
+// second. A Rate <= 0 means unlimited.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// DefaultGroup is the limit used for groups without one of their own.
+const DefaultGroup = "default"
+
+type bucketKey struct {
+	group, client string
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// sweepEvery is how often buckets that have refilled are forgotten, so idle
+// clients don't accumulate.
+const sweepEvery = time.Minute
+
+// Limiter holds the buckets of every client.
+type Limiter struct {
+	limits map[string]Limit
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// New creates a Limiter enforcing limits, keyed by route group.
+func New(limits map[string]Limit) *Limiter {
+	return &Limiter{limits: limits, buckets: make(map[bucketKey]*bucket), now: time.Now}
+}
+
+// Allow takes a token from client's bucket for group. When the bucket is
+// empty it returns false and how long until the next token.
+func (l *Limiter) Allow(group, client string) (bool, time.Duration) {
+	limit, ok := l.limits[group]
+	if !ok {
+		group, limit = DefaultGroup, l.limits[DefaultGroup]
+	}
+	if limit.Rate <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) > sweepEvery {
+		l.sweep(now)
+	}
+	key := bucketKey{group, client}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep forgets buckets that would be full by now. Callers hold l.mu.
+func (l *Limiter) sweep(now time.Time) {
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		limit, ok := l.limits[key.group]
+		if !ok || b.tokens+now.Sub(b.last).Seconds()*limit.Rate >= float64(limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Middleware rejects clients over the limit of the group their request
+// falls in with a 429 and Retry-After. Clients are told apart by client,
+// e.g. by IP. Requests it can't place, such as those over a Unix socket
+// with no client IP, aren't limited rather than all sharing one bucket.
+func (l *Limiter) Middleware(group, client func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := client(c)
+		if id == "" {
+			c.Next()
+			return
+		}
+		ok, wait := l.Allow(group(c), id)
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			apierror.RateLimited.Abort(c, "Too many requests")
+			return
+		}
+		c.Next()
+	}
+}
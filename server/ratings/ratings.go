@@ -0,0 +1,254 @@
+// Package ratings maintains Elo-style driver ratings updated race by race,
+// optionally persisting them to disk so restarts only process new races.
+package ratings
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/jolpica"
+)
+
+const (
+	// Initial is the rating of a driver's first race.
+	Initial = 1500
+	// k is the most a driver can gain or lose in one race.
+	k = 32
+)
+
+// Point is a driver's rating after one race.
+type Point struct {
+	Season int     `json:"season"`
+	Round  int     `json:"round"`
+	Race   string  `json:"race"`
+	Rating float64 `json:"rating"`
+}
+
+// Rating is a driver's current standing.
+type Rating struct {
+	DriverID string  `json:"driver_id"`
+	Code     string  `json:"code,omitempty"`
+	Name     string  `json:"name"`
+	Rating   float64 `json:"rating"`
+	Peak     float64 `json:"peak"`
+	Races    int     `json:"races"`
+	Last     Point   `json:"last_race"`
+}
+
+type state struct {
+	UpdatedAt time.Time          `json:"updated_at"`
+	Season    int                `json:"season"` // last race applied
+	Round     int                `json:"round"`
+	Drivers   map[string]*Rating `json:"drivers"`
+	Timelines map[string][]Point `json:"timelines"`
+}
+
+// Engine applies races in order and keeps every driver's rating history.
+type Engine struct {
+	mu   sync.RWMutex
+	path string
+	st   state
+}
+
+// Open creates an Engine. If path is not empty, ratings are loaded from it and
+// saved back after every update.
+func Open(path string) (*Engine, error) {
+	e := &Engine{path: path, st: state{
+		Drivers:   make(map[string]*Rating),
+		Timelines: make(map[string][]Point),
+	}}
+	if path == "" {
+		return e, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return e, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open ratings: %w", err)
+	}
+	if err := json.Unmarshal(data, &e.st); err != nil {
+		return nil, fmt.Errorf("open ratings: %w", err)
+	}
+	return e, nil
+}
+
+// LastRace returns the season and round of the latest race applied, or zeros
+// when the engine is empty.
+func (e *Engine) LastRace() (season, round int) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.st.Season, e.st.Round
+}
+
+// Apply updates ratings with every race newer than the last one applied and
+// returns how many were new.
+func (e *Engine) Apply(races []jolpica.Race) (int, error) {
+	sort.SliceStable(races, func(i, j int) bool {
+		if races[i].SeasonInt() != races[j].SeasonInt() {
+			return races[i].SeasonInt() < races[j].SeasonInt()
+		}
+		return races[i].RoundInt() < races[j].RoundInt()
+	})
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	applied := 0
+	for _, race := range races {
+		season, round := race.SeasonInt(), race.RoundInt()
+		if season < e.st.Season || (season == e.st.Season && round <= e.st.Round) {
+			continue
+		}
+		e.apply(race)
+		e.st.Season, e.st.Round = season, round
+		applied++
+	}
+	if applied == 0 {
+		return 0, nil
+	}
+	e.st.UpdatedAt = time.Now().UTC()
+	return applied, e.save()
+}
+
+// apply scores every pair of classified drivers as a head-to-head: finishing
+// ahead is a win. Gains are scaled so a full grid moves at most k points.
+// Callers hold e.mu.
+func (e *Engine) apply(race jolpica.Race) {
+	var field []jolpica.Result
+	for _, r := range race.Results {
+		if r.PositionInt() > 0 {
+			field = append(field, r)
+		}
+	}
+	if len(field) < 2 {
+		return
+	}
+
+	before := make([]float64, len(field))
+	for i, r := range field {
+		before[i] = Initial
+		if d, ok := e.st.Drivers[r.Driver.DriverID]; ok {
+			before[i] = d.Rating
+		}
+	}
+
+	scale := k / float64(len(field)-1)
+	point := Point{Season: race.SeasonInt(), Round: race.RoundInt(), Race: race.RaceName}
+	for i, r := range field {
+		var delta float64
+		for j, other := range field {
+			if i == j {
+				continue
+			}
+			expected := 1 / (1 + math.Pow(10, (before[j]-before[i])/400))
+			score := 0.0
+			if r.PositionInt() < other.PositionInt() {
+				score = 1
+			}
+			delta += scale * (score - expected)
+		}
+
+		id := r.Driver.DriverID
+		d, ok := e.st.Drivers[id]
+		if !ok {
+			d = &Rating{DriverID: id, Rating: Initial, Peak: Initial}
+			e.st.Drivers[id] = d
+		}
+		d.Code, d.Name = r.Driver.Code, r.Driver.Name()
+		d.Rating = math.Round((before[i]+delta)*10) / 10
+		d.Peak = math.Max(d.Peak, d.Rating)
+		d.Races++
+		point.Rating = d.Rating
+		d.Last = point
+		e.st.Timelines[id] = append(e.st.Timelines[id], point)
+	}
+}
+
+// Top returns the n highest-rated drivers whose last race was in season or
+// later (0 for any season).
+func (e *Engine) Top(n, season int) []Rating {
+	e.mu.RLock()
+	out := make([]Rating, 0, len(e.st.Drivers))
+	for _, d := range e.st.Drivers {
+		if d.Last.Season >= season {
+			out = append(out, *d)
+		}
+	}
+	e.mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Rating != out[j].Rating {
+			return out[i].Rating > out[j].Rating
+		}
+		return out[i].DriverID < out[j].DriverID
+	})
+	if n > 0 && len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// Timeline returns a driver's rating after each of their races.
+func (e *Engine) Timeline(driverID string) (Rating, []Point, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	d, ok := e.st.Drivers[driverID]
+	if !ok {
+		return Rating{}, nil, false
+	}
+	return *d, append([]Point(nil), e.st.Timelines[driverID]...), true
+}
+
+// UpdatedAt returns when ratings last changed.
+func (e *Engine) UpdatedAt() time.Time {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.st.UpdatedAt
+}
+
+// Export returns the ratings for Import into another Engine.
+func (e *Engine) Export() ([]byte, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return json.Marshal(e.st)
+}
+
+// Import replaces the ratings with exported ones that are further along,
+// saving them if the Engine persists.
+func (e *Engine) Import(data []byte) error {
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("import ratings: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if st.Season < e.st.Season || (st.Season == e.st.Season && st.Round <= e.st.Round) {
+		return nil
+	}
+	e.st = st
+	return e.save()
+}
+
+// save writes the state atomically. Callers hold e.mu.
+func (e *Engine) save() error {
+	if e.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(e.st)
+	if err != nil {
+		return fmt.Errorf("save ratings: %w", err)
+	}
+	tmp := e.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("save ratings: %w", err)
+	}
+	return os.Rename(tmp, e.path)
+}
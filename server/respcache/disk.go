@@ -0,0 +1,126 @@
+package respcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Disk keeps response bodies in files under a directory, one per key, for
+// data that will never change. Entries survive restarts and are never
+// expired; Prune evicts the least recently used ones to bound the space
+// they take, and Clear removes them all.
+type Disk struct {
+	dir string
+}
+
+// OpenDisk uses dir, creating it if needed.
+func OpenDisk(dir string) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Disk{dir: dir}, nil
+}
+
+func (d *Disk) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Load returns key's body, marking it used.
+func (d *Disk) Load(key string) ([]byte, bool) {
+	path := d.path(key)
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return body, true
+}
+
+// Save stores body under key. The file is written aside and renamed into
+// place, so a crash never leaves a partial body behind.
+func (d *Disk) Save(key string, body []byte) error {
+	f, err := os.CreateTemp(d.dir, ".save-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(body); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), d.path(key))
+}
+
+// Prune removes the least recently used entries until the rest take up at
+// most max bytes.
+func (d *Disk) Prune(max int64) error {
+	files, err := os.ReadDir(d.dir)
+	if err != nil {
+		return err
+	}
+	type entry struct {
+		name string
+		size int64
+		used time.Time
+	}
+	var entries []entry
+	var total int64
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue // removed meanwhile
+		}
+		entries = append(entries, entry{f.Name(), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+	slices.SortFunc(entries, func(a, b entry) int { return a.used.Compare(b.used) })
+	for _, e := range entries {
+		if total <= max {
+			break
+		}
+		if err := os.Remove(filepath.Join(d.dir, e.name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		total -= e.size
+	}
+	return nil
+}
+
+// Delete removes key's entry, if there is one.
+func (d *Disk) Delete(key string) error {
+	if err := os.Remove(d.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Clear removes every entry.
+func (d *Disk) Clear() error {
+	files, err := os.ReadDir(d.dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), ".json") {
+			if err := os.Remove(filepath.Join(d.dir, f.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package respcache
+
+import (
+	"container/list"
+	"context"
+	"slices"
+	"time"
+)
+
+type item struct {
+	key     string
+	entry   Entry
+	expires time.Time
+}
+
+// memory is a size-bounded LRU split into shards, each with its own lock.
+// Eviction is per shard: a shard that is full drops its least recently used
+// entry, which may be newer than another shard's.
+type memory struct {
+	shards [shards]memoryShard
+}
+
+type memoryShard struct {
+	max int
+
+	mu    countedMutex
+	order *list.List // most recently used first
+	items map[string]*list.Element
+}
+
+// Memory creates an in-process Backend holding up to max entries, rounded
+// up to a multiple of the shard count. max <= 0 disables caching.
+func Memory(max int) Backend {
+	m := &memory{}
+	for i := range m.shards {
+		m.shards[i] = memoryShard{
+			max:   (max + shards - 1) / shards,
+			order: list.New(),
+			items: make(map[string]*list.Element),
+		}
+	}
+	return m
+}
+
+func (m *memory) Load(_ context.Context, key string) (Entry, bool) {
+	s := &m.shards[shardOf(key)]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.items[key]
+	if !ok {
+		return Entry{}, false
+	}
+	it := el.Value.(*item)
+	if time.Now().After(it.expires) {
+		s.remove(el)
+		return Entry{}, false
+	}
+	s.order.MoveToFront(el)
+	return it.entry, true
+}
+
+func (m *memory) Save(_ context.Context, key string, entry Entry, keep time.Duration) {
+	s := &m.shards[shardOf(key)]
+	if s.max <= 0 {
+		return
+	}
+	it := &item{key: key, entry: entry, expires: time.Now().Add(keep)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[key]; ok {
+		el.Value = it
+		s.order.MoveToFront(el)
+		return
+	}
+	s.items[key] = s.order.PushFront(it)
+	for s.order.Len() > s.max {
+		s.remove(s.order.Back())
+	}
+}
+
+func (m *memory) Delete(_ context.Context, keys ...string) {
+	for _, key := range keys {
+		s := &m.shards[shardOf(key)]
+		s.mu.Lock()
+		if el, ok := s.items[key]; ok {
+			s.remove(el)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// TakeDependents looks through every entry; it runs when data changes, not
+// per request.
+func (m *memory) TakeDependents(_ context.Context, node string) []string {
+	var keys []string
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.Lock()
+		for key, el := range s.items {
+			if slices.Contains(el.Value.(*item).entry.Deps, node) {
+				keys = append(keys, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+	return keys
+}
+
+func (m *memory) Clear(context.Context) {
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.Lock()
+		s.order.Init()
+		s.items = make(map[string]*list.Element)
+		s.mu.Unlock()
+	}
+}
+
+func (m *memory) Ping(context.Context) error { return nil }
+
+func (m *memory) lockStats() []LockStats {
+	mutexes := make([]*countedMutex, len(m.shards))
+	for i := range m.shards {
+		mutexes[i] = &m.shards[i].mu
+	}
+	return stats("entries", mutexes)
+}
+
+func (s *memoryShard) remove(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.items, el.Value.(*item).key)
+}
@@ -0,0 +1,210 @@
+// Package respcache keeps recent data service responses so repeat dashboard
+// loads don't wait on the upstream. An entry past its TTL is still served
+// while a single background refresh replaces it. Entries live in memory by
+// default, or in Redis so several replicas share them.
+package respcache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// State says how a lookup was answered.
+type State int
+
+const (
+	Miss State = iota
+	Hit
+	// Stale entries are past their TTL but within the stale allowance; the
+	// caller should serve them and revalidate.
+	Stale
+)
+
+func (s State) String() string {
+	switch s {
+	case Hit:
+		return "HIT"
+	case Stale:
+		return "STALE"
+	}
+	return "MISS"
+}
+
+// Entry is a cached upstream response.
+type Entry struct {
+	Body         []byte        `json:"body"`
+	CacheControl string        `json:"cache_control,omitempty"`
+	Stored       time.Time     `json:"stored"`
+	TTL          time.Duration `json:"ttl"`
+	// Deps are what the entry was built from, other keys or names of data
+	// that isn't cached itself; see Cache.Invalidate.
+	Deps []string `json:"deps,omitempty"`
+}
+
+// Backend stores entries. Backends may drop entries at any time; keep is how
+// long an entry is worth keeping at all.
+type Backend interface {
+	Load(ctx context.Context, key string) (Entry, bool)
+	Save(ctx context.Context, key string, entry Entry, keep time.Duration)
+	Clear(ctx context.Context)
+	// Delete drops the entries under keys.
+	Delete(ctx context.Context, keys ...string)
+	// TakeDependents returns the keys of entries saved with node among
+	// their Deps. Keys taken may not be returned again.
+	TakeDependents(ctx context.Context, node string) []string
+	// Ping reports whether the backend can be reached.
+	Ping(ctx context.Context) error
+}
+
+// separateSize bounds a Separate backend kept in memory. It holds bans, job
+// results and the few responses held back as suspect.
+const separateSize = 64 * shards
+
+// Separate returns a Backend for data kept alongside backend's responses
+// that must outlive them, such as bans and job results: backend's Clear, and
+// so Cache.Purge, leaves it alone. With Redis its keys go under "f1:<name>:";
+// otherwise it is a backend of its own in memory.
+func Separate(backend Backend, name string) Backend {
+	if r, ok := backend.(*redisBackend); ok {
+		return &redisBackend{client: r.client, prefix: "f1:" + name + ":"}
+	}
+	return Memory(separateSize)
+}
+
+// Cache adds freshness and background revalidation to a Backend.
+type Cache struct {
+	backend  Backend
+	maxStale time.Duration
+
+	mu         sync.Mutex
+	refreshing map[string]bool
+	now        func() time.Time
+
+	usage *usage
+}
+
+// New creates a Cache over backend whose entries are served for at most
+// maxStale past their TTL.
+func New(backend Backend, maxStale time.Duration) *Cache {
+	return &Cache{
+		backend:    backend,
+		maxStale:   maxStale,
+		refreshing: make(map[string]bool),
+		now:        time.Now,
+		usage:      newUsage(),
+	}
+}
+
+// Get looks up key.
+func (c *Cache) Get(ctx context.Context, key string) (Entry, State) {
+	entry, ok := c.backend.Load(ctx, key)
+	if !ok {
+		return Entry{}, Miss
+	}
+	now := c.now()
+	switch age := now.Sub(entry.Stored); {
+	case age < entry.TTL:
+		c.usage.hit(now, key, len(entry.Body))
+		return entry, Hit
+	case age < entry.TTL+c.maxStale:
+		c.usage.hit(now, key, len(entry.Body))
+		return entry, Stale
+	}
+	return Entry{}, Miss
+}
+
+// Set stores body under key for ttl, built from deps.
+func (c *Cache) Set(ctx context.Context, key string, body []byte, cacheControl string, ttl time.Duration, deps ...string) {
+	if ttl <= 0 {
+		return
+	}
+	entry := Entry{Body: body, CacheControl: cacheControl, Stored: c.now(), TTL: ttl, Deps: deps}
+	c.backend.Save(ctx, key, entry, ttl+c.maxStale)
+	c.usage.set(entry.Stored, key, len(body), ttl+c.maxStale)
+}
+
+// Peek returns key's entry however old it is, without counting a hit.
+func (c *Cache) Peek(ctx context.Context, key string) (Entry, bool) {
+	return c.backend.Load(ctx, key)
+}
+
+// Invalidate drops the entries built from node, and those built from them
+// in turn, returning their keys. node itself is left alone: it is a key
+// whose data has just been replaced, or names data that isn't cached.
+func (c *Cache) Invalidate(ctx context.Context, node string) []string {
+	var dropped []string
+	seen := map[string]bool{node: true}
+	for queue := []string{node}; len(queue) > 0; queue = queue[1:] {
+		for _, key := range c.backend.TakeDependents(ctx, queue[0]) {
+			if !seen[key] {
+				seen[key] = true
+				dropped = append(dropped, key)
+				queue = append(queue, key)
+			}
+		}
+	}
+	if len(dropped) > 0 {
+		c.backend.Delete(ctx, dropped...)
+	}
+	return dropped
+}
+
+// Delete drops the entries under keys.
+func (c *Cache) Delete(ctx context.Context, keys ...string) {
+	c.backend.Delete(ctx, keys...)
+}
+
+// Fetched records that a miss on key was answered by the upstream with size
+// bytes after elapsed, for the usage report.
+func (c *Cache) Fetched(key string, size int, elapsed time.Duration) {
+	c.usage.miss(c.now(), key, size, elapsed)
+}
+
+// Report summarises this process's cache traffic over the last window,
+// listing top keys in each ranking.
+func (c *Cache) Report(window time.Duration, top int) Report {
+	return c.usage.report(c.now(), window, top)
+}
+
+// Revalidate runs refresh in the background unless this process is already
+// refreshing key. refresh is responsible for calling Set.
+func (c *Cache) Revalidate(key string, refresh func()) {
+	c.mu.Lock()
+	if c.refreshing[key] {
+		c.mu.Unlock()
+		return
+	}
+	c.refreshing[key] = true
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.refreshing, key)
+			c.mu.Unlock()
+		}()
+		refresh()
+	}()
+}
+
+// Purge drops every entry.
+func (c *Cache) Purge(ctx context.Context) {
+	c.backend.Clear(ctx)
+	c.usage.clear()
+}
+
+// LockStats reports how contended the cache's locks have been, per shard:
+// the usage counters' and, in memory, the entries'.
+func (c *Cache) LockStats() []LockStats {
+	out := c.usage.lockStats()
+	if r, ok := c.backend.(lockReporter); ok {
+		out = append(out, r.lockStats()...)
+	}
+	return out
+}
+
+// Ping checks the backend is reachable.
+func (c *Cache) Ping(ctx context.Context) error {
+	return c.backend.Ping(ctx)
+}
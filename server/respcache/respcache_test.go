@@ -0,0 +1,76 @@
+package respcache_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/respcache"
+)
+
+// keys are as many distinct responses as a busy race weekend keeps cached.
+var keys = func() []string {
+	keys := make([]string, 500)
+	for i := range keys {
+		keys[i] = "/api/race/2024/" + strconv.Itoa(i)
+	}
+	return keys
+}()
+
+var body = make([]byte, 16<<10)
+
+// filled is a cache holding every key, with room to spare since keys don't
+// spread evenly over the shards.
+func filled() *respcache.Cache {
+	c := respcache.New(respcache.Memory(4*len(keys)), time.Hour)
+	for _, key := range keys {
+		c.Set(context.Background(), key, body, "", time.Hour)
+	}
+	return c
+}
+
+// BenchmarkGet is a cache hit from many requests at once, as after a race.
+func BenchmarkGet(b *testing.B) {
+	c := filled()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := context.Background()
+		for i := 0; pb.Next(); i++ {
+			if _, state := c.Get(ctx, keys[i%len(keys)]); state != respcache.Hit {
+				b.Errorf("state %s", state)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkSet stores responses, evicting the oldest once full.
+func BenchmarkSet(b *testing.B) {
+	c := filled()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := context.Background()
+		for i := 0; pb.Next(); i++ {
+			c.Set(ctx, keys[i%len(keys)]+"?n="+strconv.Itoa(i), body, "", time.Hour)
+		}
+	})
+}
+
+// BenchmarkDisk reads an archived race back, as a miss in memory does.
+func BenchmarkDisk(b *testing.B) {
+	d, err := respcache.OpenDisk(b.TempDir())
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := d.Save(keys[0], body); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	for range b.N {
+		if _, ok := d.Load(keys[0]); !ok {
+			b.Fatal("not archived")
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package respcache
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// shards is how many independently locked parts the in-memory entries and
+// the usage counters are split into, so that lookups of different keys
+// rarely wait for each other after a race, when every client reloads at
+// once.
+const shards = 16
+
+// shardOf picks key's shard by its FNV-1a hash.
+func shardOf(key string) int {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return int(h % shards)
+}
+
+// countedMutex is a mutex that counts how often it had to be waited for.
+type countedMutex struct {
+	mu       sync.Mutex
+	acquired atomic.Uint64
+	waited   atomic.Uint64
+}
+
+func (m *countedMutex) Lock() {
+	m.acquired.Add(1)
+	if !m.mu.TryLock() {
+		m.waited.Add(1)
+		m.mu.Lock()
+	}
+}
+
+func (m *countedMutex) Unlock() { m.mu.Unlock() }
+
+// LockStats is how contended one shard of the cache has been: how often its
+// lock was taken, and how often it was already held.
+type LockStats struct {
+	Lock     string // "entries" or "usage"
+	Shard    int
+	Acquired uint64
+	Waited   uint64
+}
+
+// lockReporter is implemented by backends with locks of their own.
+type lockReporter interface {
+	lockStats() []LockStats
+}
+
+func stats(lock string, mutexes []*countedMutex) []LockStats {
+	out := make([]LockStats, len(mutexes))
+	for i, m := range mutexes {
+		out[i] = LockStats{Lock: lock, Shard: i, Acquired: m.acquired.Load(), Waited: m.waited.Load()}
+	}
+	return out
+}
@@ -0,0 +1,236 @@
+package respcache
+
+import (
+	"sort"
+	"time"
+)
+
+// usageRetention is how far back usage reports can look.
+const usageRetention = 7 * 24 * time.Hour
+
+// keyUsage counts one key's traffic within an hour.
+type keyUsage struct {
+	hits          int
+	misses        int
+	cacheBytes    int64
+	upstreamBytes int64
+	saved         time.Duration
+}
+
+// stored is what usage knows about an entry this process cached.
+type stored struct {
+	size    int
+	stored  time.Time
+	expires time.Time
+	// fetch is how long the key last took upstream; each hit saves about
+	// that much
+	fetch time.Duration
+}
+
+// usage tracks this process's cache traffic in hourly buckets, sharded by
+// key like the in-memory entries. With a shared Redis backend each replica
+// reports only its own traffic.
+type usage struct {
+	shards [shards]usageShard
+}
+
+type usageShard struct {
+	mu      countedMutex
+	hours   map[time.Time]map[string]*keyUsage
+	entries map[string]*stored
+}
+
+func newUsage() *usage {
+	u := &usage{}
+	for i := range u.shards {
+		u.shards[i] = usageShard{hours: make(map[time.Time]map[string]*keyUsage), entries: make(map[string]*stored)}
+	}
+	return u
+}
+
+// shard returns key's shard, locked.
+func (u *usage) shard(key string) *usageShard {
+	s := &u.shards[shardOf(key)]
+	s.mu.Lock()
+	return s
+}
+
+// bucket returns key's counters for the hour of now. Callers hold s.mu.
+func (s *usageShard) bucket(now time.Time, key string) *keyUsage {
+	hour := now.Truncate(time.Hour)
+	keys, ok := s.hours[hour]
+	if !ok {
+		keys = make(map[string]*keyUsage)
+		s.hours[hour] = keys
+		for h := range s.hours {
+			if now.Sub(h) > usageRetention {
+				delete(s.hours, h)
+			}
+		}
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+	}
+	ku, ok := keys[key]
+	if !ok {
+		ku = &keyUsage{}
+		keys[key] = ku
+	}
+	return ku
+}
+
+func (u *usage) hit(now time.Time, key string, size int) {
+	s := u.shard(key)
+	defer s.mu.Unlock()
+	ku := s.bucket(now, key)
+	ku.hits++
+	ku.cacheBytes += int64(size)
+	if e, ok := s.entries[key]; ok {
+		ku.saved += e.fetch
+	}
+}
+
+func (u *usage) miss(now time.Time, key string, size int, elapsed time.Duration) {
+	s := u.shard(key)
+	defer s.mu.Unlock()
+	ku := s.bucket(now, key)
+	ku.misses++
+	ku.upstreamBytes += int64(size)
+	if e, ok := s.entries[key]; ok {
+		e.fetch = elapsed
+	} else {
+		s.entries[key] = &stored{fetch: elapsed}
+	}
+}
+
+func (u *usage) set(now time.Time, key string, size int, keep time.Duration) {
+	s := u.shard(key)
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		e = &stored{}
+		s.entries[key] = e
+	}
+	e.size, e.stored, e.expires = size, now, now.Add(keep)
+}
+
+func (u *usage) clear() {
+	for i := range u.shards {
+		s := &u.shards[i]
+		s.mu.Lock()
+		s.entries = make(map[string]*stored)
+		s.mu.Unlock()
+	}
+}
+
+func (u *usage) lockStats() []LockStats {
+	mutexes := make([]*countedMutex, len(u.shards))
+	for i := range u.shards {
+		mutexes[i] = &u.shards[i].mu
+	}
+	return stats("usage", mutexes)
+}
+
+// KeyReport is one key's traffic over a report's window.
+type KeyReport struct {
+	Key        string  `json:"key"`
+	Hits       int     `json:"hits"`
+	Misses     int     `json:"misses"`
+	CacheBytes int64   `json:"bytes_from_cache"`
+	SavedMs    float64 `json:"upstream_ms_saved"`
+}
+
+// EntryReport is a cached entry that costs more memory than it earns.
+type EntryReport struct {
+	Key    string    `json:"key"`
+	Bytes  int       `json:"bytes"`
+	Hits   int       `json:"hits"`
+	Stored time.Time `json:"stored"`
+}
+
+// Report summarises cache traffic over a window. Hits include stale
+// entries served while they were refreshed. Bytes are of stored bodies,
+// before transforms and compression.
+type Report struct {
+	Window        string        `json:"window"`
+	Hits          int           `json:"hits"`
+	Misses        int           `json:"misses"`
+	HitRatio      float64       `json:"hit_ratio"`
+	CacheBytes    int64         `json:"bytes_from_cache"`
+	UpstreamBytes int64         `json:"bytes_from_upstream"`
+	SavedSeconds  float64       `json:"upstream_seconds_saved"`
+	Hottest       []KeyReport   `json:"hottest"`
+	Coldest       []EntryReport `json:"coldest_large_entries"`
+}
+
+func (u *usage) report(now time.Time, window time.Duration, top int) Report {
+	byKey := map[string]*KeyReport{}
+	r := Report{Hottest: []KeyReport{}, Coldest: []EntryReport{}}
+	since := now.Add(-window).Truncate(time.Hour)
+	// One shard is locked at a time, so a report never holds up every lookup
+	entries := map[string]stored{}
+	for i := range u.shards {
+		s := &u.shards[i]
+		s.mu.Lock()
+		for hour, keys := range s.hours {
+			if hour.Before(since) {
+				continue
+			}
+			for key, ku := range keys {
+				kr, ok := byKey[key]
+				if !ok {
+					kr = &KeyReport{Key: key}
+					byKey[key] = kr
+				}
+				kr.Hits += ku.hits
+				kr.Misses += ku.misses
+				kr.CacheBytes += ku.cacheBytes
+				kr.SavedMs += float64(ku.saved.Microseconds()) / 1000
+				r.UpstreamBytes += ku.upstreamBytes
+			}
+		}
+		for key, e := range s.entries {
+			entries[key] = *e
+		}
+		s.mu.Unlock()
+	}
+	for _, kr := range byKey {
+		r.Hits += kr.Hits
+		r.Misses += kr.Misses
+		r.CacheBytes += kr.CacheBytes
+		r.SavedSeconds += kr.SavedMs / 1000
+		if kr.Hits > 0 {
+			r.Hottest = append(r.Hottest, *kr)
+		}
+	}
+	if total := r.Hits + r.Misses; total > 0 {
+		r.HitRatio = float64(r.Hits) / float64(total)
+	}
+	sort.Slice(r.Hottest, func(i, j int) bool {
+		if r.Hottest[i].Hits != r.Hottest[j].Hits {
+			return r.Hottest[i].Hits > r.Hottest[j].Hits
+		}
+		return r.Hottest[i].Key < r.Hottest[j].Key
+	})
+	r.Hottest = r.Hottest[:min(top, len(r.Hottest))]
+
+	// Coldest large entries hold the most bytes per hit
+	for key, e := range entries {
+		if e.size == 0 || now.After(e.expires) {
+			continue
+		}
+		er := EntryReport{Key: key, Bytes: e.size, Stored: e.stored}
+		if kr, ok := byKey[key]; ok {
+			er.Hits = kr.Hits
+		}
+		r.Coldest = append(r.Coldest, er)
+	}
+	sort.Slice(r.Coldest, func(i, j int) bool {
+		a, b := r.Coldest[i], r.Coldest[j]
+		return float64(a.Bytes)/float64(a.Hits+1) > float64(b.Bytes)/float64(b.Hits+1)
+	})
+	r.Coldest = r.Coldest[:min(top, len(r.Coldest))]
+	return r
+}
@@ -0,0 +1,213 @@
+// Package sampler keeps the most recent full request/response pairs per
+// route, with credentials scrubbed, so user-reported data bugs can be
+// reproduced exactly. It is off until switched on from the admin API.
+package sampler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBody caps how much of each request and response body is kept.
+const maxBody = 64 << 10
+
+// scrubbed headers are replaced with "[redacted]".
+var scrubbed = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+	"X-Csrf-Token":  true,
+	"X-Signature":   true,
+	"X-Api-Key":     true,
+}
+
+// Sample is one request/response pair.
+type Sample struct {
+	At              time.Time         `json:"at"`
+	Route           string            `json:"route"`
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"request_headers"`
+	RequestBody     string            `json:"request_body,omitempty"`
+	Status          int               `json:"status"`
+	ResponseHeaders map[string]string `json:"response_headers"`
+	ResponseBody    string            `json:"response_body"`
+	Truncated       bool              `json:"truncated"`
+	DurationMs      float64           `json:"duration_ms"`
+	// Replay is a curl command that repeats the request.
+	Replay string `json:"replay"`
+}
+
+type ring struct {
+	samples []Sample
+	next    int
+}
+
+// Sampler holds the last N samples of each route.
+type Sampler struct {
+	enabled atomic.Bool
+	size    int
+
+	mu     sync.Mutex
+	routes map[string]*ring
+}
+
+// New creates a disabled Sampler keeping perRoute samples of each route.
+func New(perRoute int) *Sampler {
+	return &Sampler{size: perRoute, routes: make(map[string]*ring)}
+}
+
+// Enable turns sampling on or off.
+func (s *Sampler) Enable(on bool) { s.enabled.Store(on) }
+
+// Enabled reports whether requests are being sampled.
+func (s *Sampler) Enabled() bool { return s.enabled.Load() }
+
+// Middleware records matched requests while the sampler is enabled.
+func (s *Sampler) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		// Admin routes are skipped so reading samples doesn't sample itself
+		if !s.Enabled() || route == "" || s.size <= 0 || strings.HasPrefix(route, "/api/admin") {
+			c.Next()
+			return
+		}
+
+		var reqBody []byte
+		if c.Request.Body != nil {
+			reqBody, _ = io.ReadAll(io.LimitReader(c.Request.Body, maxBody))
+			c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(reqBody), c.Request.Body))
+		}
+		w := &teeWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		start := time.Now()
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		sample := Sample{
+			At:              start.UTC(),
+			Route:           route,
+			Method:          c.Request.Method,
+			URL:             c.Request.URL.RequestURI(),
+			RequestHeaders:  scrub(c.Request.Header),
+			RequestBody:     string(reqBody),
+			Status:          w.Status(),
+			ResponseHeaders: scrub(w.Header()),
+			ResponseBody:    w.buf.String(),
+			Truncated:       w.truncated,
+			DurationMs:      float64(time.Since(start).Microseconds()) / 1000,
+		}
+		sample.Replay = replay(c.Request, sample)
+		s.add(sample)
+	}
+}
+
+func (s *Sampler) add(sample Sample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.routes[sample.Route]
+	if !ok {
+		r = &ring{}
+		s.routes[sample.Route] = r
+	}
+	if len(r.samples) < s.size {
+		r.samples = append(r.samples, sample)
+		return
+	}
+	r.samples[r.next] = sample
+	r.next = (r.next + 1) % s.size
+}
+
+// Samples returns the samples of routes starting with prefix, newest first.
+func (s *Sampler) Samples(prefix string) []Sample {
+	s.mu.Lock()
+	out := []Sample{}
+	for route, r := range s.routes {
+		if strings.HasPrefix(route, prefix) {
+			out = append(out, r.samples...)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].At.After(out[j].At) })
+	return out
+}
+
+// Clear drops every sample.
+func (s *Sampler) Clear() {
+	s.mu.Lock()
+	s.routes = make(map[string]*ring)
+	s.mu.Unlock()
+}
+
+func scrub(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		if scrubbed[http.CanonicalHeaderKey(k)] {
+			out[k] = "[redacted]"
+			continue
+		}
+		out[k] = strings.Join(v, ", ")
+	}
+	return out
+}
+
+// replay builds a curl command for the request, leaving out scrubbed and
+// hop-by-hop headers.
+func replay(r *http.Request, sample Sample) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	cmd := fmt.Sprintf("curl -X %s '%s://%s%s'", sample.Method, scheme, r.Host, sample.URL)
+	keys := make([]string, 0, len(r.Header))
+	for k := range r.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if scrubbed[k] || k == "Connection" || k == "Content-Length" {
+			continue
+		}
+		cmd += fmt.Sprintf(" -H '%s: %s'", k, strings.ReplaceAll(r.Header.Get(k), "'", `'\''`))
+	}
+	if sample.RequestBody != "" {
+		cmd += fmt.Sprintf(" --data '%s'", strings.ReplaceAll(sample.RequestBody, "'", `'\''`))
+	}
+	return cmd
+}
+
+// teeWriter copies the first maxBody bytes of the response.
+type teeWriter struct {
+	gin.ResponseWriter
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (w *teeWriter) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *teeWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *teeWriter) capture(b []byte) {
+	if room := maxBody - w.buf.Len(); room < len(b) {
+		b = b[:max(room, 0)]
+		w.truncated = true
+	}
+	w.buf.Write(b)
+}
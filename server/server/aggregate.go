@@ -0,0 +1,200 @@
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/ekjyotshinh/f1-server/transform"
+)
+
+// compoundOrder lists tyre compounds from softest to wettest for stable output.
+var compoundOrder = map[string]int{"SOFT": 0, "MEDIUM": 1, "HARD": 2, "INTERMEDIATE": 3, "WET": 4}
+
+type lapGroup struct {
+	Driver   string  `json:"driver,omitempty"`
+	Stint    int     `json:"stint,omitempty"`
+	Compound string  `json:"compound,omitempty"`
+	StartLap int     `json:"start_lap,omitempty"`
+	EndLap   int     `json:"end_lap,omitempty"`
+	Laps     int     `json:"laps"`
+	Value    float64 `json:"value"`
+
+	times []float64
+}
+
+// lapAggregation handles ?agg=avg|median|best&group_by=driver|stint|compound on
+// lap data, replacing the raw per-lap rows with one summary row per group.
+func lapAggregation(q url.Values) (transform.Step, error) {
+	agg, groupBy := q.Get("agg"), q.Get("group_by")
+	if agg == "" {
+		if groupBy != "" {
+			return nil, &transform.ParamError{Param: "group_by", Message: "requires an agg parameter"}
+		}
+		return nil, nil
+	}
+
+	var reduce func([]float64) float64
+	switch agg {
+	case "avg":
+		reduce = mean
+	case "median":
+		reduce = median
+	case "best":
+		reduce = func(v []float64) float64 { return v[0] }
+	default:
+		return nil, &transform.ParamError{Param: "agg", Message: "must be one of avg, median, best"}
+	}
+	if groupBy == "" {
+		groupBy = "driver"
+	}
+	if groupBy != "driver" && groupBy != "stint" && groupBy != "compound" {
+		return nil, &transform.ParamError{Param: "group_by", Message: "must be one of driver, stint, compound"}
+	}
+
+	return func(doc any) any {
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			return doc
+		}
+		groups := groupLaps(obj, groupBy)
+		for _, g := range groups {
+			sort.Float64s(g.times)
+			g.Laps = len(g.times)
+			g.Value = math.Round(reduce(g.times)*1000) / 1000
+		}
+		return map[string]any{
+			"agg":        agg,
+			"group_by":   groupBy,
+			"total_laps": obj["total_laps"],
+			"groups":     groups,
+		}
+	}, nil
+}
+
+type stintStart struct {
+	lap      int
+	stint    int
+	compound string
+}
+
+func groupLaps(doc map[string]any, groupBy string) []*lapGroup {
+	lapTimes, _ := doc["lap_times"].(map[string]any)
+
+	stints := map[string][]stintStart{}
+	strategy, _ := doc["tire_strategy"].([]any)
+	for _, entry := range strategy {
+		e, _ := entry.(map[string]any)
+		driver, _ := e["driver"].(string)
+		compound, _ := e["compound"].(string)
+		stints[driver] = append(stints[driver], stintStart{
+			lap:      int(asFloat(e["lap"])),
+			stint:    int(asFloat(e["stint"])),
+			compound: compound,
+		})
+	}
+
+	index := map[string]*lapGroup{}
+	groups := []*lapGroup{}
+	for driver, raw := range lapTimes {
+		times, _ := raw.([]any)
+		for i, t := range times {
+			if t == nil {
+				continue
+			}
+			lap := i + 1
+			current := stintFor(stints[driver], lap)
+
+			var key string
+			var g lapGroup
+			switch groupBy {
+			case "driver":
+				key, g = driver, lapGroup{Driver: driver}
+			case "stint":
+				key = driver + "/" + strconv.Itoa(current.stint)
+				g = lapGroup{Driver: driver, Stint: current.stint, Compound: current.compound}
+			case "compound":
+				if current.compound == "" {
+					continue
+				}
+				key, g = current.compound, lapGroup{Compound: current.compound}
+			}
+
+			grp, ok := index[key]
+			if !ok {
+				grp = &g
+				grp.StartLap = lap
+				index[key] = grp
+				groups = append(groups, grp)
+			}
+			grp.EndLap = lap
+			grp.times = append(grp.times, asFloat(t))
+		}
+	}
+
+	if groupBy == "compound" {
+		for _, g := range groups {
+			g.StartLap, g.EndLap = 0, 0 // spans drivers, so lap ranges are meaningless
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.Driver != b.Driver {
+			return a.Driver < b.Driver
+		}
+		if a.Stint != b.Stint {
+			return a.Stint < b.Stint
+		}
+		return compoundRank(a.Compound) < compoundRank(b.Compound)
+	})
+	return groups
+}
+
+// stintFor returns the stint a lap belongs to; stints are listed in lap order.
+func stintFor(stints []stintStart, lap int) stintStart {
+	current := stintStart{stint: 1}
+	for _, s := range stints {
+		if s.lap > lap {
+			break
+		}
+		current = s
+	}
+	return current
+}
+
+func compoundRank(c string) int {
+	if r, ok := compoundOrder[c]; ok {
+		return r
+	}
+	return len(compoundOrder)
+}
+
+func asFloat(v any) float64 {
+	switch n := v.(type) {
+	case json.Number:
+		f, _ := n.Float64()
+		return f
+	case float64:
+		return n
+	}
+	return 0
+}
+
+func mean(v []float64) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += x
+	}
+	return sum / float64(len(v))
+}
+
+// median expects sorted input.
+func median(v []float64) float64 {
+	mid := len(v) / 2
+	if len(v)%2 == 0 {
+		return (v[mid-1] + v[mid]) / 2
+	}
+	return v[mid]
+}
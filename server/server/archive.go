@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/respcache"
+	"github.com/gin-gonic/gin"
+)
+
+// settleAfter is how long after race day a race's data is taken as final:
+// FastF1 fills in late timing data and stewards' penalties for a day or two.
+const settleAfter = 72 * time.Hour
+
+// archiveLookupTimeout bounds the schedule lookup that tells whether a
+// current season race is over, so a slow Jolpica doesn't hold requests up.
+const archiveLookupTimeout = 2 * time.Second
+
+// settled reports whether the request is for a race whose data can no
+// longer change, so its response belongs in the archive: any race of a past
+// season, or a race of this season settleAfter its race day. Whole-season
+// responses of the current season, and the current and upcoming race
+// weekends, are never settled.
+func (s *Server) settled(c *gin.Context) bool {
+	if s.archive == nil {
+		return false
+	}
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil {
+		return false
+	}
+	now := time.Now().UTC()
+	if year < now.Year() {
+		return true
+	}
+	name := c.Param("race_name")
+	if year > now.Year() || name == "" {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), archiveLookupTimeout)
+	defer cancel()
+	schedule, err := s.history.Schedule(ctx, year)
+	if err != nil {
+		return false
+	}
+	round, ok := scheduledRound(schedule, name)
+	if !ok {
+		return false
+	}
+	for _, race := range schedule {
+		if race.RoundInt() != round {
+			continue
+		}
+		day, err := time.Parse("2006-01-02", race.Date)
+		return err == nil && now.After(day.Add(settleAfter))
+	}
+	return false
+}
+
+// fromArchive loads key from the archive into the response cache, returning
+// the entry to serve.
+func (s *Server) fromArchive(ctx context.Context, route, key string, ttl time.Duration) (respcache.Entry, bool) {
+	body, ok := s.archive.Load(key)
+	if !ok {
+		return respcache.Entry{}, false
+	}
+	s.cache.Set(ctx, key, body, "", ttl, cacheDeps(route, key)...)
+	return respcache.Entry{Body: body, Stored: time.Now(), TTL: ttl}, true
+}
+
+// toArchive keeps a settled response for good.
+func (s *Server) toArchive(key string, body []byte) {
+	if err := s.archive.Save(key, body); err != nil {
+		log.Printf("archive %s: %v", key, err)
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/ekjyotshinh/f1-server/normalize"
+	"github.com/gin-gonic/gin"
+)
+
+type driverBirthday struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Nationality string `json:"nationality"`
+	Flag        string `json:"flag"`
+	DateOfBirth string `json:"date_of_birth"`
+	Age         int    `json:"age"` // the age they turn this year
+}
+
+// birthdays lists the drivers born on ?date=MM-DD, or today by default.
+func birthdays(c *gin.Context) {
+	now := time.Now().UTC()
+	date := c.DefaultQuery("date", "today")
+	if date == "today" {
+		date = now.Format("01-02")
+	}
+	day, err := time.Parse("01-02", date)
+	if err != nil {
+		apierror.InvalidParameter.Respond(c, "date must be today or a day of the year as MM-DD")
+		return
+	}
+
+	drivers := []driverBirthday{}
+	for _, d := range normalize.DriversBornOn(day.Month(), day.Day()) {
+		born, _ := time.Parse(time.DateOnly, d.DateOfBirth)
+		drivers = append(drivers, driverBirthday{
+			Code:        d.Code,
+			Name:        d.FullName(),
+			Nationality: d.Nationality,
+			Flag:        d.Flag(),
+			DateOfBirth: d.DateOfBirth,
+			Age:         now.Year() - born.Year(),
+		})
+	}
+	// "today" rolls over at midnight
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(http.StatusOK, gin.H{"date": date, "drivers": drivers})
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/ekjyotshinh/f1-server/ics"
+	"github.com/ekjyotshinh/f1-server/jolpica"
+	"github.com/gin-gonic/gin"
+)
+
+// maxCalendarSize bounds an uploaded ICS file; a few years of a busy
+// calendar fits comfortably.
+const maxCalendarSize = 2 << 20
+
+// sessionLengths are how long each session is planned to take, including a
+// margin for delays.
+var sessionLengths = map[string]time.Duration{
+	"Practice 1":        time.Hour,
+	"Practice 2":        time.Hour,
+	"Practice 3":        time.Hour,
+	"Sprint Qualifying": 45 * time.Minute,
+	"Sprint":            time.Hour,
+	"Qualifying":        time.Hour,
+	"Race":              2 * time.Hour,
+}
+
+type busyEvent struct {
+	Summary string    `json:"summary"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	AllDay  bool      `json:"all_day"`
+}
+
+type sessionConflict struct {
+	Round   int         `json:"round"`
+	Race    string      `json:"race"`
+	Session string      `json:"session"`
+	Start   time.Time   `json:"start"`
+	End     time.Time   `json:"end"`
+	Events  []busyEvent `json:"events"`
+}
+
+type weekendSession struct {
+	name       string
+	start, end time.Time
+}
+
+// calendarConflicts takes an ICS file, as the request body or a "calendar"
+// form file, and lists the sessions of the season that clash with its
+// events. Times are given in ?tz= (an IANA zone such as Europe/London), or
+// the calendar's own zone, or UTC.
+func (s *Server) calendarConflicts(c *gin.Context) {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil || year < firstSeason || year > time.Now().Year()+1 {
+		apierror.InvalidParameter.Respond(c, "year must be a season between 1950 and next year")
+		return
+	}
+	loc := time.UTC
+	if tz := c.Query("tz"); tz != "" {
+		if loc, err = time.LoadLocation(tz); err != nil {
+			apierror.InvalidParameter.Respond(c, "tz must be an IANA time zone such as Europe/London")
+			return
+		}
+	}
+
+	data, err := readCalendar(c)
+	if err != nil {
+		apierror.InvalidCalendar.Respond(c, "Failed to read calendar: "+err.Error())
+		return
+	}
+	cal, err := ics.Parse(bytes.NewReader(data), loc)
+	if errors.Is(err, ics.ErrTooLarge) {
+		apierror.InvalidCalendar.Respond(c, "Calendar has too many events to check")
+		return
+	}
+	if err != nil {
+		apierror.InvalidCalendar.Respond(c, "Calendar isn't an ICS file")
+		return
+	}
+	if c.Query("tz") == "" && cal.TimeZone != "" {
+		loc, _ = time.LoadLocation(cal.TimeZone)
+	}
+
+	schedule, err := s.history.Schedule(c.Request.Context(), year)
+	if err != nil {
+		c.Error(err)
+		apierror.HistoryUnavailable.Respond(c, "Failed to reach historical data provider")
+		return
+	}
+
+	type raceSession struct {
+		race jolpica.ScheduledRace
+		weekendSession
+	}
+	var sessions []raceSession
+	var from, to time.Time
+	for _, race := range schedule {
+		for _, session := range weekendSessions(race) {
+			sessions = append(sessions, raceSession{race, session})
+			if from.IsZero() || session.start.Before(from) {
+				from = session.start
+			}
+			if session.end.After(to) {
+				to = session.end
+			}
+		}
+	}
+
+	// Recurring events are expanded once, over the whole season
+	var busy []busyEvent
+	for _, e := range cal.Events {
+		for _, o := range e.Occurrences(from, to) {
+			busy = append(busy, busyEvent{Summary: e.Summary, Start: o[0].In(loc), End: o[1].In(loc), AllDay: e.AllDay})
+		}
+	}
+
+	conflicts := []sessionConflict{}
+	for _, session := range sessions {
+		var clashes []busyEvent
+		for _, b := range busy {
+			if b.Start.Before(session.end) && b.End.After(session.start) {
+				clashes = append(clashes, b)
+			}
+		}
+		if len(clashes) > 0 {
+			conflicts = append(conflicts, sessionConflict{
+				Round:   session.race.RoundInt(),
+				Race:    session.race.RaceName,
+				Session: session.name,
+				Start:   session.start.In(loc),
+				End:     session.end.In(loc),
+				Events:  clashes,
+			})
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"year":      year,
+		"timezone":  loc.String(),
+		"events":    len(cal.Events),
+		"conflicts": conflicts,
+	})
+}
+
+// readCalendar returns the uploaded file, whichever way it was sent.
+func readCalendar(c *gin.Context) ([]byte, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCalendarSize)
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		fh, err := c.FormFile("calendar")
+		if err != nil {
+			return nil, err
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return io.ReadAll(f)
+	}
+	return io.ReadAll(c.Request.Body)
+}
+
+// weekendSessions lists a race weekend's sessions that have a start time;
+// older seasons list few or none.
+func weekendSessions(race jolpica.ScheduledRace) []weekendSession {
+	sessions := []struct {
+		name    string
+		session *jolpica.Session
+	}{
+		{"Practice 1", race.FirstPractice},
+		{"Practice 2", race.SecondPractice},
+		{"Practice 3", race.ThirdPractice},
+		{"Sprint Qualifying", race.SprintQualifying},
+		{"Sprint", race.Sprint},
+		{"Qualifying", race.Qualifying},
+		{"Race", &jolpica.Session{Date: race.Date, Time: race.Time}},
+	}
+	var out []weekendSession
+	for _, s := range sessions {
+		if s.session == nil || s.session.Time == "" {
+			continue
+		}
+		start, err := time.Parse(time.RFC3339, s.session.Date+"T"+s.session.Time)
+		if err != nil {
+			continue
+		}
+		out = append(out, weekendSession{name: s.name, start: start, end: start.Add(sessionLengths[s.name])})
+	}
+	return out
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/gin-gonic/gin"
+)
+
+type driverChampion struct {
+	DriverID    string  `json:"driver_id"`
+	Name        string  `json:"name"`
+	Nationality string  `json:"nationality"`
+	Team        string  `json:"team"`
+	Points      float64 `json:"points"`
+	Wins        int     `json:"wins"`
+	Margin      float64 `json:"margin"` // points ahead of second place
+}
+
+type constructorChampion struct {
+	ConstructorID string  `json:"constructor_id"`
+	Name          string  `json:"name"`
+	Points        float64 `json:"points"`
+	Wins          int     `json:"wins"`
+	Margin        float64 `json:"margin"`
+}
+
+type seasonChampions struct {
+	Year        int                  `json:"year"`
+	Driver      *driverChampion      `json:"driver"`
+	Constructor *constructorChampion `json:"constructor"` // nil before 1958
+	Provisional bool                 `json:"provisional"` // season still in progress
+}
+
+// champions lists the drivers' and constructors' champions for each season in
+// ?from=&to= (default: all of them).
+func (s *Server) champions(c *gin.Context) {
+	from, to, ok := seasonRange(c, firstSeason)
+	if !ok {
+		return
+	}
+
+	all, err := s.loadChampions(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		apierror.HistoryUnavailable.Respond(c, "Failed to reach historical data provider")
+		return
+	}
+
+	seasons := []seasonChampions{}
+	for _, sc := range all {
+		if sc.Year >= from && sc.Year <= to {
+			seasons = append(seasons, sc)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"from": from, "to": to, "seasons": seasons})
+}
+
+// loadChampions returns every season's champions. Completed seasons never
+// change, so they are kept for the life of the process; only the season in
+// progress is re-fetched.
+func (s *Server) loadChampions(ctx context.Context) ([]seasonChampions, error) {
+	current := time.Now().Year()
+
+	s.championsMu.Lock()
+	defer s.championsMu.Unlock()
+
+	if err := s.fetchChampions(ctx, current); err != nil {
+		if len(s.championsDone) == 0 {
+			return nil, err
+		}
+		// Completed seasons are still good without the provisional one
+		delete(s.championsLive, current)
+	}
+
+	all := make([]seasonChampions, 0, len(s.championsDone)+1)
+	for _, sc := range s.championsDone {
+		all = append(all, sc)
+	}
+	if sc, ok := s.championsLive[current]; ok {
+		all = append(all, sc)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Year < all[j].Year })
+	return all, nil
+}
+
+// fetchChampions loads champions and runners-up for every season. Callers
+// hold s.championsMu.
+func (s *Server) fetchChampions(ctx context.Context, current int) error {
+	drivers, err := s.history.DriversAtPosition(ctx, 1)
+	if err != nil {
+		return err
+	}
+	driverRunnersUp, err := s.history.DriversAtPosition(ctx, 2)
+	if err != nil {
+		return err
+	}
+	teams, err := s.history.ConstructorsAtPosition(ctx, 1)
+	if err != nil {
+		return err
+	}
+	teamRunnersUp, err := s.history.ConstructorsAtPosition(ctx, 2)
+	if err != nil {
+		return err
+	}
+
+	for year, champ := range drivers {
+		sc := seasonChampions{Year: year, Provisional: year >= current}
+		sc.Driver = &driverChampion{
+			DriverID:    champ.Driver.DriverID,
+			Name:        champ.Driver.Name(),
+			Nationality: champ.Driver.Nationality,
+			Points:      champ.PointsFloat(),
+			Wins:        atoi(champ.Wins),
+			Margin:      champ.PointsFloat() - driverRunnersUp[year].PointsFloat(),
+		}
+		if len(champ.Constructors) > 0 {
+			sc.Driver.Team = champ.Constructors[len(champ.Constructors)-1].Name
+		}
+		if team, ok := teams[year]; ok {
+			sc.Constructor = &constructorChampion{
+				ConstructorID: team.Constructor.ConstructorID,
+				Name:          team.Constructor.Name,
+				Points:        team.PointsFloat(),
+				Wins:          atoi(team.Wins),
+				Margin:        team.PointsFloat() - teamRunnersUp[year].PointsFloat(),
+			}
+		}
+
+		if sc.Provisional {
+			s.championsLive[year] = sc
+		} else {
+			s.championsDone[year] = sc
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/gin-gonic/gin"
+)
+
+// maxCompareDrivers bounds ?drivers= on /api/compare.
+const maxCompareDrivers = 10
+
+// raceDataRoutes are the data service responses that race analyses such
+// as /api/compare are computed from, in the order handlers decode them.
+var raceDataRoutes = []string{"/api/analytics/:year/:race_name", "/api/race/:year/:race_name"}
+
+// raceAnalytics is the part of /api/analytics that comparisons use. Lap
+// lists hold one entry per lap from lap 1, null where FastF1 has no value.
+type raceAnalytics struct {
+	Error        string                `json:"error"`
+	LapTimes     map[string][]*float64 `json:"lap_times"`
+	Positions    map[string][]*int     `json:"position_changes"`
+	TotalLaps    int                   `json:"total_laps"`
+	TireStrategy []struct {
+		Driver   string `json:"driver"`
+		Lap      int    `json:"lap"`
+		Compound string `json:"compound"`
+		Stint    int    `json:"stint"`
+	} `json:"tire_strategy"`
+	DriverInfo map[string]struct {
+		Team   string `json:"team"`
+		Number string `json:"number"`
+	} `json:"driver_info"`
+}
+
+// raceResults is the part of /api/race that comparisons use.
+type raceResults struct {
+	Error    string `json:"error"`
+	RaceName string `json:"race_name"`
+	Results  []struct {
+		// Positions are numbers, or classification codes such as "R"
+		Position     any    `json:"Position"`
+		Abbreviation string `json:"Abbreviation"`
+		Status       string `json:"Status"`
+		GridPosition any    `json:"GridPosition"`
+	} `json:"results"`
+}
+
+// pitStop is a stop at the end of Lap, fitting Compound.
+type pitStop struct {
+	Lap      int    `json:"lap"`
+	Compound string `json:"compound"`
+}
+
+type compareDriver struct {
+	Driver   string    `json:"driver"`
+	Team     string    `json:"team"`
+	Number   string    `json:"number"`
+	Grid     *int      `json:"grid"`
+	Position *int      `json:"position"` // null when not classified
+	Status   string    `json:"status"`
+	PitStops []pitStop `json:"pit_stops"`
+}
+
+// compareLap aligns the drivers' laps. Gaps are seconds behind the first
+// driver asked for, from summed lap times, so they stop at the first lap
+// either driver has no time for.
+type compareLap struct {
+	Lap       int                 `json:"lap"`
+	Times     map[string]*float64 `json:"times"`
+	Positions map[string]*int     `json:"positions"`
+	Gaps      map[string]*float64 `json:"gaps"`
+}
+
+// compareDrivers lines up two or more drivers' races, e.g.
+// ?drivers=VER,HAM: lap times, gaps, positions, pit stops and results,
+// built from the cached race and analytics responses.
+func (s *Server) compareDrivers(c *gin.Context) {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil {
+		apierror.InvalidParameter.Respond(c, "year must be a number")
+		return
+	}
+	var drivers []string
+	for _, d := range strings.Split(c.Query("drivers"), ",") {
+		if d = strings.ToUpper(strings.TrimSpace(d)); d != "" {
+			drivers = append(drivers, d)
+		}
+	}
+	if len(drivers) < 2 || len(drivers) > maxCompareDrivers {
+		apierror.InvalidParameter.Respond(c, "drivers must list 2 to "+strconv.Itoa(maxCompareDrivers)+" driver codes, e.g. VER,HAM")
+		return
+	}
+
+	s.computed(c, raceDataRoutes, func(bodies [][]byte) (any, bool) {
+		var analytics raceAnalytics
+		var race raceResults
+		if !decodeUpstream(c, bodies[0], &analytics) || !decodeUpstream(c, bodies[1], &race) {
+			return nil, false
+		}
+		if analytics.Error != "" || race.Error != "" {
+			apierror.UpstreamError.Respond(c, "Data service has no data for this race")
+			return nil, false
+		}
+		for _, d := range drivers {
+			if _, ok := analytics.LapTimes[d]; !ok {
+				apierror.InvalidParameter.Respond(c, "drivers: "+d+" didn't take part in this race")
+				return nil, false
+			}
+		}
+		return compareRaces(year, drivers, &analytics, &race), true
+	})
+}
+
+// compareRaces lines up drivers' laps, the first of them being the reference
+// for gaps.
+func compareRaces(year int, drivers []string, analytics *raceAnalytics, race *raceResults) gin.H {
+	rows := make([]compareDriver, 0, len(drivers))
+	for _, d := range drivers {
+		info := analytics.DriverInfo[d]
+		row := compareDriver{Driver: d, Team: info.Team, Number: info.Number, PitStops: []pitStop{}}
+		for _, r := range race.Results {
+			if r.Abbreviation == d {
+				row.Grid, row.Position, row.Status = resultPosition(r.GridPosition), resultPosition(r.Position), r.Status
+			}
+		}
+		for _, stint := range analytics.TireStrategy {
+			if stint.Driver == d && stint.Stint > 1 {
+				row.PitStops = append(row.PitStops, pitStop{Lap: stint.Lap - 1, Compound: stint.Compound})
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	ref := drivers[0]
+	elapsed := map[string]float64{}
+	timed := map[string]bool{} // whether every lap so far has a time
+	for _, d := range drivers {
+		timed[d] = true
+	}
+	laps := make([]compareLap, 0, analytics.TotalLaps)
+	for i := range analytics.TotalLaps {
+		lap := compareLap{
+			Lap:       i + 1,
+			Times:     map[string]*float64{},
+			Positions: map[string]*int{},
+			Gaps:      map[string]*float64{},
+		}
+		for _, d := range drivers {
+			var t *float64
+			if times := analytics.LapTimes[d]; i < len(times) {
+				t = times[i]
+			}
+			if positions := analytics.Positions[d]; i < len(positions) {
+				lap.Positions[d] = positions[i]
+			}
+			lap.Times[d] = t
+			if t == nil {
+				timed[d] = false
+			} else {
+				elapsed[d] += *t
+			}
+		}
+		for _, d := range drivers {
+			if timed[d] && timed[ref] {
+				gap := math.Round((elapsed[d]-elapsed[ref])*1000) / 1000
+				lap.Gaps[d] = &gap
+			} else {
+				lap.Gaps[d] = nil
+			}
+		}
+		laps = append(laps, lap)
+	}
+
+	return gin.H{
+		"year":       year,
+		"race":       race.RaceName,
+		"reference":  ref,
+		"total_laps": analytics.TotalLaps,
+		"drivers":    rows,
+		"laps":       laps,
+	}
+}
+
+// resultPosition reads a position given as a pandas float such as 3.0 or a
+// string such as "3"; anything else is no position.
+func resultPosition(v any) *int {
+	var n int
+	switch v := v.(type) {
+	case float64:
+		n = int(v)
+	case string:
+		var err error
+		if n, err = strconv.Atoi(v); err != nil {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return &n
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// compress encodes responses with Brotli or gzip, whichever the client
+// prefers, once they reach minSize bytes; smaller ones aren't worth it.
+// Responses that are already encoded, or of types that don't compress, pass
+// through untouched. minSize <= 0 disables compression.
+func compress(minSize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := acceptedEncoding(c.GetHeader("Accept-Encoding"))
+		if minSize <= 0 || encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{ResponseWriter: c.Writer, encoding: encoding, minSize: minSize}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		w.Close()
+	}
+}
+
+// acceptedEncoding picks br or gzip from an Accept-Encoding header, by q
+// value with br winning ties.
+func acceptedEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, _ = strconv.ParseFloat(v, 64)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if (name == "br" || name == "gzip") && (q > bestQ || q == bestQ && name == "br") && q > 0 {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressible reports whether a media type is text that compresses well.
+func compressible(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case mediaType == "application/json", mediaType == "application/yaml",
+		mediaType == "application/javascript", mediaType == "application/xml",
+		mediaType == "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// compressWriter holds the start of a response back until it knows whether
+// to compress: it does once minSize bytes arrive or the handler flushes.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	minSize  int
+
+	decided bool
+	skip    bool
+	buf     []byte
+	enc     io.WriteCloser
+}
+
+// decide checks the response headers the first time there is a body.
+func (w *compressWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	h := w.Header()
+	h.Add("Vary", "Accept-Encoding")
+	status := w.Status()
+	w.skip = h.Get("Content-Encoding") != "" || !compressible(h.Get("Content-Type")) ||
+		status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified
+}
+
+func (w *compressWriter) start() {
+	h := w.Header()
+	h.Set("Content-Encoding", w.encoding)
+	h.Del("Content-Length")
+	if w.encoding == "br" {
+		w.enc = brotli.NewWriterLevel(w.ResponseWriter, 5)
+	} else {
+		w.enc, _ = gzip.NewWriterLevel(w.ResponseWriter, gzip.DefaultCompression)
+	}
+	if len(w.buf) > 0 {
+		w.enc.Write(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	w.decide()
+	switch {
+	case w.skip:
+		return w.ResponseWriter.Write(b)
+	case w.enc != nil:
+		return w.enc.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minSize {
+		w.start()
+	}
+	return len(b), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow is deferred until the encoding is known.
+func (w *compressWriter) WriteHeaderNow() {}
+
+func (w *compressWriter) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// Flush sends what the handler has written so far, so streamed responses
+// keep streaming; a flushed response is compressed whatever its size.
+func (w *compressWriter) Flush() {
+	w.decide()
+	if !w.skip && w.enc == nil && len(w.buf) > 0 {
+		w.start()
+	}
+	if f, ok := w.enc.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Close finishes the response: the encoder's trailer, or the held-back body
+// of a response too small to compress.
+func (w *compressWriter) Close() {
+	if w.enc != nil {
+		w.enc.Close()
+		return
+	}
+	if len(w.buf) > 0 {
+		w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+	}
+}
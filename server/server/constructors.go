@@ -0,0 +1,25 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/ekjyotshinh/f1-server/normalize"
+	"github.com/gin-gonic/gin"
+)
+
+// constructorLineage lists team lineages, or the one a ?team= name belongs to.
+func constructorLineage(c *gin.Context) {
+	if team := c.Query("team"); team != "" {
+		lineage, ok := normalize.LineageOf(team)
+		if !ok {
+			apierror.UnknownTeam.Respond(c, "Unknown team")
+			return
+		}
+		c.JSON(http.StatusOK, lineage)
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.JSON(http.StatusOK, normalize.Lineages())
+}
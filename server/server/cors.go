@@ -0,0 +1,134 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// CORSTier is the browser access policy for one group of routes. An Origins
+// entry of "*" allows any origin, which cannot be combined with Credentials.
+type CORSTier struct {
+	Origins     []string
+	Methods     []string
+	Credentials bool
+}
+
+// CORSTiers assigns a policy to each kind of route.
+type CORSTiers struct {
+	// Frontend covers the dashboard's own race data routes.
+	Frontend CORSTier
+	// Widgets covers the public history and stats routes other sites embed.
+	Widgets CORSTier
+	// Admin covers /api/admin and cache management.
+	Admin CORSTier
+}
+
+// Route tiers. Each route group marks its tier in the request context
+// first thing, and the CORS policy and rate limiter that follow read it.
+const (
+	tierFrontend = "frontend"
+	tierWidgets  = "widgets"
+	tierAdmin    = "admin"
+
+	ctxTier = "tier"
+)
+
+// tiers registers route groups that share the middleware after the tier
+// mark. Each path is also registered for OPTIONS with its group's
+// middleware, so preflight requests get the CORS policy of its tier.
+type tiers struct {
+	engine    *gin.Engine
+	chain     []gin.HandlerFunc
+	preflight map[string]bool // paths with an OPTIONS route already
+}
+
+// group starts a route group of tier at path.
+func (t *tiers) group(tier, path string, handlers ...gin.HandlerFunc) tierGroup {
+	mark := func(c *gin.Context) { c.Set(ctxTier, tier) }
+	chain := append(append([]gin.HandlerFunc{mark}, t.chain...), handlers...)
+	return tierGroup{t.engine.Group(path, chain...), t.preflight}
+}
+
+// noRoute marks requests matching no route, preflight requests for routes
+// without an OPTIONS route among them, as tier.
+func (t *tiers) noRoute(tier string, handlers ...gin.HandlerFunc) {
+	mark := func(c *gin.Context) { c.Set(ctxTier, tier) }
+	t.engine.NoRoute(append(append([]gin.HandlerFunc{mark}, t.chain...), handlers...)...)
+}
+
+// tierGroup is a route group of one tier.
+type tierGroup struct {
+	*gin.RouterGroup
+	preflight map[string]bool
+}
+
+func (g tierGroup) Group(path string, handlers ...gin.HandlerFunc) tierGroup {
+	return tierGroup{g.RouterGroup.Group(path, handlers...), g.preflight}
+}
+
+func (g tierGroup) GET(path string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return g.handle(http.MethodGet, path, handlers)
+}
+
+func (g tierGroup) POST(path string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return g.handle(http.MethodPost, path, handlers)
+}
+
+func (g tierGroup) PUT(path string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return g.handle(http.MethodPut, path, handlers)
+}
+
+func (g tierGroup) DELETE(path string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return g.handle(http.MethodDelete, path, handlers)
+}
+
+func (g tierGroup) handle(method, path string, handlers []gin.HandlerFunc) gin.IRoutes {
+	routes := g.RouterGroup.Handle(method, path, handlers...)
+	full := strings.TrimSuffix(g.BasePath(), "/") + path
+	if !g.preflight[full] {
+		g.preflight[full] = true
+		// The CORS middleware answers preflights itself; anything else gets
+		// an empty answer
+		g.RouterGroup.OPTIONS(path, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	}
+	return routes
+}
+
+// corsMiddleware applies the CORS policy of the request's tier.
+func (s *Server) corsMiddleware() gin.HandlerFunc {
+	frontend := s.cfg.CORS.Frontend.handler()
+	widgets := s.cfg.CORS.Widgets.handler()
+	admin := s.cfg.CORS.Admin.handler()
+
+	return func(c *gin.Context) {
+		switch c.GetString(ctxTier) {
+		case tierAdmin:
+			admin(c)
+		case tierWidgets:
+			widgets(c)
+		default:
+			frontend(c)
+		}
+	}
+}
+
+func (t CORSTier) handler() gin.HandlerFunc {
+	cfg := cors.Config{
+		AllowMethods:     t.Methods,
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", adminKeyHeader},
+		ExposeHeaders:    []string{"Content-Length", "X-Cache", "X-Data-Source", "X-Request-Id"},
+		AllowCredentials: t.Credentials,
+		MaxAge:           12 * time.Hour,
+	}
+	if slices.Contains(t.Origins, "*") {
+		cfg.AllowAllOrigins = true
+	} else {
+		cfg.AllowOrigins = t.Origins
+	}
+	return cors.New(cfg)
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"container/list"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/signing"
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecation announces that a route is going away.
+type Deprecation struct {
+	// Since is when the route was deprecated.
+	Since time.Time
+	// Sunset is when it stops working; zero if not yet decided.
+	Sunset time.Time
+	// Successor is the route or docs page consumers should move to.
+	Successor string
+}
+
+type deprecationUse struct {
+	Route    string    `json:"route"`
+	Consumer string    `json:"consumer"`
+	Requests int64     `json:"requests"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// maxDeprecationUses bounds the (route, consumer) pairs counted. Consumers
+// are mostly client IPs, so without a bound every address that ever called
+// a deprecated route would be kept; the least recently seen go first.
+const maxDeprecationUses = 1000
+
+// deprecations sends Deprecation/Sunset headers on deprecated routes and
+// counts who still calls them, so they can be retired once traffic is gone.
+type deprecations struct {
+	routes map[string]Deprecation // keyed by gin route pattern
+
+	mu    sync.Mutex
+	order *list.List // of *deprecationUse, most recently seen first
+	uses  map[[2]string]*list.Element
+}
+
+func newDeprecations(routes map[string]Deprecation) *deprecations {
+	return &deprecations{routes: routes, order: list.New(), uses: make(map[[2]string]*list.Element)}
+}
+
+func (d *deprecations) middleware(c *gin.Context) {
+	route := c.FullPath()
+	dep, ok := d.routes[route]
+	if !ok {
+		c.Next()
+		return
+	}
+
+	// RFC 9745 structured date, RFC 8594 HTTP date
+	c.Header("Deprecation", fmt.Sprintf("@%d", dep.Since.Unix()))
+	if !dep.Sunset.IsZero() {
+		c.Header("Sunset", dep.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if dep.Successor != "" {
+		c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", dep.Successor))
+	}
+	d.record(route, consumer(c))
+	c.Next()
+}
+
+// consumer identifies the caller: a signed client id, else the client IP.
+func consumer(c *gin.Context) string {
+	if id := c.GetString(signing.ClientKey); id != "" {
+		return id
+	}
+	return c.ClientIP()
+}
+
+func (d *deprecations) record(route, who string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := [2]string{route, who}
+	el, ok := d.uses[key]
+	if ok {
+		d.order.MoveToFront(el)
+	} else {
+		// Log each consumer once per route rather than on every request
+		log.Printf("deprecated route %s used by %s", route, who)
+		el = d.order.PushFront(&deprecationUse{Route: route, Consumer: who})
+		d.uses[key] = el
+		for d.order.Len() > maxDeprecationUses {
+			oldest := d.order.Remove(d.order.Back()).(*deprecationUse)
+			delete(d.uses, [2]string{oldest.Route, oldest.Consumer})
+		}
+	}
+	use := el.Value.(*deprecationUse)
+	use.Requests++
+	use.LastSeen = time.Now().UTC()
+}
+
+// report lists usage by route, busiest consumers first.
+func (d *deprecations) report() []deprecationUse {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	out := make([]deprecationUse, 0, len(d.uses))
+	for el := d.order.Front(); el != nil; el = el.Next() {
+		out = append(out, *el.Value.(*deprecationUse))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Route != out[j].Route {
+			return out[i].Route < out[j].Route
+		}
+		return out[i].Requests > out[j].Requests
+	})
+	return out
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/ekjyotshinh/f1-server/jolpica"
+	"github.com/ekjyotshinh/f1-server/normalize"
+	"github.com/gin-gonic/gin"
+)
+
+// firstSeason is the first world championship season.
+const firstSeason = 1950
+
+type driverSeason struct {
+	Year  int      `json:"year"`
+	Teams []string `json:"teams"`
+
+	teamIDs []string
+}
+
+type driverCareer struct {
+	DriverID string         `json:"driver_id"`
+	Code     string         `json:"code,omitempty"`
+	Name     string         `json:"name"`
+	Seasons  []driverSeason `json:"seasons"`
+}
+
+type transfer struct {
+	DriverID  string `json:"driver_id"`
+	Name      string `json:"name"`
+	Year      int    `json:"year"`
+	FromTeam  string `json:"from_team"`
+	ToTeam    string `json:"to_team"`
+	MidSeason bool   `json:"mid_season"`
+}
+
+type transferLink struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Value  int    `json:"value"`
+}
+
+// driverTransfers summarises which driver drove for which team in each season
+// of ?from=..&to=.., derived from the season entry lists. Moves between names
+// of the same team lineage (rebrands) are not counted as transfers.
+func (s *Server) driverTransfers(c *gin.Context) {
+	from, to, ok := seasonRange(c, time.Now().Year()-5)
+	if !ok {
+		return
+	}
+
+	careers := map[string]*driverCareer{}
+	var order []string
+	for year := from; year <= to; year++ {
+		standings, err := s.history.DriverStandings(c.Request.Context(), year)
+		if err != nil {
+			c.Error(err)
+			apierror.HistoryUnavailable.Respond(c, "Failed to reach historical data provider")
+			return
+		}
+		for _, st := range standings {
+			career, ok := careers[st.Driver.DriverID]
+			if !ok {
+				career = &driverCareer{DriverID: st.Driver.DriverID, Code: st.Driver.Code, Name: st.Driver.Name()}
+				careers[st.Driver.DriverID] = career
+				order = append(order, st.Driver.DriverID)
+			}
+			season := driverSeason{Year: year}
+			for _, team := range st.Constructors {
+				season.Teams = append(season.Teams, team.Name)
+				season.teamIDs = append(season.teamIDs, team.ConstructorID)
+			}
+			career.Seasons = append(career.Seasons, season)
+		}
+	}
+
+	sort.Strings(order)
+	drivers := make([]driverCareer, 0, len(order))
+	transfers := []transfer{}
+	links := map[[2]string]int{}
+	for _, id := range order {
+		career := careers[id]
+		drivers = append(drivers, *career)
+
+		var prev, prevID string
+		for _, season := range career.Seasons {
+			for i, team := range season.Teams {
+				id := season.teamIDs[i]
+				if prev != "" && !sameLineage(prevID, id) {
+					transfers = append(transfers, transfer{
+						DriverID:  career.DriverID,
+						Name:      career.Name,
+						Year:      season.Year,
+						FromTeam:  prev,
+						ToTeam:    team,
+						MidSeason: i > 0,
+					})
+					links[[2]string{prev, team}]++
+				}
+				prev, prevID = team, id
+			}
+		}
+	}
+
+	sankey := make([]transferLink, 0, len(links))
+	for k, v := range links {
+		sankey = append(sankey, transferLink{Source: k[0], Target: k[1], Value: v})
+	}
+	sort.Slice(sankey, func(i, j int) bool {
+		if sankey[i].Value != sankey[j].Value {
+			return sankey[i].Value > sankey[j].Value
+		}
+		if sankey[i].Source != sankey[j].Source {
+			return sankey[i].Source < sankey[j].Source
+		}
+		return sankey[i].Target < sankey[j].Target
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":      from,
+		"to":        to,
+		"drivers":   drivers,
+		"transfers": transfers,
+		"links":     sankey,
+	})
+}
+
+// sameLineage reports whether two constructor IDs are the same entry under
+// different names, e.g. racing_point and aston_martin.
+func sameLineage(a, b string) bool {
+	if a == b {
+		return true
+	}
+	la, okA := normalize.LineageOf(a)
+	lb, okB := normalize.LineageOf(b)
+	return okA && okB && la.ID == lb.ID
+}
+
+// seasonRange parses ?from=&to= (defaulting to defaultFrom through this
+// season) and writes a 400 response when they are invalid.
+func seasonRange(c *gin.Context, defaultFrom int) (from, to int, ok bool) {
+	current := time.Now().Year()
+	to = current
+	from = defaultFrom
+
+	var err error
+	if v := c.Query("to"); v != "" {
+		if to, err = strconv.Atoi(v); err != nil {
+			apierror.InvalidParameter.Respond(c, "to must be a year")
+			return 0, 0, false
+		}
+	}
+	if v := c.Query("from"); v != "" {
+		if from, err = strconv.Atoi(v); err != nil {
+			apierror.InvalidParameter.Respond(c, "from must be a year")
+			return 0, 0, false
+		}
+	}
+	if from < firstSeason || to > current || from > to {
+		apierror.InvalidParameter.Respond(c, "from and to must be seasons between 1950 and this year, with from <= to")
+		return 0, 0, false
+	}
+	return from, to, true
+}
+
+type teammateComparison struct {
+	DriverID    string  `json:"driver_id"`
+	Name        string  `json:"name"`
+	Position    int     `json:"position"`
+	Points      float64 `json:"points"`
+	PointsDelta float64 `json:"points_delta"` // rookie minus teammate
+}
+
+type rookie struct {
+	DriverID  string               `json:"driver_id"`
+	Code      string               `json:"code,omitempty"`
+	Name      string               `json:"name"`
+	Team      string               `json:"team"`
+	Position  int                  `json:"position"`
+	Points    float64              `json:"points"`
+	Wins      int                  `json:"wins"`
+	Teammates []teammateComparison `json:"teammates"`
+}
+
+// driverRookies lists drivers whose first season is :year, with their
+// championship standing so far and how they compare to their teammates.
+func (s *Server) driverRookies(c *gin.Context) {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil || year < firstSeason || year > time.Now().Year() {
+		apierror.InvalidParameter.Respond(c, "year must be a season between 1950 and this year")
+		return
+	}
+
+	ctx := c.Request.Context()
+	standings, err := s.history.DriverStandings(ctx, year)
+	if err != nil {
+		c.Error(err)
+		apierror.HistoryUnavailable.Respond(c, "Failed to reach historical data provider")
+		return
+	}
+
+	rookies := []rookie{}
+	for _, st := range standings {
+		seasons, err := s.history.DriverSeasons(ctx, st.Driver.DriverID)
+		if err != nil {
+			c.Error(err)
+			apierror.HistoryUnavailable.Respond(c, "Failed to reach historical data provider")
+			return
+		}
+		if len(seasons) == 0 || seasons[0] != year || len(st.Constructors) == 0 {
+			continue
+		}
+
+		// Compare against whoever shared the rookie's most recent seat
+		team := st.Constructors[len(st.Constructors)-1]
+		r := rookie{
+			DriverID:  st.Driver.DriverID,
+			Code:      st.Driver.Code,
+			Name:      st.Driver.Name(),
+			Team:      team.Name,
+			Position:  st.PositionInt(),
+			Points:    st.PointsFloat(),
+			Wins:      atoi(st.Wins),
+			Teammates: []teammateComparison{},
+		}
+		for _, mate := range standings {
+			if mate.Driver.DriverID == st.Driver.DriverID || !droveFor(mate, team.ConstructorID) {
+				continue
+			}
+			r.Teammates = append(r.Teammates, teammateComparison{
+				DriverID:    mate.Driver.DriverID,
+				Name:        mate.Driver.Name(),
+				Position:    mate.PositionInt(),
+				Points:      mate.PointsFloat(),
+				PointsDelta: r.Points - mate.PointsFloat(),
+			})
+		}
+		rookies = append(rookies, r)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"year": year, "rookies": rookies})
+}
+
+func droveFor(st jolpica.DriverStanding, constructorID string) bool {
+	for _, team := range st.Constructors {
+		if team.ConstructorID == constructorID {
+			return true
+		}
+	}
+	return false
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net/url"
+
+	"github.com/ekjyotshinh/f1-server/normalize"
+	"github.com/ekjyotshinh/f1-server/transform"
+)
+
+// enrichResults adds driver nationality, flag and team colour to each row of
+// a race results list so consumers don't need a second lookup.
+func enrichResults(url.Values) (transform.Step, error) {
+	return func(doc any) any {
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			return doc
+		}
+		results, _ := obj["results"].([]any)
+		for _, row := range results {
+			r, ok := row.(map[string]any)
+			if !ok {
+				continue
+			}
+			if code, _ := r["Abbreviation"].(string); code != "" {
+				if d, ok := normalize.DriverByCode(code); ok {
+					r["FullName"] = d.FullName()
+					r["Nationality"] = d.Nationality
+					r["CountryCode"] = d.CountryCode
+					r["Flag"] = d.Flag()
+				}
+			}
+			if team, _ := r["TeamName"].(string); team != "" {
+				if color, ok := normalize.TeamColor(team); ok {
+					r["TeamColor"] = color
+				}
+			}
+		}
+		return obj
+	}, nil
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/gin-gonic/gin"
+)
+
+// exportRequest is the body of POST /api/admin/export/sheets.
+type exportRequest struct {
+	// Table is "results", "driver_standings" or "constructor_standings".
+	Table string `json:"table"`
+	Year  int    `json:"year"`
+	// Round is required for results; standings are as of the latest round.
+	Round int `json:"round"`
+	// Sheet is the tab to (over)write; it defaults to one named after the table.
+	Sheet string `json:"sheet"`
+}
+
+// exportSheets writes a results or standings table to the configured Google
+// Sheet, replacing the tab's contents.
+func (s *Server) exportSheets(c *gin.Context) {
+	if s.sheets == nil {
+		apierror.ExportUnavailable.Respond(c, "Google Sheets export is not configured")
+		return
+	}
+	var req exportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.InvalidParameter.Respond(c, "body must be JSON with table and year")
+		return
+	}
+	if req.Year < firstSeason || req.Year > time.Now().Year() {
+		apierror.InvalidParameter.Respond(c, "year must be a season between 1950 and this year")
+		return
+	}
+
+	ctx := c.Request.Context()
+	var rows [][]string
+	var err error
+	switch req.Table {
+	case "results":
+		if req.Round < 1 {
+			apierror.InvalidParameter.Respond(c, "round is required for results")
+			return
+		}
+		rows, err = s.resultRows(c, req.Year, req.Round)
+		if req.Sheet == "" {
+			req.Sheet = fmt.Sprintf("%d R%d Results", req.Year, req.Round)
+		}
+	case "driver_standings":
+		rows, err = s.driverStandingRows(c, req.Year)
+		if req.Sheet == "" {
+			req.Sheet = fmt.Sprintf("%d Drivers", req.Year)
+		}
+	case "constructor_standings":
+		rows, err = s.constructorStandingRows(c, req.Year)
+		if req.Sheet == "" {
+			req.Sheet = fmt.Sprintf("%d Constructors", req.Year)
+		}
+	default:
+		apierror.InvalidParameter.Respond(c, "table must be results, driver_standings or constructor_standings")
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		apierror.HistoryUnavailable.Respond(c, "Failed to reach historical data provider")
+		return
+	}
+	if len(rows) < 2 {
+		apierror.NotFound.Respond(c, "No data for that season or round yet")
+		return
+	}
+
+	if err := s.sheets.Replace(ctx, s.cfg.SheetsSpreadsheetID, req.Sheet, rows); err != nil {
+		c.Error(err)
+		apierror.ExportFailed.Respond(c, "Failed to write to Google Sheets")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"table": req.Table, "sheet": req.Sheet, "rows": len(rows) - 1})
+}
+
+func (s *Server) resultRows(c *gin.Context, year, round int) ([][]string, error) {
+	race, err := s.history.RaceResults(c.Request.Context(), year, round)
+	if err != nil || race == nil {
+		return nil, err
+	}
+	rows := [][]string{{"Position", "No", "Driver", "Code", "Team", "Grid", "Laps", "Status", "Points"}}
+	for _, r := range race.Results {
+		rows = append(rows, []string{
+			r.Position, r.Number, r.Driver.Name(), r.Driver.Code, r.Constructor.Name,
+			r.Grid, r.Laps, r.Status, r.Points,
+		})
+	}
+	return rows, nil
+}
+
+func (s *Server) driverStandingRows(c *gin.Context, year int) ([][]string, error) {
+	standings, err := s.history.DriverStandings(c.Request.Context(), year)
+	if err != nil {
+		return nil, err
+	}
+	rows := [][]string{{"Position", "Driver", "Code", "Team", "Wins", "Points"}}
+	for _, st := range standings {
+		team := ""
+		if n := len(st.Constructors); n > 0 {
+			team = st.Constructors[n-1].Name
+		}
+		rows = append(rows, []string{st.Position, st.Driver.Name(), st.Driver.Code, team, st.Wins, st.Points})
+	}
+	return rows, nil
+}
+
+func (s *Server) constructorStandingRows(c *gin.Context, year int) ([][]string, error) {
+	standings, err := s.history.ConstructorStandings(c.Request.Context(), year)
+	if err != nil {
+		return nil, err
+	}
+	rows := [][]string{{"Position", "Team", "Wins", "Points"}}
+	for _, st := range standings {
+		rows = append(rows, []string{st.Position, st.Constructor.Name, st.Wins, st.Points})
+	}
+	return rows, nil
+}
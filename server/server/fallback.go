@@ -0,0 +1,156 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/ekjyotshinh/f1-server/jolpica"
+	"github.com/ekjyotshinh/f1-server/jsoncodec"
+	"github.com/ekjyotshinh/f1-server/transform"
+	"github.com/gin-gonic/gin"
+)
+
+// fallbackSource is the X-Data-Source of responses rebuilt from the
+// historical data provider.
+const fallbackSource = "jolpica"
+
+// errNoFallback is returned when the historical data provider doesn't have
+// what was asked for either.
+var errNoFallback = errors.New("not available from the historical data provider")
+
+// fallbacks rebuild data service responses from the historical data
+// provider, in the data service's shape, for when the data service is down.
+// They cover what the Ergast-compatible API has: calendars and race results.
+var fallbacks = map[string]func(s *Server, c *gin.Context) (any, error){
+	"/api/schedule/:year":        (*Server).fallbackSchedule,
+	"/api/race/:year/:race_name": (*Server).fallbackRace,
+}
+
+// serveFallback answers a request the data service failed from its fallback,
+// if route has one, and reports whether it did. The answer isn't cached, so
+// the data service is asked again on the next request.
+func (s *Server) serveFallback(c *gin.Context, route string, steps []transform.Step) bool {
+	build, ok := fallbacks[route]
+	if !ok || !s.cfg.UpstreamFallback || c.Request.Context().Err() != nil {
+		return false
+	}
+	doc, err := build(s, c)
+	if err == nil {
+		var body []byte
+		if body, err = jsoncodec.Default.Marshal(doc); err == nil {
+			doc, err = transform.Run(body, steps)
+		}
+	}
+	if err != nil {
+		log.Printf("fallback %s: %v", c.Request.URL.Path, err)
+		return false
+	}
+	s.metrics.upstreamFallbacks.WithLabelValues(route).Inc()
+	c.Header("X-Data-Source", fallbackSource)
+	c.Header("Cache-Control", "no-store")
+	writeDoc(c, doc)
+	return true
+}
+
+func (s *Server) fallbackSchedule(c *gin.Context) (any, error) {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil {
+		return nil, errNoFallback
+	}
+	schedule, err := s.history.Schedule(c.Request.Context(), year)
+	if err != nil {
+		return nil, err
+	}
+	return scheduleEvents(schedule), nil
+}
+
+type fallbackResult struct {
+	Position     *int   `json:"Position"`
+	Abbreviation string `json:"Abbreviation"`
+	TeamName     string `json:"TeamName"`
+	Status       string `json:"Status"`
+	GridPosition *int   `json:"GridPosition"`
+	Time         string `json:"Time"`
+}
+
+// fallbackRace rebuilds /api/race. Jolpica's times are already formatted, so
+// they read slightly differently from FastF1's timedeltas.
+func (s *Server) fallbackRace(c *gin.Context) (any, error) {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil {
+		return nil, errNoFallback
+	}
+	ctx := c.Request.Context()
+	schedule, err := s.history.Schedule(ctx, year)
+	if err != nil {
+		return nil, err
+	}
+	round, ok := scheduledRound(schedule, c.Param("race_name"))
+	if !ok {
+		return nil, errNoFallback
+	}
+	race, err := s.history.RaceResults(ctx, year, round)
+	if err != nil {
+		return nil, err
+	}
+	if race == nil {
+		return nil, fmt.Errorf("round %d: %w", round, errNoFallback)
+	}
+
+	raceTime := "N/A"
+	fastest := gin.H{"driver": "N/A", "time": "N/A"}
+	results := make([]fallbackResult, 0, len(race.Results))
+	for _, r := range race.Results {
+		row := fallbackResult{
+			Abbreviation: r.Driver.Code,
+			TeamName:     r.Constructor.Name,
+			Status:       r.Status,
+		}
+		if p := r.PositionInt(); p > 0 {
+			row.Position = &p
+		}
+		if g := atoi(r.Grid); g > 0 {
+			row.GridPosition = &g
+		}
+		if r.Time != nil {
+			row.Time = r.Time.Time
+			if r.PositionInt() == 1 {
+				raceTime = r.Time.Time
+			}
+		}
+		if r.FastestLapRank() == 1 {
+			fastest = gin.H{"driver": r.Driver.Code, "time": r.FastestLap.Time.Time}
+		}
+		results = append(results, row)
+	}
+	return gin.H{
+		"race_name":   race.RaceName,
+		"race_date":   race.Date + "T00:00:00",
+		"race_time":   raceTime,
+		"fastest_lap": fastest,
+		"results":     results,
+	}, nil
+}
+
+// scheduledRound finds the round FastF1 would for name: a round number, or
+// the race's name, country or locality, matched loosely.
+func scheduledRound(schedule []jolpica.ScheduledRace, name string) (int, bool) {
+	if n, err := strconv.Atoi(name); err == nil {
+		return n, true
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return 0, false
+	}
+	for _, race := range schedule {
+		loc := race.Circuit.Location
+		if strings.Contains(strings.ToLower(race.RaceName), name) ||
+			strings.EqualFold(loc.Country, name) || strings.EqualFold(loc.Locality, name) {
+			return race.RoundInt(), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SecurityHeaders are sent with every response. ContentSecurityPolicy only
+// applies to HTML, since JSON is never rendered by the browser.
+type SecurityHeaders struct {
+	// HSTSMaxAge enables Strict-Transport-Security when positive.
+	HSTSMaxAge            time.Duration
+	ReferrerPolicy        string
+	ContentSecurityPolicy string
+}
+
+func (h SecurityHeaders) middleware() gin.HandlerFunc {
+	var hsts string
+	if h.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d; includeSubDomains", int(h.HSTSMaxAge.Seconds()))
+	}
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		if hsts != "" {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		if h.ReferrerPolicy != "" {
+			header.Set("Referrer-Policy", h.ReferrerPolicy)
+		}
+		if h.ContentSecurityPolicy != "" {
+			c.Writer = &cspWriter{ResponseWriter: c.Writer, policy: h.ContentSecurityPolicy}
+		}
+		c.Next()
+	}
+}
+
+// cspWriter adds the policy just before headers are sent, once the handler
+// has decided the content type.
+type cspWriter struct {
+	gin.ResponseWriter
+	policy string
+}
+
+func (w *cspWriter) apply() {
+	if w.Written() {
+		return
+	}
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		w.Header().Set("Content-Security-Policy", w.policy)
+	}
+}
+
+func (w *cspWriter) WriteHeaderNow() {
+	w.apply()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *cspWriter) Write(b []byte) (int, error) {
+	w.apply()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *cspWriter) WriteString(s string) (int, error) {
+	w.apply()
+	return w.ResponseWriter.WriteString(s)
+}
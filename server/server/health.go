@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readyTimeout bounds a readiness check, so a hung data service fails it
+// rather than stalling the orchestrator's probe.
+const readyTimeout = 3 * time.Second
+
+// healthz is the liveness probe: the process is up and serving.
+func healthz(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// readyz is the readiness probe. The instance is ready while at least one
+// data service region answers; the response cache is reported but doesn't
+// decide it, since losing Redis only costs cache hits.
+func (s *Server) readyz(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readyTimeout)
+	defer cancel()
+
+	backend := "memory"
+	if s.cfg.CacheRedisURL != "" {
+		backend = "redis"
+	}
+	cache := gin.H{"backend": backend, "ok": true}
+	if err := s.cache.Ping(ctx); err != nil {
+		cache["ok"], cache["error"] = false, err.Error()
+	}
+
+	if s.cfg.Demo {
+		c.JSON(http.StatusOK, gin.H{"status": "ready", "demo": true, "cache": cache})
+		return
+	}
+
+	// The data service's root is cheap; it doesn't load a session
+	s.regions.Probe(ctx, s.direct, "/", readyTimeout)
+	// Upstream URLs stay on the admin API
+	var regions []gin.H
+	ready := false
+	for _, r := range s.regions.Status() {
+		regions = append(regions, gin.H{"name": r.Name, "healthy": r.Healthy, "probe_ms": r.ProbeMs})
+		ready = ready || r.Healthy
+	}
+
+	status, state := http.StatusOK, "ready"
+	if !ready {
+		status, state = http.StatusServiceUnavailable, "unavailable"
+	}
+	c.JSON(status, gin.H{"status": state, "data_service": regions, "cache": cache})
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/ekjyotshinh/f1-server/jsoncodec"
+	"github.com/ekjyotshinh/f1-server/respcache"
+	"github.com/gin-gonic/gin"
+)
+
+// raceRoute is the race results route, which other data of a race is
+// computed alongside.
+const raceRoute = "/api/race/:year/:race_name"
+
+// fromResults are the routes the data service computes from the same
+// session as the race results, so that a correction to one shows in both.
+var fromResults = []string{"/api/laps/:year/:race_name/:driver", "/api/analytics/:year/:race_name"}
+
+// keyParam is the value of the path parameter name in key, a cache key for
+// route, or "" if route has no such parameter.
+func keyParam(route, key, name string) string {
+	path, _, _ := strings.Cut(key, "?")
+	segments, keySegments := strings.Split(route, "/"), strings.Split(path, "/")
+	if len(segments) != len(keySegments) {
+		return ""
+	}
+	i := slices.Index(segments, ":"+name)
+	if i < 0 {
+		return ""
+	}
+	v, err := url.PathUnescape(keySegments[i])
+	if err != nil {
+		return ""
+	}
+	return v
+}
+
+// scheduleNode names a race's entry in its season's calendar, which the
+// race weekend's cached data is built from. Races cached under a name
+// rather than a round depend on the whole calendar.
+func scheduleNode(year, race string) string {
+	if _, err := strconv.Atoi(race); err != nil {
+		return "schedule:" + year
+	}
+	return "schedule:" + year + "/" + race
+}
+
+// cacheDeps is what the response cached under key for route is built from:
+// its race's calendar entry and, for data computed alongside them, the race
+// results. Its tags go in too, so it can be dropped by them.
+func cacheDeps(route, key string) []string {
+	deps := cacheTags(route, key)
+	year, race := keyParam(route, key, "year"), keyParam(route, key, "race_name")
+	if year == "" || race == "" {
+		return deps
+	}
+	deps = append(deps, scheduleNode(year, race))
+	if slices.Contains(fromResults, route) {
+		deps = append(deps, upstreamPath(raceRoute, gin.Params{{Key: "year", Value: year}, {Key: "race_name", Value: race}}))
+	}
+	return deps
+}
+
+// cacheTags are the tags of the response cached under key for route: its
+// kind of data, season and race weekend, alone and combined, such as
+// "race", "2025", "race/2025", "2025/3" and "race/2025/3".
+func cacheTags(route, key string) []string {
+	kind := strings.Split(route, "/")[2]
+	tags := []string{kind}
+	if year := keyParam(route, key, "year"); year != "" {
+		tags = append(tags, year, kind+"/"+year)
+		if race := keyParam(route, key, "race_name"); race != "" {
+			tags = append(tags, year+"/"+race, kind+"/"+year+"/"+race)
+		}
+	}
+	for i, tag := range tags {
+		tags[i] = tagNode(tag)
+	}
+	return tags
+}
+
+// tagNode is what entries tagged tag are built from, as far as the cache is
+// concerned.
+func tagNode(tag string) string {
+	return "tag:" + tag
+}
+
+// tagKey is tag with its race named as raceKey names it, so "2025/Monaco"
+// finds what "/api/race/2025/monaco" was cached under.
+func (s *Server) tagKey(ctx context.Context, tag string) string {
+	parts := strings.Split(tag, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if _, err := strconv.Atoi(parts[i]); err == nil {
+			parts[i+1] = s.raceKey(ctx, parts[i], parts[i+1])
+			break
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// invalidateTags drops the cached responses with any of the ?tag= tags,
+// and what was built from them, e.g. ?tag=2025/3 for everything about
+// round 3 of 2025 after an upstream correction.
+func (s *Server) invalidateTags(c *gin.Context) {
+	tags := c.QueryArray("tag")
+	if len(tags) == 0 {
+		apierror.InvalidParameter.Respond(c, "tag is required, e.g. ?tag=2025/3 or ?tag=race/2025")
+		return
+	}
+	dropped := make(map[string]int, len(tags))
+	for _, tag := range tags {
+		dropped[tag] = s.invalidate(c.Request.Context(), tagNode(s.tagKey(c.Request.Context(), tag)))
+	}
+	c.JSON(http.StatusOK, gin.H{"dropped": dropped})
+}
+
+// cacheResponse stores a data service response for route under key. When
+// it replaces a different response, such as corrected race results, the
+// entries built from the old one are dropped rather than left to expire.
+func (s *Server) cacheResponse(ctx context.Context, route, key string, body []byte, cacheControl string, ttl time.Duration) {
+	old, replaced := s.cache.Peek(ctx, key)
+	s.cache.Set(ctx, key, body, cacheControl, ttl, cacheDeps(route, key)...)
+	if replaced && !bytes.Equal(old.Body, body) {
+		if n := s.invalidate(ctx, key); n > 0 {
+			log.Printf("cache: %s changed upstream; dropped %d responses built from it", key, n)
+		}
+	}
+}
+
+// invalidate drops the cached responses built from node, archived copies
+// included, and returns how many there were.
+func (s *Server) invalidate(ctx context.Context, node string) int {
+	dropped := s.cache.Invalidate(ctx, node)
+	if s.archive != nil {
+		for _, key := range dropped {
+			if err := s.archive.Delete(key); err != nil {
+				log.Printf("archive delete %s: %v", key, err)
+			}
+		}
+	}
+	return len(dropped)
+}
+
+// scheduleKeep is how long the calendar last seen is remembered; the check
+// saves it again on every run.
+const scheduleKeep = 30 * 24 * time.Hour
+
+// checkSchedule compares this season's calendar with the one it saw last,
+// kept in the state store so replicas and restarts share it, and drops the
+// cached data of every race whose calendar entry changed: a race moved,
+// renamed, cancelled or renumbered by a race added before it.
+func (s *Server) checkSchedule(ctx context.Context) error {
+	season := time.Now().UTC().Year()
+	year := strconv.Itoa(season)
+	schedule, err := s.history.Schedule(ctx, season)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]string, len(schedule))
+	for _, race := range schedule {
+		raw, err := jsoncodec.Default.Marshal(race)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(raw)
+		seen[race.Round] = hex.EncodeToString(sum[:8])
+	}
+
+	key := "schedule:" + year
+	var before map[string]string
+	entry, ok := s.state.Load(ctx, key)
+	if ok && jsoncodec.Default.Unmarshal(entry.Body, &before) != nil {
+		ok = false
+	}
+	raw, err := jsoncodec.Default.Marshal(seen)
+	if err != nil {
+		return err
+	}
+	s.state.Save(ctx, key, respcache.Entry{Body: raw, Stored: time.Now()}, scheduleKeep)
+	// The first look has nothing to compare with
+	if !ok {
+		return nil
+	}
+
+	var changed []string
+	for round, sum := range seen {
+		if before[round] != sum {
+			changed = append(changed, round)
+		}
+	}
+	for round := range before {
+		if _, ok := seen[round]; !ok {
+			changed = append(changed, round)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	slices.SortFunc(changed, func(a, b string) int { return cmp.Compare(atoi(a), atoi(b)) })
+	n := s.invalidate(ctx, scheduleNode(year, ""))
+	for _, round := range changed {
+		n += s.invalidate(ctx, scheduleNode(year, round))
+	}
+	log.Printf("schedule: %s calendar changed in rounds %s; dropped %d cached responses", year, strings.Join(changed, ", "), n)
+	return nil
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/ekjyotshinh/f1-server/transform"
+	"github.com/gin-gonic/gin"
+)
+
+// maxJSONPBytes caps JSONP bodies; script tags are no place for bulk data.
+const maxJSONPBytes = 1 << 20
+
+// callbackName allows plain identifiers and dotted paths such as
+// "F1Widget.render", nothing that could break out of the call expression.
+var callbackName = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]{0,63}(\.[A-Za-z_$][A-Za-z0-9_$]{0,63}){0,3}$`)
+
+// jsonp wraps JSON responses in ?callback= for embeds that can't use CORS.
+// Requests without a callback are untouched.
+func jsonp(c *gin.Context) {
+	callback, ok := c.GetQuery("callback")
+	if !ok {
+		c.Next()
+		return
+	}
+	if !callbackName.MatchString(callback) {
+		apierror.InvalidParameter.Abort(c, "invalid callback parameter")
+		return
+	}
+
+	w := &bufferedWriter{ResponseWriter: c.Writer}
+	c.Writer = w
+	c.Next()
+	c.Writer = w.ResponseWriter
+
+	body := w.buf.Bytes()
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		c.Writer.Write(body)
+		return
+	}
+	// negotiate has already validated the query and won't see JSON from here
+	steps, _ := transform.Parse(c.Request.URL.Query(), transform.DateFormat)
+	body = formatJSON(body, steps)
+	if len(body) > maxJSONPBytes {
+		c.Writer.WriteHeader(apierror.JSONPTooLarge.Status)
+		body, _ = json.Marshal(apierror.JSONPTooLarge.Body(c, "Response too large for JSONP"))
+	}
+
+	// U+2028 and U+2029 are valid in JSON but end statements in older JavaScript
+	body = bytes.ReplaceAll(body, []byte("\u2028"), []byte(`\u2028`))
+	body = bytes.ReplaceAll(body, []byte("\u2029"), []byte(`\u2029`))
+
+	// The leading comment defuses content sniffing attacks such as Rosetta Flash
+	w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+	w.Header().Del("Content-Length")
+	c.Writer.WriteString("/**/" + callback + "(")
+	c.Writer.Write(body)
+	c.Writer.WriteString(");")
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/gin-gonic/gin"
+)
+
+type requestIDKey struct{}
+
+// withRequestID carries the request ID into upstream calls made with ctx.
+func withRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// requestIDFrom returns the request ID ctx carries, if any.
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// logRequests writes one structured line per request, with what the proxy
+// learned about it.
+func logRequests(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	attrs := []slog.Attr{
+		slog.String("request_id", c.GetString(apierror.RequestIDKey)),
+		slog.String("method", c.Request.Method),
+		slog.String("path", c.Request.URL.Path),
+		slog.String("route", c.FullPath()),
+		slog.Int("status", c.Writer.Status()),
+		slog.Float64("latency_ms", milliseconds(time.Since(start))),
+		slog.Int("bytes", c.Writer.Size()),
+		slog.String("client_ip", c.ClientIP()),
+	}
+	if v, ok := c.Get(ctxUpstreamStatus); ok {
+		attrs = append(attrs, slog.Any(ctxUpstreamStatus, v))
+	}
+	if v, ok := c.Get(ctxUpstreamLatency); ok {
+		attrs = append(attrs, slog.Float64("upstream_latency_ms", milliseconds(v.(time.Duration))))
+	}
+	if v := c.GetString(ctxCacheState); v != "" {
+		attrs = append(attrs, slog.String(ctxCacheState, v))
+	}
+	if errs := c.Errors.ByType(gin.ErrorTypeAny); len(errs) > 0 {
+		attrs = append(attrs, slog.String("errors", errs.String()))
+	}
+
+	level := slog.LevelInfo
+	if c.Writer.Status() >= 500 {
+		level = slog.LevelError
+	}
+	slog.LogAttrs(c.Request.Context(), level, "request", attrs...)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/ekjyotshinh/f1-server/jsoncodec"
+	"github.com/ekjyotshinh/f1-server/respcache"
+	"github.com/gin-gonic/gin"
+)
+
+// memo keeps the responses of endpoints the gateway computes from data
+// service data, such as /api/compare. Each is tagged with the revision of
+// the data it was computed from, so it is recomputed once that data
+// changes and not before. The least recently used go first.
+type memo struct {
+	max int
+
+	mu      sync.Mutex
+	order   *list.List // of *memoEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type memoEntry struct {
+	key      string
+	revision string
+	body     []byte
+}
+
+// newMemo keeps up to max responses; max <= 0 keeps none.
+func newMemo(max int) *memo {
+	return &memo{max: max, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (m *memo) get(key, revision string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.entries[key]
+	if !ok || el.Value.(*memoEntry).revision != revision {
+		return nil, false
+	}
+	m.order.MoveToFront(el)
+	return el.Value.(*memoEntry).body, true
+}
+
+func (m *memo) set(key, revision string, body []byte) {
+	if m.max <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.entries[key]; ok {
+		el.Value = &memoEntry{key: key, revision: revision, body: body}
+		m.order.MoveToFront(el)
+		return
+	}
+	m.entries[key] = m.order.PushFront(&memoEntry{key: key, revision: revision, body: body})
+	for m.order.Len() > m.max {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoEntry).key)
+	}
+}
+
+// computed answers a request whose response is computed from the data
+// service responses of routes, proxy routes whose parameters the request
+// shares. compute is given their bodies in order and returns the response,
+// or responds with an error itself and returns false. Responses are
+// memoized per URL until any of the bodies change.
+func (s *Server) computed(c *gin.Context, routes []string, compute func(bodies [][]byte) (any, bool)) {
+	h := sha256.New()
+	bodies := make([][]byte, len(routes))
+	for i, route := range routes {
+		body, ok := s.upstreamBody(c, route)
+		if !ok {
+			return
+		}
+		bodies[i] = body
+		h.Write(body)
+		h.Write([]byte{0})
+	}
+	revision := hex.EncodeToString(h.Sum(nil))
+	key := c.Request.URL.Path + "?" + c.Request.URL.RawQuery
+
+	if body, ok := s.memo.get(key, revision); ok {
+		c.Header("X-Cache", respcache.Hit.String())
+		c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+		return
+	}
+	v, ok := compute(bodies)
+	if !ok {
+		return
+	}
+	body, err := jsoncodec.Default.Marshal(v)
+	if err != nil {
+		apierror.Internal.Respond(c, "Failed to encode response")
+		return
+	}
+	s.memo.set(key, revision, body)
+	c.Header("X-Cache", respcache.Miss.String())
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// decodeUpstream decodes a data service body into v, responding with an
+// error when it isn't valid JSON.
+func decodeUpstream(c *gin.Context, body []byte, v any) bool {
+	if err := jsoncodec.Default.Unmarshal(body, v); err != nil {
+		apierror.UpstreamInvalid.Respond(c, "Data service returned invalid JSON")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/respcache"
+	"github.com/ekjyotshinh/f1-server/upstream"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics are the Prometheus series served on /metrics. Each Server has its
+// own registry, so several can run in one process.
+type metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+	upstream *prometheus.CounterVec
+	cache    *prometheus.CounterVec
+
+	upstreamInFlight  *prometheus.GaugeVec
+	upstreamQueued    *prometheus.GaugeVec
+	upstreamWait      *prometheus.HistogramVec
+	upstreamRejected  *prometheus.CounterVec
+	upstreamFallbacks *prometheus.CounterVec
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "f1_http_requests_total",
+			Help: "Requests handled, by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		// Telemetry takes minutes on a cold FastF1 cache
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "f1_http_request_duration_seconds",
+			Help:    "Time to serve a request, by route.",
+			Buckets: []float64{0.005, 0.025, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+		}, []string{"route"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "f1_http_requests_in_flight",
+			Help: "Requests currently being served.",
+		}),
+		upstream: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "f1_upstream_responses_total",
+			Help: `Data service calls, by route and status code ("error" when unreachable).`,
+		}, []string{"route", "code"}),
+		cache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "f1_cache_lookups_total",
+			Help: "Response cache lookups, by route and result (hit, stale or miss).",
+		}, []string{"route", "result"}),
+		upstreamInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "f1_upstream_in_flight",
+			Help: "Data service calls in flight, by route.",
+		}, []string{"route"}),
+		upstreamQueued: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "f1_upstream_queued",
+			Help: "Data service calls waiting for a concurrency slot, by route.",
+		}, []string{"route"}),
+		upstreamWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "f1_upstream_queue_wait_seconds",
+			Help:    "Time data service calls waited for a concurrency slot, by route.",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"route"}),
+		upstreamRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "f1_upstream_queue_rejected_total",
+			Help: "Data service calls refused because the route's queue was full or too slow, by route.",
+		}, []string{"route"}),
+		upstreamFallbacks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "f1_upstream_fallbacks_total",
+			Help: "Requests answered from the historical data provider because the data service failed, by route.",
+		}, []string{"route"}),
+	}
+	m.registry.MustRegister(m.requests, m.duration, m.inFlight, m.upstream, m.cache,
+		m.upstreamInFlight, m.upstreamQueued, m.upstreamWait, m.upstreamRejected, m.upstreamFallbacks,
+		collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return m
+}
+
+// cacheLocks exports how contended the response cache's shard locks are.
+type cacheLocks struct {
+	cache *respcache.Cache
+}
+
+var (
+	cacheLockAcquired = prometheus.NewDesc("f1_cache_lock_acquisitions_total",
+		"Response cache lock acquisitions, by lock and shard.", []string{"lock", "shard"}, nil)
+	cacheLockWaited = prometheus.NewDesc("f1_cache_lock_waits_total",
+		"Response cache lock acquisitions that waited for another holder, by lock and shard.", []string{"lock", "shard"}, nil)
+)
+
+func (cacheLocks) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheLockAcquired
+	ch <- cacheLockWaited
+}
+
+func (l cacheLocks) Collect(ch chan<- prometheus.Metric) {
+	for _, st := range l.cache.LockStats() {
+		shard := strconv.Itoa(st.Shard)
+		ch <- prometheus.MustNewConstMetric(cacheLockAcquired, prometheus.CounterValue, float64(st.Acquired), st.Lock, shard)
+		ch <- prometheus.MustNewConstMetric(cacheLockWaited, prometheus.CounterValue, float64(st.Waited), st.Lock, shard)
+	}
+}
+
+// middleware records every request. Unmatched paths share one label so
+// scanners can't blow up the number of series.
+func (m *metrics) middleware(c *gin.Context) {
+	m.inFlight.Inc()
+	defer m.inFlight.Dec()
+	start := time.Now()
+	c.Next()
+
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	m.requests.WithLabelValues(route, c.Request.Method, strconv.Itoa(c.Writer.Status())).Inc()
+	m.duration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+	if state := c.GetString(ctxCacheState); state != "" && state != "uncached" {
+		m.cache.WithLabelValues(route, strings.ToLower(state)).Inc()
+	}
+}
+
+// observeUpstream records one data service call; status 0 means it failed
+// before a response.
+func (s *Server) observeUpstream(route string, status int, elapsed time.Duration) {
+	s.latency.Observe(route, status, elapsed)
+	code := "error"
+	if status != 0 {
+		code = strconv.Itoa(status)
+	}
+	s.metrics.upstream.WithLabelValues(route, code).Inc()
+}
+
+// acquireUpstream waits for a concurrency slot to call route and returns the
+// function that frees it.
+func (s *Server) acquireUpstream(ctx context.Context, route string) (func(), error) {
+	start := time.Now()
+	release, err := s.concurrency.Acquire(ctx, route, func(waiting bool) {
+		if waiting {
+			s.metrics.upstreamQueued.WithLabelValues(route).Inc()
+			return
+		}
+		s.metrics.upstreamQueued.WithLabelValues(route).Dec()
+		s.metrics.upstreamWait.WithLabelValues(route).Observe(time.Since(start).Seconds())
+	})
+	if err != nil {
+		if errors.Is(err, upstream.ErrQueueFull) || errors.Is(err, upstream.ErrQueueTimeout) {
+			s.metrics.upstreamRejected.WithLabelValues(route).Inc()
+		}
+		return nil, err
+	}
+	s.metrics.upstreamInFlight.WithLabelValues(route).Inc()
+	return func() {
+		s.metrics.upstreamInFlight.WithLabelValues(route).Dec()
+		release()
+	}, nil
+}
+
+func (m *metrics) handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
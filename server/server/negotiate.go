@@ -0,0 +1,71 @@
+package server
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/ekjyotshinh/f1-server/transform"
+	"github.com/gin-gonic/gin"
+)
+
+// negotiate is the serialization layer for JSON responses: it applies
+// ?date_format= and renders YAML for clients that send Accept:
+// application/yaml. Other responses pass through untouched.
+func negotiate(c *gin.Context) {
+	c.Writer.Header().Add("Vary", "Accept")
+	steps, err := transform.Parse(c.Request.URL.Query(), transform.DateFormat)
+	if err != nil {
+		apierror.InvalidParameter.Abort(c, err.Error())
+		return
+	}
+	yaml := transform.PrefersYAML(c.GetHeader("Accept"))
+	if len(steps) == 0 && !yaml {
+		c.Next()
+		return
+	}
+
+	w := &bufferedWriter{ResponseWriter: c.Writer}
+	c.Writer = w
+	c.Next()
+	c.Writer = w.ResponseWriter
+
+	body := w.buf.Bytes()
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		body = formatJSON(body, steps)
+		if out, err := transform.YAML(body); yaml && err == nil {
+			body = out
+			w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+		}
+	}
+	w.Header().Del("Content-Length")
+	if len(body) > 0 {
+		c.Writer.Write(body)
+	}
+}
+
+// formatJSON applies serialization steps, leaving bodies that aren't valid
+// JSON as they are.
+func formatJSON(body []byte, steps []transform.Step) []byte {
+	if out, err := transform.Apply(body, steps); err == nil {
+		return out
+	}
+	return body
+}
+
+// bufferedWriter holds the body back so it can be re-encoded. The status is
+// recorded by the wrapped writer and sent with the first real write.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) { return w.buf.Write(b) }
+
+func (w *bufferedWriter) WriteString(s string) (int, error) { return w.buf.WriteString(s) }
+
+func (w *bufferedWriter) Written() bool { return false }
+
+func (w *bufferedWriter) Flush() {}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/gin-gonic/gin"
+)
+
+type onThisDayWinner struct {
+	DriverID string `json:"driver_id"`
+	Name     string `json:"name"`
+	Team     string `json:"team"`
+	Grid     int    `json:"grid"` // 0 for a pit lane start
+}
+
+type onThisDayRace struct {
+	Year    int             `json:"year"`
+	Round   int             `json:"round"`
+	Race    string          `json:"race"`
+	Circuit string          `json:"circuit"`
+	Country string          `json:"country"`
+	Winner  onThisDayWinner `json:"winner"`
+	// Notes are the notable moments of the race, e.g. a maiden win
+	Notes []string `json:"notes"`
+}
+
+type onThisDayEntry struct {
+	day   string // UTC date the entry was computed on
+	races []onThisDayRace
+}
+
+// onThisDay lists the championship races held on ?date=MM-DD (default:
+// today) in any season, with their winners and what made them notable.
+func (s *Server) onThisDay(c *gin.Context) {
+	now := time.Now().UTC()
+	date := c.DefaultQuery("date", now.Format("01-02"))
+	if _, err := time.Parse("01-02", date); err != nil {
+		apierror.InvalidParameter.Respond(c, "date must be a day of the year as MM-DD")
+		return
+	}
+
+	races, err := s.loadOnThisDay(c.Request.Context(), date, now.Format(time.DateOnly))
+	if err != nil {
+		c.Error(err)
+		apierror.HistoryUnavailable.Respond(c, "Failed to reach historical data provider")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"date": date, "races": races})
+}
+
+// loadOnThisDay returns the races held on date, computing them at most once
+// per calendar day: a race finishing today changes the answer tomorrow.
+func (s *Server) loadOnThisDay(ctx context.Context, date, today string) ([]onThisDayRace, error) {
+	s.onThisDayMu.Lock()
+	defer s.onThisDayMu.Unlock()
+
+	if entry, ok := s.onThisDayCache[date]; ok && entry.day == today {
+		return entry.races, nil
+	}
+	winners, err := s.history.Winners(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Notes need each winner's tally up to the race, and whether they ever
+	// won again after it
+	driverWins := map[string]int{}
+	teamWins := map[string]int{}
+	lastWin := map[string]int{} // driver ID to index of their latest win
+	for i, race := range winners {
+		if len(race.Results) > 0 {
+			lastWin[race.Results[0].Driver.DriverID] = i
+		}
+	}
+
+	races := []onThisDayRace{}
+	for i, race := range winners {
+		if len(race.Results) == 0 {
+			continue
+		}
+		win := race.Results[0]
+		driverWins[win.Driver.DriverID]++
+		teamWins[win.Constructor.ConstructorID]++
+		if !strings.HasSuffix(race.Date, "-"+date) {
+			continue
+		}
+
+		r := onThisDayRace{
+			Year:    race.SeasonInt(),
+			Round:   race.RoundInt(),
+			Race:    race.RaceName,
+			Circuit: race.Circuit.CircuitName,
+			Country: race.Circuit.Location.Country,
+			Winner: onThisDayWinner{
+				DriverID: win.Driver.DriverID,
+				Name:     win.Driver.Name(),
+				Team:     win.Constructor.Name,
+				Grid:     atoi(win.Grid),
+			},
+			Notes: []string{},
+		}
+		name, wins := win.Driver.Name(), driverWins[win.Driver.DriverID]
+		switch {
+		case wins == 1:
+			r.Notes = append(r.Notes, fmt.Sprintf("First career win for %s", name))
+		case wins%10 == 0:
+			r.Notes = append(r.Notes, fmt.Sprintf("%s's %s career win", name, ordinal(wins)))
+		}
+		// The current season's winners may well win again
+		if lastWin[win.Driver.DriverID] == i && wins > 1 && r.Year < time.Now().Year() {
+			r.Notes = append(r.Notes, fmt.Sprintf("The last of %s's %d wins", name, wins))
+		}
+		if teamWins[win.Constructor.ConstructorID] == 1 {
+			r.Notes = append(r.Notes, fmt.Sprintf("First win for %s", win.Constructor.Name))
+		}
+		switch grid := r.Winner.Grid; {
+		case grid == 0:
+			r.Notes = append(r.Notes, "Won from the pit lane")
+		case grid >= 10:
+			r.Notes = append(r.Notes, fmt.Sprintf("Won from %s on the grid", ordinal(grid)))
+		}
+		races = append(races, r)
+	}
+
+	s.onThisDayCache[date] = onThisDayEntry{day: today, races: races}
+	return races, nil
+}
+
+// ordinal formats n as 1st, 2nd, 3rd, 4th, ...
+func ordinal(n int) string {
+	suffix := "th"
+	switch n % 10 {
+	case 1:
+		suffix = "st"
+	case 2:
+		suffix = "nd"
+	case 3:
+		suffix = "rd"
+	}
+	if n%100 >= 11 && n%100 <= 13 {
+		suffix = "th"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/gin-gonic/gin"
+)
+
+// battleWindow is how many laps apart two drivers' stops can be and still
+// count as one trying to undercut or overcut the other.
+const battleWindow = 5
+
+type stint struct {
+	Stint    int    `json:"stint"`
+	Compound string `json:"compound"`
+	StartLap int    `json:"start_lap"`
+	EndLap   int    `json:"end_lap"`
+	Laps     int    `json:"laps"`
+}
+
+// stopBattle is what a stop did against the car ahead when that car
+// stopped within battleWindow laps: an undercut if this driver stopped
+// first, an overcut if they stayed out longer. Gaps are seconds behind the
+// rival on the lap before the first of the two stops and the lap after the
+// second; Delta is the time gained.
+type stopBattle struct {
+	Rival     string  `json:"rival"`
+	Kind      string  `json:"kind"`
+	RivalLap  int     `json:"rival_lap"`
+	GapBefore float64 `json:"gap_before"`
+	GapAfter  float64 `json:"gap_after"`
+	Delta     float64 `json:"delta"`
+	Passed    bool    `json:"passed"`
+}
+
+type strategyStop struct {
+	Lap    int         `json:"lap"` // the stop came at the end of this lap
+	From   string      `json:"from"`
+	To     string      `json:"to"`
+	Battle *stopBattle `json:"battle"`
+}
+
+type driverStrategy struct {
+	Driver   string         `json:"driver"`
+	Team     string         `json:"team"`
+	Position *int           `json:"position"`
+	Stints   []stint        `json:"stints"`
+	Stops    []strategyStop `json:"stops"`
+}
+
+// pitStops lays out every driver's race strategy: stints, stops and how each
+// stop fared against the car ahead, from the cached race and analytics
+// responses. Stops onto the same compound don't show in the data service's
+// tyre data, so they are missing here too.
+func (s *Server) pitStops(c *gin.Context) {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil {
+		apierror.InvalidParameter.Respond(c, "year must be a number")
+		return
+	}
+	s.computed(c, raceDataRoutes, func(bodies [][]byte) (any, bool) {
+		var analytics raceAnalytics
+		var race raceResults
+		if !decodeUpstream(c, bodies[0], &analytics) || !decodeUpstream(c, bodies[1], &race) {
+			return nil, false
+		}
+		if analytics.Error != "" || race.Error != "" {
+			apierror.UpstreamError.Respond(c, "Data service has no data for this race")
+			return nil, false
+		}
+		return raceStrategies(year, &analytics, &race), true
+	})
+}
+
+// raceStrategies works out each driver's stints and stops.
+func raceStrategies(year int, analytics *raceAnalytics, race *raceResults) gin.H {
+	positions := map[string]*int{}
+	for _, r := range race.Results {
+		positions[r.Abbreviation] = resultPosition(r.Position)
+	}
+	elapsed := map[string][]*float64{}
+	for d, times := range analytics.LapTimes {
+		elapsed[d] = raceTimes(times)
+	}
+
+	// Stints in order per driver; the data service lists them that way
+	stints := map[string][]stint{}
+	for _, st := range analytics.TireStrategy {
+		list := stints[st.Driver]
+		if n := len(list); n > 0 {
+			list[n-1].EndLap = st.Lap - 1
+		}
+		stints[st.Driver] = append(list, stint{Stint: st.Stint, Compound: st.Compound, StartLap: st.Lap})
+	}
+
+	strategies := make([]driverStrategy, 0, len(stints))
+	for d, list := range stints {
+		list[len(list)-1].EndLap = len(analytics.LapTimes[d])
+		for i := range list {
+			list[i].Laps = list[i].EndLap - list[i].StartLap + 1
+		}
+		ds := driverStrategy{
+			Driver:   d,
+			Team:     analytics.DriverInfo[d].Team,
+			Position: positions[d],
+			Stints:   list,
+			Stops:    []strategyStop{},
+		}
+		for i := 1; i < len(list); i++ {
+			stop := strategyStop{Lap: list[i-1].EndLap, From: list[i-1].Compound, To: list[i].Compound}
+			stop.Battle = battle(analytics, stints, elapsed, d, stop.Lap)
+			ds.Stops = append(ds.Stops, stop)
+		}
+		strategies = append(strategies, ds)
+	}
+	// Classified drivers in finishing order, then the rest
+	sort.Slice(strategies, func(i, j int) bool {
+		a, b := strategies[i].Position, strategies[j].Position
+		switch {
+		case a != nil && b != nil:
+			return *a < *b
+		case a != nil || b != nil:
+			return a != nil
+		}
+		return strategies[i].Driver < strategies[j].Driver
+	})
+
+	return gin.H{
+		"year":       year,
+		"race":       race.RaceName,
+		"total_laps": analytics.TotalLaps,
+		"drivers":    strategies,
+	}
+}
+
+// battle compares driver's stop at the end of lap with the car running ahead
+// of them before it, or returns nil when that car didn't stop nearby or the
+// lap times to compare are missing.
+func battle(analytics *raceAnalytics, stints map[string][]stint, elapsed map[string][]*float64, driver string, lap int) *stopBattle {
+	before := lapValue(analytics.Positions[driver], lap-1)
+	if before == nil || *before <= 1 {
+		return nil
+	}
+	var rival string
+	for d, positions := range analytics.Positions {
+		if p := lapValue(positions, lap-1); p != nil && *p == *before-1 {
+			rival = d
+		}
+	}
+	rivalLap := 0
+	for i, st := range stints[rival] {
+		if stop := st.StartLap - 1; i > 0 && stop != lap && abs(stop-lap) <= battleWindow {
+			rivalLap = stop
+			break
+		}
+	}
+	if rivalLap == 0 {
+		return nil
+	}
+
+	first, last := min(lap, rivalLap), max(lap, rivalLap)
+	gapBefore, ok1 := gapAt(elapsed, driver, rival, first-1)
+	gapAfter, ok2 := gapAt(elapsed, driver, rival, last+1)
+	if !ok1 || !ok2 {
+		return nil
+	}
+	kind := "undercut"
+	if lap > rivalLap {
+		kind = "overcut"
+	}
+	b := &stopBattle{
+		Rival:     rival,
+		Kind:      kind,
+		RivalLap:  rivalLap,
+		GapBefore: gapBefore,
+		GapAfter:  gapAfter,
+		Delta:     math.Round((gapBefore-gapAfter)*1000) / 1000,
+	}
+	mine, theirs := lapValue(analytics.Positions[driver], last+1), lapValue(analytics.Positions[rival], last+1)
+	b.Passed = mine != nil && theirs != nil && *mine < *theirs
+	return b
+}
+
+// raceTimes sums lap times into the race time at the end of each lap, which
+// is unknown from the first lap without a time on.
+func raceTimes(times []*float64) []*float64 {
+	out := make([]*float64, len(times))
+	total := 0.0
+	for i, t := range times {
+		if t == nil {
+			break
+		}
+		total += *t
+		v := total
+		out[i] = &v
+	}
+	return out
+}
+
+// gapAt is how far a was behind b at the end of lap, in seconds.
+func gapAt(elapsed map[string][]*float64, a, b string, lap int) (float64, bool) {
+	ta, tb := lapValue(elapsed[a], lap), lapValue(elapsed[b], lap)
+	if ta == nil || tb == nil {
+		return 0, false
+	}
+	return math.Round((*ta-*tb)*1000) / 1000, true
+}
+
+// lapValue returns the entry for lap (from 1) of a per-lap list.
+func lapValue[T any](list []*T, lap int) *T {
+	if lap < 1 || lap > len(list) {
+		return nil
+	}
+	return list[lap-1]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
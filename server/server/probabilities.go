@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/ekjyotshinh/f1-server/jolpica"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSimulations = 10000
+	maxSimulations     = 50000
+)
+
+var (
+	racePoints   = []float64{25, 18, 15, 12, 10, 8, 6, 4, 2, 1}
+	sprintPoints = []float64{8, 7, 6, 5, 4, 3, 2, 1}
+)
+
+type titleOdds struct {
+	DriverID       string  `json:"driver_id"`
+	Code           string  `json:"code,omitempty"`
+	Name           string  `json:"name"`
+	Points         float64 `json:"points"`
+	Probability    float64 `json:"title_probability"`
+	ExpectedPoints float64 `json:"expected_points"`
+}
+
+type probabilityReport struct {
+	Year             int         `json:"year"`
+	AfterRound       int         `json:"after_round"`
+	RemainingRaces   int         `json:"remaining_races"`
+	RemainingSprints int         `json:"remaining_sprints"`
+	Simulations      int         `json:"simulations"`
+	Drivers          []titleOdds `json:"drivers"`
+}
+
+// pacePrior is a driver's simple form model: where they tend to finish, how
+// much that varies, and how often they don't finish at all.
+type pacePrior struct {
+	mean, spread, dnfRate float64
+}
+
+// championshipProbabilities runs a Monte Carlo simulation of the remaining
+// races of :year and reports each driver's chance of winning the title.
+// Results are cached until a new round is completed.
+func (s *Server) championshipProbabilities(c *gin.Context) {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil || year < firstSeason || year > time.Now().Year() {
+		apierror.InvalidParameter.Respond(c, "year must be a season between 1950 and this year")
+		return
+	}
+	sims := defaultSimulations
+	if v := c.Query("simulations"); v != "" {
+		if sims, err = strconv.Atoi(v); err != nil || sims < 1 || sims > maxSimulations {
+			apierror.InvalidParameter.Respond(c, fmt.Sprintf("simulations must be between 1 and %d", maxSimulations))
+			return
+		}
+	}
+
+	report, err := s.simulateChampionship(c.Request.Context(), year, sims)
+	if err != nil {
+		c.Error(err)
+		apierror.HistoryUnavailable.Respond(c, "Failed to reach historical data provider")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (s *Server) simulateChampionship(ctx context.Context, year, sims int) (*probabilityReport, error) {
+	standings, err := s.history.DriverStandings(ctx, year)
+	if err != nil {
+		return nil, err
+	}
+	results, err := s.history.SeasonResults(ctx, year)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := s.history.Schedule(ctx, year)
+	if err != nil {
+		return nil, err
+	}
+
+	afterRound := 0
+	for _, race := range results {
+		afterRound = max(afterRound, race.RoundInt())
+	}
+
+	key := fmt.Sprintf("%d/%d/%d", year, afterRound, sims)
+	s.oddsMu.Lock()
+	cached, ok := s.odds[key]
+	s.oddsMu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	var remaining, sprints int
+	for _, race := range schedule {
+		if race.RoundInt() > afterRound {
+			remaining++
+			if race.Sprint != nil {
+				sprints++
+			}
+		}
+	}
+
+	priors := pacePriors(results)
+	report := &probabilityReport{
+		Year:             year,
+		AfterRound:       afterRound,
+		RemainingRaces:   remaining,
+		RemainingSprints: sprints,
+		Simulations:      sims,
+		Drivers:          make([]titleOdds, len(standings)),
+	}
+	base := make([]float64, len(standings))
+	for i, st := range standings {
+		base[i] = st.PointsFloat()
+		report.Drivers[i] = titleOdds{
+			DriverID: st.Driver.DriverID,
+			Code:     st.Driver.Code,
+			Name:     st.Driver.Name(),
+			Points:   base[i],
+		}
+	}
+
+	// Seeded from the inputs so a cached and a fresh answer always agree
+	if len(standings) == 0 {
+		return report, nil // season hasn't started
+	}
+
+	rng := rand.New(rand.NewSource(int64(year*1000 + afterRound)))
+	wins := make([]int, len(standings))
+	totals := make([]float64, len(standings))
+	points := make([]float64, len(standings))
+	scores := make([]float64, len(standings))
+	order := make([]int, len(standings))
+	for n := 0; n < sims; n++ {
+		copy(points, base)
+		for r := 0; r < remaining+sprints; r++ {
+			table := racePoints
+			if r >= remaining {
+				table = sprintPoints
+			}
+			for i, st := range standings {
+				p := priors[st.Driver.DriverID]
+				scores[i] = p.mean + rng.NormFloat64()*p.spread
+				if rng.Float64() < p.dnfRate {
+					scores[i] = math.Inf(1)
+				}
+				order[i] = i
+			}
+			sort.Slice(order, func(a, b int) bool { return scores[order[a]] < scores[order[b]] })
+			for pos, i := range order {
+				if pos >= len(table) || math.IsInf(scores[i], 1) {
+					break
+				}
+				points[i] += table[pos]
+			}
+		}
+
+		champ := 0
+		for i := range points {
+			totals[i] += points[i]
+			if points[i] > points[champ] {
+				champ = i
+			}
+		}
+		wins[champ]++
+	}
+
+	for i := range report.Drivers {
+		report.Drivers[i].Probability = float64(wins[i]) / float64(sims)
+		report.Drivers[i].ExpectedPoints = math.Round(totals[i]/float64(sims)*10) / 10
+	}
+	sort.SliceStable(report.Drivers, func(i, j int) bool {
+		if report.Drivers[i].Probability != report.Drivers[j].Probability {
+			return report.Drivers[i].Probability > report.Drivers[j].Probability
+		}
+		return report.Drivers[i].Points > report.Drivers[j].Points
+	})
+
+	s.oddsMu.Lock()
+	s.odds[key] = report
+	s.oddsMu.Unlock()
+	return report, nil
+}
+
+// pacePriors derives each driver's form from the season's race results.
+// Drivers with little data get a wide spread so the simulation stays humble.
+func pacePriors(results []jolpica.Race) map[string]pacePrior {
+	finishes := map[string][]float64{}
+	starts := map[string]int{}
+	dnfs := map[string]int{}
+	for _, race := range results {
+		for _, r := range race.Results {
+			id := r.Driver.DriverID
+			starts[id]++
+			if !r.Finished() {
+				dnfs[id]++
+				continue
+			}
+			finishes[id] = append(finishes[id], float64(r.PositionInt()))
+		}
+	}
+
+	priors := make(map[string]pacePrior, len(starts))
+	for id, n := range starts {
+		p := pacePrior{mean: 15, spread: 5, dnfRate: 0.1}
+		if f := finishes[id]; len(f) > 0 {
+			p.mean = mean(f)
+			var sq float64
+			for _, x := range f {
+				sq += (x - p.mean) * (x - p.mean)
+			}
+			p.spread = math.Max(2, math.Sqrt(sq/float64(len(f))))
+		}
+		p.dnfRate = math.Min(0.25, math.Max(0.05, float64(dnfs[id])/float64(n)))
+		priors[id] = p
+	}
+	return priors
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// probeResult is the latest outcome of one synthetic check.
+type probeResult struct {
+	Path                string     `json:"path"`
+	OK                  bool       `json:"ok"`
+	Status              int        `json:"status"`
+	DurationMs          float64    `json:"duration_ms"`
+	CheckedAt           time.Time  `json:"checked_at"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+}
+
+type prober struct {
+	mu      sync.Mutex
+	results map[string]*probeResult
+}
+
+// probe requests every configured route through the full handler chain, as
+// a user would, so regressions show up between real visits. Probe traffic
+// counts towards the SLO and latency metrics like any other request.
+func (s *Server) probe(ctx context.Context) error {
+	var failed []string
+	for _, path := range s.cfg.ProbeRoutes {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		req := httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx)
+		req.Header.Set("User-Agent", "f1-self-probe")
+		req.Header.Set("Cache-Control", "no-cache") // probe the data service, not the cache
+		rec := httptest.NewRecorder()
+
+		start := time.Now()
+		s.ServeHTTP(rec, req)
+		ok := rec.Code == http.StatusOK
+		if !ok {
+			failed = append(failed, fmt.Sprintf("%s (%d)", path, rec.Code))
+		}
+		s.probes.record(path, ok, rec.Code, time.Since(start))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("probes failed: %v", failed)
+	}
+	return nil
+}
+
+func (p *prober) record(path string, ok bool, status int, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r, found := p.results[path]
+	if !found {
+		r = &probeResult{Path: path}
+		p.results[path] = r
+	}
+	r.OK, r.Status = ok, status
+	r.DurationMs = float64(d.Microseconds()) / 1000
+	r.CheckedAt = time.Now().UTC()
+	if ok {
+		r.ConsecutiveFailures = 0
+		at := r.CheckedAt
+		r.LastSuccess = &at
+	} else {
+		r.ConsecutiveFailures++
+	}
+}
+
+// status serves the latest probe results. The service is "degraded" while
+// any probe is failing and "unknown" before the first run.
+func (s *Server) status(c *gin.Context) {
+	s.probes.mu.Lock()
+	results := make([]probeResult, 0, len(s.cfg.ProbeRoutes))
+	for _, path := range s.cfg.ProbeRoutes {
+		if r, ok := s.probes.results[path]; ok {
+			results = append(results, *r)
+		}
+	}
+	s.probes.mu.Unlock()
+
+	state := "ok"
+	if len(results) == 0 {
+		state = "unknown"
+	}
+	for _, r := range results {
+		if !r.OK {
+			state = "degraded"
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"status": state, "probes": results})
+}
@@ -0,0 +1,845 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/ekjyotshinh/f1-server/budget"
+	"github.com/ekjyotshinh/f1-server/demo"
+	"github.com/ekjyotshinh/f1-server/jsoncodec"
+	"github.com/ekjyotshinh/f1-server/respcache"
+	"github.com/ekjyotshinh/f1-server/transform"
+	"github.com/ekjyotshinh/f1-server/upstream"
+	"github.com/gin-gonic/gin"
+)
+
+// proxyRoute maps a gateway route to a data service path. Path parameters
+// in upstream use the same :name syntax as the gateway route.
+type proxyRoute struct {
+	route    string
+	upstream string
+	// query are the query parameters the data service reads, the only ones
+	// forwarded; the rest are the gateway's own, such as ?sort=.
+	query []string
+	// stream passes the body through as it arrives instead of buffering it,
+	// for payloads too large to hold; transforms and size checks don't apply.
+	stream bool
+	// raw passes responses through the same way, skipping the response
+	// cache, when the request selects no transforms. For cheap endpoints
+	// where buffering and caching cost more than they save.
+	raw        bool
+	transforms []transform.Transform
+}
+
+// proxyRoutes are the data service endpoints exposed by the gateway. Adding
+// one is a single entry here.
+var proxyRoutes = []proxyRoute{
+	// A static list the data service answers without loading anything
+	{route: "/api/years", upstream: "/api/years", raw: true},
+	{route: "/api/schedule/:year", upstream: "/api/schedule/:year"},
+	{
+		route:      "/api/race/:year/:race_name",
+		upstream:   "/api/race/:year/:race_name",
+		transforms: []transform.Transform{enrichResults, resultsSort.Transform()},
+	},
+	// The rest of the weekend: Q1/Q2/Q3 times, and the sprint where there is one
+	{
+		route:      "/api/qualifying/:year/:race_name",
+		upstream:   "/api/qualifying/:year/:race_name",
+		transforms: []transform.Transform{enrichResults, qualifyingSort.Transform()},
+	},
+	{
+		route:      "/api/sprint/:year/:race_name",
+		upstream:   "/api/sprint/:year/:race_name",
+		transforms: []transform.Transform{enrichResults, resultsSort.Transform()},
+	},
+	{
+		route:      "/api/laps/:year/:race_name/:driver",
+		upstream:   "/api/laps/:year/:race_name/:driver",
+		transforms: []transform.Transform{lapsSort.Transform(), lapsFields.Transform(), lapsPage.Transform()},
+	},
+	{
+		route:      "/api/analytics/:year/:race_name",
+		upstream:   "/api/analytics/:year/:race_name",
+		transforms: []transform.Transform{lapAggregation},
+	},
+	// Track and air conditions over a session, ?session=R (default), Q, FP1, ...
+	{
+		route:      "/api/weather/:year/:race_name",
+		upstream:   "/api/weather/:year/:race_name",
+		query:      []string{"session"},
+		transforms: []transform.Transform{weatherSession},
+	},
+	// Telemetry for the live race replay, whole or in progressive chunks
+	{route: "/api/telemetry/:year/:race_name", upstream: "/api/telemetry/:year/:race_name", stream: true},
+	{route: "/api/telemetry/:year/:race_name/chunk/:chunk_num", upstream: "/api/telemetry/:year/:race_name/chunk/:chunk_num", stream: true},
+}
+
+// isProxyRoute reports whether route is one of proxyRoutes.
+func isProxyRoute(route string) bool {
+	return slices.ContainsFunc(proxyRoutes, func(pr proxyRoute) bool { return pr.route == route })
+}
+
+// upstreamTimeout is how long a proxied request to route may take.
+func (s *Server) upstreamTimeout(route string) time.Duration {
+	if d, ok := s.cfg.UpstreamRouteTimeouts[route]; ok {
+		return d
+	}
+	return s.cfg.UpstreamTimeout
+}
+
+// strippedRequestHeaders never reach the data service: credentials meant for
+// the gateway, and negotiation the gateway does itself.
+var strippedRequestHeaders = []string{
+	"Authorization", "Cookie", "X-Csrf-Token", "X-Client-Id", "X-Timestamp", "X-Signature",
+	"Accept-Encoding", "If-None-Match", "If-Modified-Since",
+}
+
+// passedResponseHeaders are the data service headers clients may see.
+var passedResponseHeaders = map[string]bool{
+	"Cache-Control":  true,
+	"Content-Length": true,
+	"Content-Type":   true,
+	"Expires":        true,
+	"Last-Modified":  true,
+}
+
+// requestClass groups routes with similar upstream cost for region routing.
+func requestClass(route string) string {
+	if strings.HasPrefix(route, "/api/telemetry") {
+		return "telemetry"
+	}
+	return "data"
+}
+
+// upstreamPath fills the route's parameters into its upstream path.
+func upstreamPath(pattern string, params gin.Params) string {
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments {
+		if name, ok := strings.CutPrefix(seg, ":"); ok {
+			segments[i] = url.PathEscape(params.ByName(name))
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// upstreamKey is the data service path and query for a request to pr, and
+// so its cache key: the race is named as raceKey names it, gateway
+// parameters are left out and the rest put in a fixed order, so requests
+// differing only in those share one upstream call and one entry.
+func (s *Server) upstreamKey(pr proxyRoute, c *gin.Context) string {
+	key := upstreamPath(pr.upstream, s.keyParams(c))
+	q := url.Values{}
+	for _, name := range pr.query {
+		if v, ok := c.GetQuery(name); ok {
+			q.Set(name, v)
+		}
+	}
+	if len(q) > 0 {
+		key += "?" + q.Encode()
+	}
+	return key
+}
+
+// keyParams are c's path parameters with :race_name in its raceKey form.
+func (s *Server) keyParams(c *gin.Context) gin.Params {
+	name, ok := c.Params.Get("race_name")
+	if !ok {
+		return c.Params
+	}
+	params := slices.Clone(c.Params)
+	for i := range params {
+		if params[i].Key == "race_name" {
+			params[i].Value = s.raceKey(c.Request.Context(), c.Param("year"), name)
+		}
+	}
+	return params
+}
+
+// raceKey is the spelling of a race all its others share, so that
+// "Monaco", "monaco" and its round number are one cache entry: the round
+// number when the season's calendar gives exactly one race by that race,
+// country or city name, else the name lowercased. The data service takes
+// either.
+func (s *Server) raceKey(ctx context.Context, year, name string) string {
+	if n, err := strconv.Atoi(name); err == nil {
+		return strconv.Itoa(n)
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	season, err := strconv.Atoi(year)
+	// Years with no calendar would only cost Jolpica calls
+	if err != nil || season < 1950 || season > time.Now().Year()+1 {
+		return name
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, archiveLookupTimeout)
+	defer cancel()
+	schedule, err := s.history.Schedule(ctx, season)
+	if err != nil {
+		return name
+	}
+	round := 0
+	for _, race := range schedule {
+		loc := race.Circuit.Location
+		for _, alias := range []string{race.RaceName, strings.TrimSuffix(race.RaceName, " Grand Prix"), loc.Country, loc.Locality} {
+			if !strings.EqualFold(alias, name) {
+				continue
+			}
+			if round != 0 && round != race.RoundInt() {
+				return name // e.g. a country with two races that season
+			}
+			round = race.RoundInt()
+		}
+	}
+	if round == 0 {
+		return name
+	}
+	return strconv.Itoa(round)
+}
+
+// maxErrorBody is the largest body checked by dataServiceError; the data
+// service's error bodies are small, and its data never is.
+const maxErrorBody = 64 << 10
+
+// dataServiceError reports whether body is the data service's answer to a
+// failure, such as a session FastF1 couldn't load, which it sends with a 200
+// as {"error": ...}. Those may succeed on a later try, so they aren't kept.
+func dataServiceError(body []byte) bool {
+	if len(body) > maxErrorBody {
+		return false
+	}
+	var doc struct {
+		Error any `json:"error"`
+	}
+	return jsoncodec.Default.Unmarshal(body, &doc) == nil && doc.Error != nil
+}
+
+// proxy builds the handler for a data service route.
+func (s *Server) proxy(pr proxyRoute) gin.HandlerFunc {
+	class := requestClass(pr.route)
+	ttl := s.cfg.CacheTTLs[pr.route]
+	if pr.stream {
+		ttl = 0
+	}
+	transforms, stream := pr.transforms, pr.stream
+	raw := pr.raw
+	if paths := s.cfg.Redactions[pr.route]; len(paths) > 0 {
+		// Applied last, so fields added by enrichment can be redacted too
+		transforms = append(slices.Clone(transforms), transform.Redact(paths))
+		stream, raw = false, false
+	}
+
+	return func(c *gin.Context) {
+		// Reject bad query parameters before spending an upstream call on them
+		steps, err := transform.Parse(c.Request.URL.Query(), transforms...)
+		if err != nil {
+			apierror.InvalidParameter.Respond(c, err.Error())
+			return
+		}
+
+		if s.cfg.Demo {
+			serveDemo(c, steps)
+			return
+		}
+
+		key := s.upstreamKey(pr, c)
+		if stream || (raw && len(steps) == 0) {
+			s.stream(c, pr.route, class, key)
+			return
+		}
+
+		// Cache-Control: no-cache skips the lookup but still refreshes the entry
+		c.Set(ctxCacheState, "uncached")
+		if ttl > 0 && !strings.Contains(c.GetHeader("Cache-Control"), "no-cache") {
+			entry, state := s.cache.Get(c.Request.Context(), key)
+			if state == respcache.Miss && s.settled(c) {
+				if archived, ok := s.fromArchive(c.Request.Context(), pr.route, key, ttl); ok {
+					entry, state = archived, respcache.Hit
+				}
+			}
+			c.Set(ctxCacheState, state.String())
+			if state != respcache.Miss {
+				if state == respcache.Stale {
+					s.cache.Revalidate(key, func() { s.revalidate(pr.route, class, key, ttl) })
+				}
+				serveCached(c, entry, state, steps)
+				return
+			}
+		}
+
+		resp, err := s.fetch(c.Request.Context(), pr.route, class, key)
+		if err != nil {
+			if !s.serveFallback(c, pr.route, steps) {
+				upstreamFailed(c, err)
+			}
+			return
+		}
+		c.Set(ctxUpstreamStatus, resp.status)
+		c.Set(ctxUpstreamLatency, resp.elapsed)
+		if resp.status >= http.StatusInternalServerError && s.serveFallback(c, pr.route, steps) {
+			return
+		}
+		if resp.status != http.StatusOK {
+			c.JSON(resp.status, apierror.UpstreamError.Body(c, "Data service returned error"))
+			return
+		}
+		if ttl > 0 {
+			s.cache.Fetched(key, len(resp.body), resp.elapsed)
+		}
+		if resp.anomalous {
+			s.quarantine(c.Request.Context(), pr.route, key, resp.body, anomalyUndersized)
+			if s.cfg.QuarantineMode == QuarantineHide {
+				if !s.serveFallback(c, pr.route, steps) {
+					apierror.UpstreamSuspect.Respond(c, "Data service returned a response that looks truncated")
+				}
+				return
+			}
+		}
+		doc, err := transform.Run(resp.body, steps)
+		if err != nil {
+			apierror.UpstreamInvalid.Respond(c, "Data service returned invalid JSON")
+			return
+		}
+
+		// Don't let anything downstream keep a response that looks truncated
+		if resp.anomalous {
+			c.Header("Cache-Control", "no-store")
+			c.Header("X-Data-Anomaly", anomalyUndersized)
+		} else if dataServiceError(resp.body) {
+			c.Header("Cache-Control", "no-store")
+		} else {
+			if resp.cacheControl != "" {
+				c.Header("Cache-Control", resp.cacheControl)
+			}
+			if ttl > 0 {
+				s.cacheResponse(c.Request.Context(), pr.route, key, resp.body, resp.cacheControl, ttl)
+				c.Header("X-Cache", respcache.Miss.String())
+				if s.settled(c) {
+					s.toArchive(key, resp.body)
+				}
+			}
+			if notModified(c, resp.body) {
+				return
+			}
+		}
+		writeDoc(c, doc)
+	}
+}
+
+// writeDoc answers with a document from transform.Run, streamed so large
+// payloads aren't encoded into memory in one piece.
+func writeDoc(c *gin.Context, doc any) {
+	c.Header("Content-Type", "application/json")
+	c.Status(http.StatusOK)
+	if err := transform.Write(c.Writer, doc); err != nil {
+		log.Printf("write %s: %v", c.Request.URL.Path, err)
+	}
+}
+
+// writeValue answers with v after running steps over it, for handlers that
+// build their own response but take the same query parameters as proxied
+// ones.
+func writeValue(c *gin.Context, v any, steps []transform.Step) {
+	if len(steps) == 0 {
+		c.JSON(http.StatusOK, v)
+		return
+	}
+	body, err := jsoncodec.Default.Marshal(v)
+	if err != nil {
+		apierror.Internal.Respond(c, "Failed to encode response")
+		return
+	}
+	doc, err := transform.Run(body, steps)
+	if err != nil {
+		apierror.Internal.Respond(c, "Failed to encode response")
+		return
+	}
+	writeDoc(c, doc)
+}
+
+// notModified sets the ETag of a response built from the upstream body raw
+// and, when it matches the client's If-None-Match, answers 304 and reports
+// true. The tag also covers the query and Accept header, which select the
+// transforms and format applied to raw; it is weak because compression
+// changes the bytes but not the meaning.
+func notModified(c *gin.Context, raw []byte) bool {
+	h := sha256.New()
+	h.Write(raw)
+	fmt.Fprintf(h, "\x00%s\x00%s", c.Request.URL.RawQuery, c.GetHeader("Accept"))
+	etag := `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+	c.Header("ETag", etag)
+
+	for _, tag := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag[2:] {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// upstreamResponse is a data service response read in full.
+type upstreamResponse struct {
+	status       int
+	cacheControl string
+	body         []byte
+	anomalous    bool // far smaller than the route's usual size
+	elapsed      time.Duration
+}
+
+// fetch GETs key (path and query) from the data service. Concurrent fetches
+// of the same key share one upstream call, so a race page opened by many
+// users at once costs a single FastF1 load. The shared call outlives any one
+// caller giving up, but is cancelled once every caller has, so a slow load
+// nobody is waiting for doesn't tie up the data service.
+func (s *Server) fetch(ctx context.Context, route, class, key string) (*upstreamResponse, error) {
+	// Background work can be deferred, so it must not hold up user requests
+	flightKey := key
+	if budget.PriorityOf(ctx) == budget.Background {
+		flightKey = "background:" + key
+	}
+	call := s.joinFlight(ctx, flightKey)
+	defer s.leaveFlight(ctx, flightKey, call)
+	ch := s.flight.DoChan(flightKey, func() (any, error) {
+		ctx, cancel := context.WithTimeout(call.ctx, s.upstreamTimeout(route))
+		defer cancel()
+		// User requests are never deferred; for them this only counts the call
+		if err := s.dataBudget.Take(ctx); err != nil {
+			return nil, err
+		}
+		release, err := s.acquireUpstream(ctx, route)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		region := s.regions.Pick(class)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, region.URL+key, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		if id := requestIDFrom(ctx); id != "" {
+			req.Header.Set("X-Request-Id", id)
+		}
+
+		start := time.Now()
+		resp, err := s.client.Do(req)
+		if err != nil {
+			var open *upstream.CircuitOpenError
+			if !errors.As(err, &open) && call.ctx.Err() == nil {
+				s.observeUpstream(route, 0, time.Since(start))
+			}
+			return nil, err
+		}
+		defer resp.Body.Close()
+		elapsed := time.Since(start)
+		s.observeUpstream(route, resp.StatusCode, elapsed)
+		s.regions.Observe(class, region.Name, elapsed)
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		out := &upstreamResponse{
+			status:       resp.StatusCode,
+			cacheControl: resp.Header.Get("Cache-Control"),
+			body:         body,
+			elapsed:      elapsed,
+		}
+		if resp.StatusCode == http.StatusOK {
+			out.anomalous = s.checkSize(route, key, len(body))
+		}
+		return out, nil
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*upstreamResponse), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// flightCall is the context of a shared upstream call and the number of
+// callers still waiting on it.
+type flightCall struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+}
+
+// joinFlight registers a caller of the shared call for flightKey, starting a
+// new context for it if there is none. The context keeps ctx's values, such
+// as the request ID, but not its cancellation.
+func (s *Server) joinFlight(ctx context.Context, flightKey string) *flightCall {
+	s.flightsMu.Lock()
+	defer s.flightsMu.Unlock()
+	call, ok := s.flights[flightKey]
+	if !ok {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &flightCall{ctx: callCtx, cancel: cancel}
+		s.flights[flightKey] = call
+	}
+	call.waiters++
+	return call
+}
+
+// leaveFlight unregisters a caller. When the last one leaves because its
+// context is done, the upstream call is cancelled and forgotten, so a later
+// caller starts afresh rather than sharing the cancelled call.
+func (s *Server) leaveFlight(ctx context.Context, flightKey string, call *flightCall) {
+	s.flightsMu.Lock()
+	defer s.flightsMu.Unlock()
+	call.waiters--
+	if call.waiters > 0 {
+		return
+	}
+	if ctx.Err() != nil {
+		call.cancel()
+		s.flight.Forget(flightKey)
+	}
+	if s.flights[flightKey] == call {
+		delete(s.flights, flightKey)
+	}
+}
+
+// stream passes a response through as it arrives. Telemetry payloads are too
+// large to buffer, so unlike other routes they are neither cached nor
+// coalesced.
+func (s *Server) stream(c *gin.Context, route, class, key string) {
+	c.Set(ctxCacheState, "uncached")
+	// User requests are never deferred; this only counts the call
+	s.dataBudget.Take(c.Request.Context())
+
+	region := s.regions.Pick(class)
+	target, err := url.Parse(region.URL + key)
+	if err != nil {
+		apierror.Internal.Respond(c, fmt.Sprintf("Invalid upstream URL: %v", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), s.upstreamTimeout(route))
+	defer cancel()
+	release, err := s.acquireUpstream(ctx, route)
+	if err != nil {
+		upstreamFailed(c, err)
+		return
+	}
+	defer release()
+	start := time.Now()
+
+	rp := &httputil.ReverseProxy{
+		Transport:     s.breaker,
+		FlushInterval: -1,
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.Out.URL = target
+			r.Out.Host = ""
+			for _, h := range strippedRequestHeaders {
+				r.Out.Header.Del(h)
+			}
+			r.SetXForwarded()
+			r.Out.Header.Set("X-Request-Id", c.GetString(apierror.RequestIDKey))
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			c.Set(ctxUpstreamStatus, resp.StatusCode)
+			elapsed := time.Since(start)
+			c.Set(ctxUpstreamLatency, elapsed)
+			s.observeUpstream(route, resp.StatusCode, elapsed)
+			s.regions.Observe(class, region.Name, elapsed)
+			for h := range resp.Header {
+				if !passedResponseHeaders[h] {
+					resp.Header.Del(h)
+				}
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				resp.Body.Close()
+				body, _ := json.Marshal(apierror.UpstreamError.Body(c, "Data service returned error"))
+				replaceBody(resp, body)
+				return nil
+			}
+			resp.Header.Set("Content-Type", "application/json")
+			resp.Body = &countingBody{ReadCloser: resp.Body, done: func(n int) {
+				s.checkSize(route, key, n)
+			}}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			var open *upstream.CircuitOpenError
+			if !errors.As(err, &open) && c.Request.Context().Err() == nil {
+				s.observeUpstream(route, 0, time.Since(start))
+			}
+			upstreamFailed(c, err)
+		},
+	}
+	rp.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+}
+
+// statusClientClosed is nginx's status for a client that disconnected before
+// the response was ready.
+const statusClientClosed = 499
+
+// upstreamBusyRetry is the Retry-After, in seconds, of a request turned away
+// by a full concurrency queue.
+const upstreamBusyRetry = 5
+
+// upstreamFailed answers a request the data service couldn't serve. An open
+// circuit fails fast with 503 and says when the service will next be tried;
+// so does a route whose concurrency queue is full.
+func upstreamFailed(c *gin.Context, err error) {
+	// Nobody is left to answer; 499 keeps it out of error reports
+	if c.Request.Context().Err() != nil && errors.Is(err, context.Canceled) {
+		c.AbortWithStatus(statusClientClosed)
+		return
+	}
+	var open *upstream.CircuitOpenError
+	if errors.As(err, &open) {
+		c.Header("Retry-After", strconv.Itoa(int(open.RetryAfter.Round(time.Second).Seconds())))
+		apierror.UpstreamUnavailable.Respond(c, "Data service is failing; not calling it for now")
+		return
+	}
+	if errors.Is(err, upstream.ErrQueueFull) || errors.Is(err, upstream.ErrQueueTimeout) {
+		c.Header("Retry-After", strconv.Itoa(upstreamBusyRetry))
+		apierror.UpstreamBusy.Respond(c, "Too many requests waiting for this data service endpoint")
+		return
+	}
+	apierror.UpstreamUnreachable.Respond(c, fmt.Sprintf("Failed to reach data service: %v", err))
+}
+
+// serveCached answers from the response cache.
+func serveCached(c *gin.Context, entry respcache.Entry, state respcache.State, steps []transform.Step) {
+	if entry.CacheControl != "" {
+		c.Header("Cache-Control", entry.CacheControl)
+	}
+	c.Header("Age", strconv.Itoa(int(time.Since(entry.Stored).Seconds())))
+	c.Header("X-Cache", state.String())
+	if notModified(c, entry.Body) {
+		return
+	}
+
+	doc, err := transform.Run(entry.Body, steps)
+	if err != nil {
+		apierror.Internal.Respond(c, "Invalid cached data")
+		return
+	}
+	writeDoc(c, doc)
+}
+
+// revalidate refetches a stale cache entry. It is background work, so it
+// gives way when the data service budget is low and the stale copy stays.
+func (s *Server) revalidate(route, class, key string, ttl time.Duration) {
+	ctx := budget.WithPriority(context.Background(), budget.Background)
+	resp, err := s.fetch(ctx, route, class, key)
+	if err != nil {
+		log.Printf("cache refresh %s: %v", key, err)
+		return
+	}
+	if resp.status != http.StatusOK {
+		log.Printf("cache refresh %s: status %d", key, resp.status)
+		return
+	}
+	// The stale copy is kept in place of a suspect one
+	switch {
+	case resp.anomalous:
+		s.quarantine(ctx, route, key, resp.body, anomalyUndersized)
+		return
+	case !jsoncodec.Default.Valid(resp.body):
+		s.quarantine(ctx, route, key, resp.body, "invalid JSON")
+		return
+	case dataServiceError(resp.body):
+		return
+	}
+	s.cacheResponse(ctx, route, key, resp.body, resp.cacheControl, ttl)
+}
+
+// checkSize records a payload size and logs it if it is anomalous.
+func (s *Server) checkSize(route, path string, n int) bool {
+	anomalous := s.sizes.Observe(route, n)
+	if anomalous {
+		log.Printf("size anomaly: %s returned %d bytes, far below its usual size", path, n)
+	}
+	return anomalous
+}
+
+func replaceBody(resp *http.Response, body []byte) {
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	resp.Header.Set("Content-Type", "application/json")
+	resp.Header.Del("Content-Encoding")
+}
+
+// countingBody reports how many bytes were read once the body is closed.
+type countingBody struct {
+	io.ReadCloser
+	n    int
+	done func(n int)
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += n
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	if b.done != nil {
+		b.done(b.n)
+		b.done = nil
+	}
+	return b.ReadCloser.Close()
+}
+
+// serveDemo answers from the embedded sample season.
+func serveDemo(c *gin.Context, steps []transform.Step) {
+	body, ok := demo.Lookup(c.Request.URL.Path)
+	if !ok {
+		apierror.DemoUnavailable.Respond(c, "Not available in demo mode")
+		return
+	}
+	doc, err := transform.Run(body, steps)
+	if err != nil {
+		apierror.Internal.Respond(c, "Invalid demo data")
+		return
+	}
+	writeDoc(c, doc)
+}
+
+// upstreamBody returns the data service's response for route, a proxy route
+// whose parameters the request shares, for handlers that combine several
+// responses. Like a proxied request it is answered from the response cache
+// when it can be, and a miss is cached for both. It responds with an error
+// and returns false when the data can't be had.
+func (s *Server) upstreamBody(c *gin.Context, route string) ([]byte, bool) {
+	ctx := c.Request.Context()
+	key := upstreamPath(route, c.Params)
+	ttl := s.cfg.CacheTTLs[route]
+
+	if s.cfg.Demo {
+		body, ok := demo.Lookup(key)
+		if !ok {
+			apierror.DemoUnavailable.Respond(c, "Not available in demo mode")
+		}
+		return body, ok
+	}
+	key = upstreamPath(route, s.keyParams(c))
+	if entry, state := s.cache.Get(ctx, key); state != respcache.Miss {
+		if state == respcache.Stale {
+			s.cache.Revalidate(key, func() { s.revalidate(route, requestClass(route), key, ttl) })
+		}
+		return entry.Body, true
+	}
+	settled := ttl > 0 && s.settled(c)
+	if settled {
+		if entry, ok := s.fromArchive(ctx, route, key, ttl); ok {
+			return entry.Body, true
+		}
+	}
+	resp, err := s.fetch(ctx, route, requestClass(route), key)
+	if err != nil {
+		upstreamFailed(c, err)
+		return nil, false
+	}
+	if resp.status != http.StatusOK {
+		c.JSON(resp.status, apierror.UpstreamError.Body(c, "Data service returned error"))
+		return nil, false
+	}
+	if resp.anomalous {
+		s.quarantine(ctx, route, key, resp.body, anomalyUndersized)
+		if s.cfg.QuarantineMode == QuarantineHide {
+			apierror.UpstreamSuspect.Respond(c, "Data service returned a response that looks truncated")
+			return nil, false
+		}
+	}
+	if ttl > 0 && !resp.anomalous && !dataServiceError(resp.body) {
+		s.cache.Fetched(key, len(resp.body), resp.elapsed)
+		s.cacheResponse(ctx, route, key, resp.body, resp.cacheControl, ttl)
+		if settled {
+			s.toArchive(key, resp.body)
+		}
+	}
+	return resp.body, true
+}
+
+// proxyClearCache clears the gateway's response cache and archive, and the
+// FastF1 cache in every region. With a single
+// region the data service's response is passed through as is.
+func (s *Server) proxyClearCache(c *gin.Context, upstreamPath string) {
+	if s.cfg.Demo {
+		c.JSON(http.StatusOK, gin.H{"message": "Demo mode has no cache to clear"})
+		return
+	}
+
+	s.cache.Purge(c.Request.Context())
+	if s.archive != nil {
+		if err := s.archive.Clear(); err != nil {
+			log.Printf("clear archive: %v", err)
+		}
+	}
+	regions := s.regions.Regions()
+	if len(regions) == 1 {
+		status, body, err := s.clearCache(c.Request.Context(), regions[0].URL+upstreamPath)
+		if err != nil {
+			apierror.UpstreamUnreachable.Respond(c, fmt.Sprintf("Failed to reach data service: %v", err))
+			return
+		}
+		c.Data(status, "application/json", body)
+		return
+	}
+
+	results := gin.H{}
+	failed := 0
+	for _, region := range regions {
+		status, _, err := s.clearCache(c.Request.Context(), region.URL+upstreamPath)
+		if err != nil || status != http.StatusOK {
+			failed++
+		}
+		results[region.Name] = status
+	}
+	if failed > 0 {
+		c.JSON(http.StatusBadGateway, apierror.UpstreamError.Body(c, fmt.Sprintf("Cache clear failed in %d of %d regions", failed, len(regions))))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Cache cleared in %d regions", len(regions)), "regions": results})
+}
+
+func (s *Server) clearCache(ctx context.Context, targetURL string) (int, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, targetURL, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.direct.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
@@ -0,0 +1,75 @@
+package server_test
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ekjyotshinh/f1-server/demo"
+	"github.com/ekjyotshinh/f1-server/ratelimit"
+	"github.com/ekjyotshinh/f1-server/server"
+	"github.com/gin-gonic/gin"
+)
+
+// gateway serves the demo season through a fake data service, with the
+// limits that would otherwise stop a benchmark lifted and the background
+// jobs, which would call Jolpica, off.
+func gateway(b *testing.B) *server.Server {
+	gin.SetMode(gin.ReleaseMode)
+	log.SetOutput(io.Discard)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := demo.Lookup(r.URL.Path)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	b.Cleanup(upstream.Close)
+
+	cfg := server.DefaultConfig()
+	cfg.PythonServiceURL = upstream.URL
+	cfg.HistoryURL = upstream.URL
+	cfg.NativeSchedule = false
+	cfg.StreaksRefresh, cfg.RatingsRefresh, cfg.WarmInterval, cfg.ScheduleCheck, cfg.ProbeInterval = 0, 0, 0, 0, 0
+	cfg.DataServiceBudget = 0
+	cfg.HistoryBudget = 0
+	for group := range cfg.RateLimits {
+		cfg.RateLimits[group] = ratelimit.Limit{Rate: 1e9, Burst: 1e9}
+	}
+	s, err := server.New(cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { s.Close() })
+	return s
+}
+
+// BenchmarkProxy requests race results through the gateway, after the first
+// request has cached them: the path nearly every request takes on a race
+// weekend.
+func BenchmarkProxy(b *testing.B) {
+	for _, bc := range []struct{ name, path string }{
+		{"cached", "/api/race/2021/22"},
+		{"sorted", "/api/race/2021/22?sort=grid&order=desc"},
+		{"analytics", "/api/analytics/2021/22"},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			s := gateway(b)
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					rec := httptest.NewRecorder()
+					s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, bc.path, nil))
+					if rec.Code != http.StatusOK {
+						b.Errorf("%s: status %d: %s", bc.path, rec.Code, rec.Body)
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/ekjyotshinh/f1-server/jsoncodec"
+	"github.com/ekjyotshinh/f1-server/respcache"
+	"github.com/gin-gonic/gin"
+)
+
+// QuarantineWarn and QuarantineHide are the values of Config.QuarantineMode.
+const (
+	QuarantineWarn = "warn"
+	QuarantineHide = "hide"
+)
+
+// anomalyUndersized is the X-Data-Anomaly of a response far smaller than its
+// route's usual size, and the reason it is quarantined.
+const anomalyUndersized = "undersized"
+
+// maxQuarantined bounds the responses held; the oldest go first.
+const maxQuarantined = 100
+
+// quarantineIndex is where the list of held responses is kept. Their bodies
+// are kept under their cache keys, which all start with "/".
+const quarantineIndex = "index"
+
+// quarantined is a suspect response held back from the cache.
+type quarantined struct {
+	Key    string    `json:"key"`
+	Route  string    `json:"route"`
+	Reason string    `json:"reason"`
+	Size   int       `json:"size"`
+	Since  time.Time `json:"since"`
+}
+
+// quarantine holds body, route's response for key, as suspect for reason.
+// As with bans, two replicas quarantining at the same moment can lose one
+// from the list, though its body is still held.
+func (s *Server) quarantine(ctx context.Context, route, key string, body []byte, reason string) {
+	now := time.Now()
+	s.held.Save(ctx, key, respcache.Entry{Body: body, Stored: now}, s.cfg.QuarantineKeep)
+	s.updateQuarantine(ctx, func(list []quarantined) []quarantined {
+		list = slices.DeleteFunc(list, func(q quarantined) bool { return q.Key == key })
+		return append(list, quarantined{Key: key, Route: route, Reason: reason, Size: len(body), Since: now})
+	})
+	log.Printf("quarantine: %s held as %s (%d bytes)", key, reason, len(body))
+}
+
+// quarantineList returns the responses held, oldest first.
+func (s *Server) quarantineList(ctx context.Context) []quarantined {
+	entry, ok := s.held.Load(ctx, quarantineIndex)
+	if !ok {
+		return nil
+	}
+	var list []quarantined
+	if err := jsoncodec.Default.Unmarshal(entry.Body, &list); err != nil {
+		return nil
+	}
+	cutoff := time.Now().Add(-s.cfg.QuarantineKeep)
+	return slices.DeleteFunc(list, func(q quarantined) bool { return q.Since.Before(cutoff) })
+}
+
+// updateQuarantine applies change to the list of responses held, dropping
+// the oldest beyond maxQuarantined.
+func (s *Server) updateQuarantine(ctx context.Context, change func([]quarantined) []quarantined) {
+	list := change(s.quarantineList(ctx))
+	if extra := len(list) - maxQuarantined; extra > 0 {
+		for _, q := range list[:extra] {
+			s.held.Delete(ctx, q.Key)
+		}
+		list = list[extra:]
+	}
+	body, err := jsoncodec.Default.Marshal(list)
+	if err != nil {
+		return
+	}
+	s.held.Save(ctx, quarantineIndex, respcache.Entry{Body: body, Stored: time.Now()}, s.cfg.QuarantineKeep)
+}
+
+// release takes key out of quarantine, returning what was held.
+func (s *Server) release(ctx context.Context, key string) (quarantined, []byte, bool) {
+	list := s.quarantineList(ctx)
+	i := slices.IndexFunc(list, func(q quarantined) bool { return q.Key == key })
+	if i < 0 {
+		return quarantined{}, nil, false
+	}
+	held := list[i]
+	entry, ok := s.held.Load(ctx, key)
+	s.held.Delete(ctx, key)
+	s.updateQuarantine(ctx, func(list []quarantined) []quarantined {
+		return slices.DeleteFunc(list, func(q quarantined) bool { return q.Key == key })
+	})
+	return held, entry.Body, ok
+}
+
+// routeOf is the proxy route key is a cache key of, or "" if none.
+func routeOf(key string) string {
+	path, _, _ := strings.Cut(key, "?")
+	segments := strings.Split(path, "/")
+routes:
+	for _, pr := range proxyRoutes {
+		pattern := strings.Split(pr.route, "/")
+		if len(pattern) != len(segments) {
+			continue
+		}
+		for i, seg := range pattern {
+			if !strings.HasPrefix(seg, ":") && seg != segments[i] {
+				continue routes
+			}
+		}
+		return pr.route
+	}
+	return ""
+}
+
+// viewQuarantine lists the responses held or, given ?key=, answers with one
+// of them as it came from the data service.
+func (s *Server) viewQuarantine(c *gin.Context) {
+	ctx := c.Request.Context()
+	key := c.Query("key")
+	list := s.quarantineList(ctx)
+	if key == "" {
+		c.JSON(http.StatusOK, gin.H{"mode": s.cfg.QuarantineMode, "quarantined": append([]quarantined{}, list...)})
+		return
+	}
+	i := slices.IndexFunc(list, func(q quarantined) bool { return q.Key == key })
+	entry, ok := s.held.Load(ctx, key)
+	if i < 0 || !ok {
+		apierror.NotQuarantined.Respond(c, "Nothing held for this key")
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Data-Anomaly", list[i].Reason)
+	c.Data(http.StatusOK, "application/json", entry.Body)
+}
+
+// quarantineCached moves a cached response into quarantine, given
+// {"key": ..., "reason": ...}, for data found wrong after it was cached.
+// What was built from it is dropped, to be fetched again.
+func (s *Server) quarantineCached(c *gin.Context) {
+	var body struct {
+		Key    string `json:"key"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Key == "" {
+		apierror.InvalidParameter.Respond(c, `body must be {"key": "/api/...", "reason": "..."}`)
+		return
+	}
+	if body.Reason == "" {
+		body.Reason = "flagged by " + c.GetString(ctxAdmin)
+	}
+	ctx := c.Request.Context()
+	entry, ok := s.cache.Peek(ctx, body.Key)
+	if !ok {
+		apierror.NotCached.Respond(c, "Nothing cached for this key")
+		return
+	}
+	s.quarantine(ctx, routeOf(body.Key), body.Key, entry.Body, body.Reason)
+	s.cache.Delete(ctx, body.Key)
+	if s.archive != nil {
+		if err := s.archive.Delete(body.Key); err != nil {
+			log.Printf("archive delete %s: %v", body.Key, err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Quarantined", "dropped": s.invalidate(ctx, body.Key)})
+}
+
+// restoreQuarantined puts a held response back in the cache, given
+// {"key": ...}, once it has been found sound.
+func (s *Server) restoreQuarantined(c *gin.Context) {
+	var body struct {
+		Key string `json:"key"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Key == "" {
+		apierror.InvalidParameter.Respond(c, `body must be {"key": "/api/..."}`)
+		return
+	}
+	ctx := c.Request.Context()
+	held, raw, ok := s.release(ctx, body.Key)
+	if !ok {
+		apierror.NotQuarantined.Respond(c, "Nothing held for this key")
+		return
+	}
+	ttl := s.cfg.CacheTTLs[held.Route]
+	if ttl > 0 {
+		s.cacheResponse(ctx, held.Route, body.Key, raw, "", ttl)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Restored", "cached": ttl > 0})
+}
+
+// purgeQuarantine deletes the held response for ?key=, or every one.
+func (s *Server) purgeQuarantine(c *gin.Context) {
+	ctx := c.Request.Context()
+	if key := c.Query("key"); key != "" {
+		if _, _, ok := s.release(ctx, key); !ok {
+			apierror.NotQuarantined.Respond(c, "Nothing held for this key")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Purged"})
+		return
+	}
+	list := s.quarantineList(ctx)
+	for _, q := range list {
+		s.held.Delete(ctx, q.Key)
+	}
+	s.held.Delete(ctx, quarantineIndex)
+	c.JSON(http.StatusOK, gin.H{"message": "Quarantine purged", "purged": len(list)})
+}
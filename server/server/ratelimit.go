@@ -0,0 +1,34 @@
+package server
+
+import (
+	"github.com/ekjyotshinh/f1-server/ratelimit"
+	"github.com/ekjyotshinh/f1-server/signing"
+	"github.com/gin-gonic/gin"
+)
+
+// rateGroup names the RateLimits group a request counts against: "signed"
+// for verified signed clients, otherwise by its matched route and tier.
+func rateGroup(c *gin.Context) string {
+	if c.GetString(signing.ClientKey) != "" {
+		return "signed"
+	}
+	route := c.FullPath()
+	for _, pr := range proxyRoutes {
+		if pr.route == route {
+			return requestClass(route)
+		}
+	}
+	if c.GetString(ctxTier) == tierWidgets {
+		return "widgets"
+	}
+	return ratelimit.DefaultGroup
+}
+
+// rateClient tells clients apart for rate limiting: signed clients by their
+// id, wherever they call from, and everyone else by IP.
+func rateClient(c *gin.Context) string {
+	if id := c.GetString(signing.ClientKey); id != "" {
+		return "client:" + id
+	}
+	return c.ClientIP()
+}
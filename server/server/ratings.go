@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/gin-gonic/gin"
+)
+
+// ratingsKey is where the replica refreshing the ratings shares them.
+const ratingsKey = "jobs:driver-ratings"
+
+// driverRatings lists current Elo ratings, e.g. ?limit=10&active_since=2024.
+func (s *Server) driverRatings(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 0 {
+		apierror.InvalidParameter.Respond(c, "limit must be a non-negative number")
+		return
+	}
+	since, err := strconv.Atoi(c.DefaultQuery("active_since", "0"))
+	if err != nil {
+		apierror.InvalidParameter.Respond(c, "active_since must be a season")
+		return
+	}
+	if !s.ensureRatings(c) {
+		return
+	}
+
+	season, round := s.ratings.LastRace()
+	c.JSON(http.StatusOK, gin.H{
+		"updated_at": s.ratings.UpdatedAt(),
+		"season":     season,
+		"round":      round,
+		"drivers":    s.ratings.Top(limit, since),
+	})
+}
+
+// driverRatingTimeline returns one driver's rating after each of their races.
+func (s *Server) driverRatingTimeline(c *gin.Context) {
+	if !s.ensureRatings(c) {
+		return
+	}
+	rating, timeline, ok := s.ratings.Timeline(c.Param("driver_id"))
+	if !ok {
+		apierror.UnknownDriver.Respond(c, "No rating for driver")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"driver": rating, "timeline": timeline})
+}
+
+// ensureRatings computes ratings on demand if the refresh job hasn't run yet.
+func (s *Server) ensureRatings(c *gin.Context) bool {
+	if season, _ := s.ratings.LastRace(); season > 0 {
+		return true
+	}
+	if err := s.refreshRatings(c.Request.Context()); err != nil {
+		c.Error(err)
+		apierror.HistoryUnavailable.Respond(c, "Failed to reach historical data provider")
+		return false
+	}
+	return true
+}
+
+// refreshRatings applies races since the last one rated, starting from
+// cfg.RatingsFrom on an empty engine.
+func (s *Server) refreshRatings(ctx context.Context) error {
+	from, _ := s.ratings.LastRace()
+	from = max(from, s.cfg.RatingsFrom)
+	for year := from; year <= time.Now().Year(); year++ {
+		races, err := s.history.SeasonResults(ctx, year)
+		if err != nil {
+			return err
+		}
+		if _, err := s.ratings.Apply(races); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shareRatings brings the ratings up to date and shares them with the other
+// replicas.
+func (s *Server) shareRatings(ctx context.Context) error {
+	if err := s.refreshRatings(ctx); err != nil {
+		return err
+	}
+	body, err := s.ratings.Export()
+	if err != nil {
+		return err
+	}
+	s.share(ctx, ratingsKey, body)
+	return nil
+}
+
+// followRatings takes the ratings another replica shared, if they are
+// further along.
+func (s *Server) followRatings(ctx context.Context) error {
+	entry, ok := s.state.Load(ctx, ratingsKey)
+	if !ok {
+		return nil
+	}
+	return s.ratings.Import(entry.Body)
+}
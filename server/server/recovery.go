@@ -0,0 +1,110 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/ekjyotshinh/f1-server/errreport"
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDPattern accepts IDs from a load balancer or client that are safe
+// to echo back and log.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// requestID tags every request with an ID, taken from X-Request-Id when the
+// caller sent a usable one, and returns it in the same header. Calls to the
+// data service pass it on.
+func requestID(c *gin.Context) {
+	id := c.GetHeader("X-Request-Id")
+	if !requestIDPattern.MatchString(id) {
+		buf := make([]byte, 8)
+		rand.Read(buf)
+		id = hex.EncodeToString(buf)
+	}
+	c.Set(apierror.RequestIDKey, id)
+	c.Request = c.Request.WithContext(withRequestID(c.Request.Context(), id))
+	c.Header("X-Request-Id", id)
+	c.Next()
+}
+
+// recovery turns a panicking handler into a logged, reported 500 with the
+// usual error body. http.ErrAbortHandler is left to net/http, which uses it
+// to drop a connection on purpose.
+func (s *Server) recovery(c *gin.Context) {
+	w := c.Writer
+	defer func() {
+		value := recover()
+		if value == nil {
+			return
+		}
+		if err, ok := value.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+			panic(value)
+		}
+
+		id := c.GetString(apierror.RequestIDKey)
+		log.Printf("panic serving %s %s (request %s): %v\n%s", c.Request.Method, c.Request.URL.Path, id, value, debug.Stack())
+		s.reporter.Panic(c.Request, id, value)
+
+		// Drop anything a buffering middleware held back, and don't send an
+		// error body if the handler had already started its response
+		c.Writer = w
+		if c.Writer.Written() {
+			c.Abort()
+			return
+		}
+		c.Writer.Header().Del("Content-Encoding")
+		apierror.Internal.Abort(c, "Internal server error")
+	}()
+	c.Next()
+}
+
+// Context keys for what the proxy learned about a request, attached to
+// failure reports and request logs.
+const (
+	ctxUpstreamStatus  = "upstream_status"
+	ctxUpstreamLatency = "upstream_latency"
+	ctxCacheState      = "cache_state"
+)
+
+// reportFailures sends requests that ended in a server error to the error
+// tracker, with the error codes and causes recorded during the request.
+func (s *Server) reportFailures(c *gin.Context) {
+	c.Next()
+	status := c.Writer.Status()
+	if status < http.StatusInternalServerError || s.reporter == nil {
+		return
+	}
+
+	f := errreport.Failure{
+		Request:   c.Request,
+		RequestID: c.GetString(apierror.RequestIDKey),
+		Route:     c.FullPath(),
+		Status:    status,
+		Tags:      map[string]string{},
+	}
+	for _, err := range c.Errors {
+		var apiErr *apierror.Error
+		if errors.As(err.Err, &apiErr) {
+			f.Code, f.Message = apiErr.Code.Code, apiErr.Message
+		} else {
+			f.Causes = append(f.Causes, err.Error())
+		}
+	}
+	if f.Code == "" {
+		f.Code, f.Message = apierror.Internal.Code, http.StatusText(status)
+	}
+	for _, key := range []string{ctxUpstreamStatus, ctxCacheState} {
+		if v, ok := c.Get(key); ok {
+			f.Tags[key] = fmt.Sprint(v)
+		}
+	}
+	s.reporter.Failure(f)
+}
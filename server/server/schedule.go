@@ -0,0 +1,127 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/jolpica"
+	"github.com/ekjyotshinh/f1-server/jsoncodec"
+	"github.com/ekjyotshinh/f1-server/respcache"
+	"github.com/gin-gonic/gin"
+)
+
+// firstDataSeason is the first season the data service has FastF1 timing
+// for, and so the first in /api/years.
+const firstDataSeason = 2018
+
+// nativeRoutes answer data service routes in the gateway itself, from the
+// historical data provider, when cfg.NativeSchedule is set. Calendars are
+// static metadata, so there is no reason to wait on FastF1 for them. Each
+// is given the route's proxy handler for when the provider fails.
+var nativeRoutes = map[string]func(s *Server, proxied gin.HandlerFunc) gin.HandlerFunc{
+	"/api/years":          (*Server).nativeYears,
+	"/api/schedule/:year": (*Server).nativeSchedule,
+}
+
+// builtSchedule is an encoded /api/schedule response. Those of past seasons
+// never expire.
+type builtSchedule struct {
+	body    []byte
+	expires time.Time
+}
+
+// scheduleTTL is how long the current and future seasons' calendars are
+// kept before they are rebuilt, to pick up date changes.
+const scheduleTTL = 6 * time.Hour
+
+// nativeYears lists the seasons from firstDataSeason to this one.
+func (s *Server) nativeYears(proxied gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.cfg.Demo {
+			proxied(c)
+			return
+		}
+		years := make([]int, 0, time.Now().Year()-firstDataSeason+1)
+		for y := firstDataSeason; y <= time.Now().Year(); y++ {
+			years = append(years, y)
+		}
+		c.Header("Cache-Control", "public, max-age=3600")
+		c.JSON(http.StatusOK, years)
+	}
+}
+
+// nativeSchedule serves a season's calendar in the data service's shape,
+// built once from the historical data provider's and kept encoded.
+func (s *Server) nativeSchedule(proxied gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		year, err := strconv.Atoi(c.Param("year"))
+		if s.cfg.Demo || err != nil || year < firstSeason {
+			proxied(c)
+			return
+		}
+
+		s.schedulesMu.Lock()
+		built, ok := s.schedules[year]
+		s.schedulesMu.Unlock()
+		state := respcache.Hit
+		if !ok || (!built.expires.IsZero() && time.Now().After(built.expires)) {
+			state = respcache.Miss
+			schedule, err := s.history.Schedule(c.Request.Context(), year)
+			if err == nil {
+				built.body, err = jsoncodec.Default.Marshal(scheduleEvents(schedule))
+			}
+			if err != nil || len(schedule) == 0 {
+				log.Printf("native schedule %d: %v, asking the data service", year, err)
+				proxied(c)
+				return
+			}
+			built.expires = time.Time{}
+			if year >= time.Now().Year() {
+				built.expires = time.Now().Add(scheduleTTL)
+			}
+			s.schedulesMu.Lock()
+			s.schedules[year] = built
+			s.schedulesMu.Unlock()
+		}
+
+		if built.expires.IsZero() {
+			c.Header("Cache-Control", "public, max-age=86400, immutable")
+		} else {
+			c.Header("Cache-Control", "public, max-age=3600")
+		}
+		c.Header("X-Cache", state.String())
+		if notModified(c, built.body) {
+			return
+		}
+		c.Data(http.StatusOK, "application/json", built.body)
+	}
+}
+
+// scheduledEvent is an entry of /api/schedule. Jolpica has no official
+// event names, so OfficialEventName repeats the race name.
+type scheduledEvent struct {
+	RoundNumber       int    `json:"RoundNumber"`
+	Country           string `json:"Country"`
+	Location          string `json:"Location"`
+	OfficialEventName string `json:"OfficialEventName"`
+	EventDate         string `json:"EventDate"`
+	EventName         string `json:"EventName"`
+}
+
+// scheduleEvents converts a Jolpica calendar to /api/schedule entries.
+func scheduleEvents(schedule []jolpica.ScheduledRace) []scheduledEvent {
+	events := make([]scheduledEvent, 0, len(schedule))
+	for _, race := range schedule {
+		events = append(events, scheduledEvent{
+			RoundNumber:       race.RoundInt(),
+			Country:           race.Circuit.Location.Country,
+			Location:          race.Circuit.Location.Locality,
+			OfficialEventName: race.RaceName,
+			EventDate:         race.Date + "T00:00:00",
+			EventName:         race.RaceName,
+		})
+	}
+	return events
+}
@@ -0,0 +1,927 @@
+// Package server builds the F1 dashboard API as an http.Handler so it can be
+// run by the standalone binary, embedded in other Go programs, or exercised
+// with httptest without binding a port.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/abuse"
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/ekjyotshinh/f1-server/budget"
+	"github.com/ekjyotshinh/f1-server/errreport"
+	"github.com/ekjyotshinh/f1-server/jobs"
+	"github.com/ekjyotshinh/f1-server/jolpica"
+	"github.com/ekjyotshinh/f1-server/latency"
+	"github.com/ekjyotshinh/f1-server/ratelimit"
+	"github.com/ekjyotshinh/f1-server/ratings"
+	"github.com/ekjyotshinh/f1-server/respcache"
+	"github.com/ekjyotshinh/f1-server/sampler"
+	"github.com/ekjyotshinh/f1-server/sheets"
+	"github.com/ekjyotshinh/f1-server/signing"
+	"github.com/ekjyotshinh/f1-server/sizes"
+	"github.com/ekjyotshinh/f1-server/slo"
+	"github.com/ekjyotshinh/f1-server/upstream"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/singleflight"
+)
+
+// Config controls how the API is built.
+type Config struct {
+	// PythonServiceURL is the base URL of the FastF1 data service.
+	PythonServiceURL string
+	// Regions, when set, replace PythonServiceURL with several deployments of
+	// the data service. Each request class ("data" or "telemetry") goes to
+	// the fastest healthy region unless RegionPins names one for it. Health
+	// is probed every RegionProbeInterval.
+	Regions             []upstream.Region
+	RegionPins          map[string]string
+	RegionProbeInterval time.Duration
+
+	// Upstream connections to the data service are dropped after
+	// UpstreamMaxConnAge or UpstreamMaxFailures failures in a row, and when
+	// a DNS check every UpstreamDNSRefresh finds the service has moved.
+	UpstreamMaxConnAge  time.Duration
+	UpstreamMaxFailures int
+	UpstreamDNSRefresh  time.Duration
+	// Idle connections kept per data service host, and how long dialing
+	// one may take.
+	UpstreamMaxIdlePerHost int
+	UpstreamDialTimeout    time.Duration
+	// UpstreamTimeout bounds a proxied request; UpstreamRouteTimeouts
+	// overrides it per gateway route, e.g. "/api/years".
+	UpstreamTimeout       time.Duration
+	UpstreamRouteTimeouts map[string]time.Duration
+	// UpstreamRetry retries proxied requests that failed transiently.
+	UpstreamRetry upstream.RetryPolicy
+	// UpstreamBreaker stops calling a data service region that keeps
+	// failing, so requests fail fast instead of waiting on it.
+	UpstreamBreaker upstream.BreakerPolicy
+	// UpstreamConcurrency caps the data service calls in flight per gateway
+	// route, queueing the rest, so parallel telemetry loads can't overwhelm
+	// FastF1. It is separate from the per-client rate limits.
+	UpstreamConcurrency upstream.ConcurrencyPolicy
+	// UpstreamFallback answers schedule and race results requests the data
+	// service fails from the historical data provider instead.
+	UpstreamFallback bool
+	// NativeSchedule serves /api/years and /api/schedule/:year from the
+	// historical data provider without asking the data service.
+	NativeSchedule bool
+
+	// UpstreamSocket, when set, is a Unix socket every data service
+	// connection dials instead of PythonServiceURL's host, which then only
+	// names the service in requests. For a sidecar on the same host.
+	UpstreamSocket string
+
+	// UpstreamClientCert and UpstreamClientKey are the PEM certificate the
+	// gateway presents to a data service that requires mutual TLS.
+	// UpstreamCA, when set, is the CA bundle the service's certificate must
+	// chain to instead of the system roots.
+	UpstreamClientCert string
+	UpstreamClientKey  string
+	UpstreamCA         string
+	// OutboundProxy sends upstream requests (data service and history) through
+	// an egress proxy. When empty, HTTPS_PROXY, HTTP_PROXY and NO_PROXY apply.
+	OutboundProxy string
+
+	// HistoryURL is the Ergast-compatible API used for multi-season history.
+	HistoryURL string
+	// CORS is the browser access policy for each group of routes.
+	CORS CORSTiers
+
+	// SLO is the default objective; SLOOverrides are keyed by gin route pattern.
+	SLO          slo.Objective
+	SLOOverrides map[string]slo.Objective
+
+	// LatencyLog persists upstream latency samples when set.
+	LatencyLog       string
+	LatencyRetention time.Duration
+
+	// StaticDir serves a built dashboard (client/dist) from disk; StaticFS takes
+	// precedence and lets embedding programs supply an embed.FS instead.
+	// StaticBase is the path the dashboard was built for (Vite's base option).
+	StaticDir  string
+	StaticFS   fs.FS
+	StaticBase string
+
+	// Abuse bans clients that scan for routes or ignore rate limits.
+	Abuse abuse.Policy
+
+	// RateLimits are per-client request rates by route group: "data" and
+	// "telemetry" for the data service routes, "widgets" for the public
+	// history and stats routes, and "default" for everything else. Clients
+	// with a verified signature count against "signed" wherever they call,
+	// per client id rather than IP.
+	RateLimits map[string]ratelimit.Limit
+	// TrustedProxies are the proxy addresses or CIDRs whose X-Forwarded-For
+	// is believed when identifying clients; with none, a client is the
+	// address it connects from. TrustedPlatform, when set, names the header
+	// the hosting platform's edge puts the client IP in, such as X-Real-IP
+	// on Railway, and takes precedence.
+	TrustedProxies  []string
+	TrustedPlatform string
+
+	// SheetsCredentials is a Google service account key file used to export
+	// tables to the spreadsheet SheetsSpreadsheetID. Export is off without it.
+	SheetsCredentials   string
+	SheetsSpreadsheetID string
+
+	// AdminKeys are the API keys accepted on admin routes, keyed by the name
+	// recorded in the audit log. With none, admin routes reject every request.
+	AdminKeys map[string]string
+
+	// SigningSecrets are the HMAC secrets of server-to-server clients, keyed
+	// by client id. Signed timestamps must be within SigningSkew.
+	SigningSecrets map[string]string
+	SigningSkew    time.Duration
+
+	// DataServiceBudget and HistoryBudget are the upstream calls allowed per
+	// hour (0 for unlimited). BudgetReserve is the fraction kept for user
+	// requests: background refreshes are deferred once only that is left.
+	DataServiceBudget int
+	HistoryBudget     int
+	BudgetReserve     float64
+
+	// SizeAnomalyRatio flags upstream responses smaller than this fraction of
+	// their route's median size.
+	SizeAnomalyRatio float64
+
+	// QuarantineMode is how a suspect response, such as an undersized one,
+	// is answered: QuarantineWarn serves it with X-Data-Anomaly and
+	// QuarantineHide not at all. Either way it isn't cached but held for
+	// QuarantineKeep, for admins to inspect, restore or purge.
+	QuarantineMode string
+	QuarantineKeep time.Duration
+
+	// CacheTTLs are how long responses of each data service route, keyed by
+	// gin route pattern, are served from memory; routes without one aren't
+	// cached. Expired entries are served for up to CacheMaxStale more while
+	// they are refreshed in the background. CacheSize bounds the entries
+	// held in memory; CacheRedisURL, when set, keeps them in Redis instead
+	// so that replicas share one cache.
+	CacheTTLs     map[string]time.Duration
+	CacheMaxStale time.Duration
+	CacheSize     int
+	CacheRedisURL string
+
+	// CacheDir, when set, keeps responses for races that are over in files
+	// there for good, so they survive restarts ("" disables it). Once they
+	// pass CacheDirMaxMB (0 for no limit), the least recently used go.
+	CacheDir      string
+	CacheDirMaxMB int
+
+	// ComputedCacheSize bounds the responses kept of endpoints computed from
+	// data service data, such as /api/compare (0 disables it). Each is
+	// recomputed once the data it came from changes.
+	ComputedCacheSize int
+
+	// CompressMinSize is the smallest response worth compressing with
+	// Brotli or gzip (0 disables compression).
+	CompressMinSize int
+
+	// Redactions lists fields, as transform.Redact paths, removed from every
+	// response of a data service route keyed by gin route pattern. A
+	// streamed route with redactions is buffered instead.
+	Redactions map[string][]string
+
+	// Deprecations marks routes, keyed by gin route pattern, as deprecated.
+	Deprecations map[string]Deprecation
+
+	// SamplesPerRoute is how many request/response pairs the debugging
+	// sampler keeps for each route once enabled from the admin API.
+	SamplesPerRoute int
+
+	// ProbeRoutes are requested every ProbeInterval by the self-probe job;
+	// the results are published at /api/status.
+	ProbeRoutes   []string
+	ProbeInterval time.Duration
+
+	// Headers are the security headers sent with every response.
+	Headers SecurityHeaders
+
+	// SecurityContact is published in /.well-known/security.txt when set.
+	SecurityContact string
+
+	// SentryDSN, when set, reports panics and server errors to Sentry or a
+	// compatible error tracker, tagged with SentryEnvironment.
+	SentryDSN         string
+	SentryEnvironment string
+
+	// Demo serves the embedded sample season instead of calling the data service.
+	Demo bool
+
+	// StreaksRefresh is how often constructor streaks are recomputed in the
+	// background (0 computes them on demand only). Points-finish streaks cover
+	// seasons from StreaksPointsFrom.
+	StreaksRefresh    time.Duration
+	StreaksPointsFrom int
+
+	// RatingsFile persists driver Elo ratings when set. Ratings start from
+	// season RatingsFrom and are brought up to date every RatingsRefresh.
+	RatingsFile    string
+	RatingsFrom    int
+	RatingsRefresh time.Duration
+
+	// WarmInterval is how often the most recent and upcoming race weekends
+	// are checked for sessions that have ended, whose data is then loaded
+	// into the response cache ahead of visitors (0 disables it).
+	WarmInterval time.Duration
+
+	// ScheduleCheck is how often this season's calendar is compared with
+	// the one last seen, dropping the cached data of races whose entry
+	// changed (0 disables it).
+	ScheduleCheck time.Duration
+
+	// Stateless disables everything that writes to local disk, for short-lived
+	// read-only environments such as AWS Lambda, and keeps responses only in
+	// Redis: an instance's own memory is gone before it pays off, so
+	// CacheRedisURL is required.
+	Stateless bool
+}
+
+// DefaultConfig returns the production configuration.
+func DefaultConfig() Config {
+	telemetrySLO := slo.Objective{Latency: 5 * time.Minute}
+	dashboardOrigins := []string{"https://ekjyotshinh.github.io", "http://localhost:3000", "http://localhost:5173"}
+	return Config{
+		PythonServiceURL: "https://python-data-service-production.up.railway.app",
+		HistoryURL:       jolpica.DefaultBaseURL,
+		CORS: CORSTiers{
+			Frontend: CORSTier{
+				Origins:     dashboardOrigins,
+				Methods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"},
+				Credentials: true,
+			},
+			Widgets: CORSTier{
+				Origins: []string{"*"},
+				Methods: []string{"GET", "HEAD"},
+			},
+			// The admin page ships with the dashboard
+			Admin: CORSTier{
+				Origins:     dashboardOrigins,
+				Methods:     []string{"GET", "POST", "PUT", "DELETE"},
+				Credentials: true,
+			},
+		},
+		SLO: slo.Objective{Target: 0.99, Latency: 10 * time.Second},
+		// Telemetry loads are slow by design, so they get a looser latency target
+		SLOOverrides: map[string]slo.Objective{
+			"/api/telemetry/:year/:race_name":                  telemetrySLO,
+			"/api/telemetry/:year/:race_name/chunk/:chunk_num": telemetrySLO,
+		},
+		LatencyRetention: 30 * 24 * time.Hour,
+		StaticBase:       "/",
+		Abuse: abuse.Policy{
+			Window:   time.Minute,
+			NotFound: 30,
+			TooMany:  20,
+			Ban:      15 * time.Minute,
+		},
+		// A race page makes a handful of calls; replay fetches its chunks in turn
+		RateLimits: map[string]ratelimit.Limit{
+			"data":                 {Rate: 2, Burst: 20},
+			"telemetry":            {Rate: 1, Burst: 30},
+			"widgets":              {Rate: 5, Burst: 50},
+			"signed":               {Rate: 20, Burst: 100},
+			ratelimit.DefaultGroup: {Rate: 5, Burst: 30},
+		},
+		SigningSkew:       5 * time.Minute,
+		DataServiceBudget: 1000,
+		HistoryBudget:     500, // Jolpica's sustained limit
+		BudgetReserve:     0.2,
+		SizeAnomalyRatio:  0.25,
+		QuarantineMode:    QuarantineWarn,
+		QuarantineKeep:    7 * 24 * time.Hour,
+		// Past seasons don't change; the current one does after each session
+		CacheTTLs: map[string]time.Duration{
+			"/api/schedule/:year":                6 * time.Hour,
+			"/api/race/:year/:race_name":         time.Hour,
+			"/api/qualifying/:year/:race_name":   time.Hour,
+			"/api/sprint/:year/:race_name":       time.Hour,
+			"/api/analytics/:year/:race_name":    time.Hour,
+			"/api/laps/:year/:race_name/:driver": time.Hour,
+			"/api/weather/:year/:race_name":      time.Hour,
+		},
+		CacheMaxStale:       24 * time.Hour,
+		CacheDirMaxMB:       1024,
+		CompressMinSize:     1024,
+		CacheSize:           500,
+		ComputedCacheSize:   200,
+		SamplesPerRoute:     20,
+		RegionProbeInterval: time.Minute,
+		UpstreamMaxConnAge:  10 * time.Minute,
+		UpstreamMaxFailures: 3,
+		UpstreamDNSRefresh:  time.Minute,
+		// Go's default of 2 idle connections per host churns sockets under load
+		UpstreamMaxIdlePerHost: 32,
+		UpstreamDialTimeout:    5 * time.Second,
+		// FastF1 loads a session from scratch on a cache miss, and chunked
+		// telemetry takes minutes
+		UpstreamTimeout: 10 * time.Minute,
+		UpstreamRetry: upstream.RetryPolicy{
+			Attempts:   3,
+			Backoff:    250 * time.Millisecond,
+			MaxBackoff: 2 * time.Second,
+			Jitter:     0.5,
+		},
+		UpstreamBreaker:  upstream.BreakerPolicy{Failures: 5, Cooldown: 30 * time.Second},
+		UpstreamFallback: true,
+		NativeSchedule:   true,
+		UpstreamConcurrency: upstream.ConcurrencyPolicy{
+			Limits: map[string]int{
+				"/api/telemetry/:year/:race_name":                  2,
+				"/api/telemetry/:year/:race_name/chunk/:chunk_num": 4,
+				"/api/analytics/:year/:race_name":                  4,
+			},
+			QueueSize:    16,
+			QueueTimeout: 30 * time.Second,
+		},
+		// A long-finished race the data service should already have cached
+		ProbeRoutes:   []string{"/api/years", "/api/schedule/2024", "/api/race/2024/1"},
+		ProbeInterval: 5 * time.Minute,
+		Headers: SecurityHeaders{
+			HSTSMaxAge:     365 * 24 * time.Hour,
+			ReferrerPolicy: "strict-origin-when-cross-origin",
+			ContentSecurityPolicy: "default-src 'self'; img-src 'self' data:; style-src 'self' 'unsafe-inline'; " +
+				"connect-src 'self'; object-src 'none'; base-uri 'self'; frame-ancestors 'none'",
+		},
+		SecurityContact:   "https://github.com/ekjyotshinh/F1/security/advisories/new",
+		StreaksRefresh:    24 * time.Hour,
+		StreaksPointsFrom: 2010,
+		RatingsFrom:       1950,
+		RatingsRefresh:    6 * time.Hour,
+		WarmInterval:      10 * time.Minute,
+		ScheduleCheck:     10 * time.Minute,
+	}
+}
+
+// Server is the API handler together with the state its routes share.
+type Server struct {
+	cfg          Config
+	engine       *gin.Engine
+	reporter     *errreport.Reporter
+	slo          *slo.Tracker
+	latency      *latency.Recorder
+	sizes        *sizes.Tracker
+	cache        *respcache.Cache
+	state        respcache.Backend // what jobs keep apart from the cache, so a purge leaves it
+	held         respcache.Backend // quarantined responses, also apart from the cache
+	archive      *respcache.Disk   // nil unless CacheDir is set
+	flight       singleflight.Group
+	flightsMu    sync.Mutex
+	flights      map[string]*flightCall // callers of each shared call in s.flight
+	history      *jolpica.Client
+	regions      *upstream.Router
+	transport    *upstream.Transport
+	breaker      *upstream.Breaker
+	concurrency  *upstream.Limiter
+	client       *http.Client // shared by proxied requests: retries and the circuit breaker
+	direct       *http.Client // shared by probes and admin calls, which fail on the first error
+	abuse        *abuse.Detector
+	limiter      *ratelimit.Limiter
+	metrics      *metrics
+	sheets       *sheets.Client
+	signing      *signing.Verifier
+	deprecations *deprecations
+	sampler      *sampler.Sampler
+	probes       prober
+
+	dataBudget    *budget.Budget
+	historyBudget *budget.Budget
+
+	championsMu   sync.Mutex
+	championsDone map[int]seasonChampions // completed seasons, never refetched
+	championsLive map[int]seasonChampions
+
+	memo *memo // computed race analyses
+
+	onThisDayMu    sync.Mutex
+	onThisDayCache map[string]onThisDayEntry // by MM-DD, recomputed daily
+
+	streaksMu sync.Mutex
+	streaks   *streaksReport
+
+	oddsMu sync.Mutex
+	odds   map[string]*probabilityReport // keyed by year/round/simulations
+
+	summariesMu sync.Mutex
+	summaries   map[string]*seasonSummary // keyed by year/round
+
+	schedulesMu sync.Mutex
+	schedules   map[int]builtSchedule
+
+	ratings *ratings.Engine
+
+	jobs *jobs.Scheduler
+}
+
+// New builds the API handler from cfg.
+func New(cfg Config) (*Server, error) {
+	if cfg.Stateless {
+		if cfg.CacheRedisURL == "" {
+			return nil, errors.New("stateless mode needs REDIS_URL for the response cache")
+		}
+		cfg.ComputedCacheSize = 0
+		cfg.LatencyLog = ""
+		cfg.RatingsFile = ""
+		cfg.CacheDir = ""
+	}
+	if cfg.StaticFS == nil && cfg.StaticDir != "" {
+		cfg.StaticFS = os.DirFS(cfg.StaticDir)
+	}
+	if cfg.StaticBase == "" {
+		cfg.StaticBase = "/"
+	}
+
+	regions := cfg.Regions
+	if len(regions) == 0 {
+		regions = []upstream.Region{{Name: "default", URL: cfg.PythonServiceURL}}
+	}
+
+	proxy, err := upstream.ProxyFunc(cfg.OutboundProxy)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := upstream.TLSConfig(cfg.UpstreamClientCert, cfg.UpstreamClientKey, cfg.UpstreamCA)
+	if err != nil {
+		return nil, fmt.Errorf("upstream TLS: %w", err)
+	}
+	for route := range cfg.UpstreamRouteTimeouts {
+		if !isProxyRoute(route) {
+			return nil, fmt.Errorf("upstream timeouts: %s is not a proxied route", route)
+		}
+	}
+	for route := range cfg.UpstreamConcurrency.Limits {
+		if !isProxyRoute(route) {
+			return nil, fmt.Errorf("upstream concurrency: %s is not a proxied route", route)
+		}
+	}
+	for route := range cfg.Redactions {
+		if !isProxyRoute(route) {
+			return nil, fmt.Errorf("redactions: %s is not a proxied route", route)
+		}
+	}
+	dataTransport := http.DefaultTransport.(*http.Transport).Clone()
+	dataTransport.Proxy = proxy
+	dataTransport.DialContext = (&net.Dialer{Timeout: cfg.UpstreamDialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	dataTransport.TLSHandshakeTimeout = cfg.UpstreamDialTimeout
+	dataTransport.MaxIdleConnsPerHost = cfg.UpstreamMaxIdlePerHost
+	dataTransport.MaxIdleConns = max(dataTransport.MaxIdleConns, cfg.UpstreamMaxIdlePerHost*len(regions))
+	if tlsConfig != nil {
+		dataTransport.TLSClientConfig = tlsConfig
+	}
+	if cfg.UpstreamSocket != "" {
+		dataTransport.Proxy = nil
+		dataTransport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			d := net.Dialer{Timeout: cfg.UpstreamDialTimeout}
+			return d.DialContext(ctx, "unix", cfg.UpstreamSocket)
+		}
+	}
+
+	var store respcache.Backend
+	if cfg.CacheRedisURL != "" {
+		if store, err = respcache.Redis(cfg.CacheRedisURL); err != nil {
+			return nil, fmt.Errorf("response cache: %w", err)
+		}
+	} else {
+		store = respcache.Memory(cfg.CacheSize)
+	}
+	// Replicas sharing Redis take turns at the jobs that fill it
+	locker, _ := store.(jobs.Locker)
+	// and count requests against the SLOs together
+	counters, _ := store.(slo.Counters)
+	var archive *respcache.Disk
+	if cfg.CacheDir != "" {
+		if archive, err = respcache.OpenDisk(cfg.CacheDir); err != nil {
+			return nil, fmt.Errorf("response archive: %w", err)
+		}
+	}
+	reporter, err := errreport.New(cfg.SentryDSN, cfg.SentryEnvironment)
+	if err != nil {
+		return nil, fmt.Errorf("error reporting: %w", err)
+	}
+	rec, err := latency.NewRecorder(cfg.LatencyLog, cfg.LatencyRetention)
+	if err != nil {
+		return nil, fmt.Errorf("latency recorder: %w", err)
+	}
+	elo, err := ratings.Open(cfg.RatingsFile)
+	if err != nil {
+		return nil, fmt.Errorf("ratings: %w", err)
+	}
+
+	s := &Server{
+		cfg:          cfg,
+		engine:       gin.New(),
+		reporter:     reporter,
+		slo:          slo.NewTracker(cfg.SLO, cfg.SLOOverrides, counters),
+		latency:      rec,
+		sizes:        sizes.NewTracker(cfg.SizeAnomalyRatio),
+		cache:        respcache.New(store, cfg.CacheMaxStale),
+		state:        respcache.Separate(store, "state"),
+		held:         respcache.Separate(store, "quarantine"),
+		archive:      archive,
+		regions:      upstream.NewRouter(regions, cfg.RegionPins),
+		transport:    upstream.NewTransport(cfg.UpstreamMaxConnAge, cfg.UpstreamMaxFailures, dataTransport),
+		abuse:        abuse.New(cfg.Abuse, respcache.Separate(store, "abuse")),
+		limiter:      ratelimit.New(cfg.RateLimits),
+		metrics:      newMetrics(),
+		signing:      signing.NewVerifier(cfg.SigningSecrets, cfg.SigningSkew),
+		deprecations: newDeprecations(cfg.Deprecations),
+		sampler:      sampler.New(cfg.SamplesPerRoute),
+		probes:       prober{results: make(map[string]*probeResult)},
+
+		dataBudget:    budget.New("data-service", cfg.DataServiceBudget, cfg.BudgetReserve),
+		historyBudget: budget.New("jolpica", cfg.HistoryBudget, cfg.BudgetReserve),
+
+		championsDone:  make(map[int]seasonChampions),
+		championsLive:  make(map[int]seasonChampions),
+		onThisDayCache: make(map[string]onThisDayEntry),
+		memo:           newMemo(cfg.ComputedCacheSize),
+		flights:        make(map[string]*flightCall),
+
+		odds:      make(map[string]*probabilityReport),
+		summaries: make(map[string]*seasonSummary),
+		schedules: make(map[int]builtSchedule),
+		ratings:   elo,
+
+		jobs: jobs.New(locker),
+	}
+	// Left alone, gin trusts every proxy, so anyone could pick their own IP
+	// past the rate limits and bans
+	if err := s.engine.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("trusted proxies: %w", err)
+	}
+	s.engine.TrustedPlatform = cfg.TrustedPlatform
+	s.metrics.registry.MustRegister(cacheLocks{s.cache})
+	s.breaker = upstream.NewBreaker(cfg.UpstreamBreaker, cfg.UpstreamRetry.Wrap(s.transport))
+	s.client = &http.Client{Transport: s.breaker}
+	s.concurrency = upstream.NewLimiter(cfg.UpstreamConcurrency)
+	s.direct = &http.Client{Transport: s.transport}
+	s.history = jolpica.New(cfg.HistoryURL)
+	historyTransport := http.DefaultTransport.(*http.Transport).Clone()
+	historyTransport.Proxy = proxy
+	s.history.HTTP.Transport = historyTransport
+	s.history.Budget = s.historyBudget
+	if cfg.SheetsCredentials != "" {
+		key, err := os.ReadFile(cfg.SheetsCredentials)
+		if err != nil {
+			return nil, fmt.Errorf("sheets credentials: %w", err)
+		}
+		if s.sheets, err = sheets.New(key, historyTransport); err != nil {
+			return nil, fmt.Errorf("sheets credentials: %w", err)
+		}
+	}
+	if len(cfg.AdminKeys) == 0 {
+		log.Print("admin: no admin keys configured; admin routes will reject every request")
+	}
+	s.routes()
+	for route := range cfg.Deprecations {
+		if !slices.ContainsFunc(s.engine.Routes(), func(r gin.RouteInfo) bool { return r.Path == route }) {
+			return nil, fmt.Errorf("deprecations: no route %s", route)
+		}
+	}
+	for route := range cfg.CacheTTLs {
+		if !slices.ContainsFunc(proxyRoutes, func(pr proxyRoute) bool { return pr.route == route }) {
+			return nil, fmt.Errorf("cache ttls: no data service route %s", route)
+		}
+	}
+	s.startJobs()
+	return s, nil
+}
+
+// abuseSweep is how often expired strikes and bans are dropped and bans
+// taken from the shared store, i.e. how long a ban takes to reach the other
+// replicas.
+const abuseSweep = 10 * time.Second
+
+// sloFlush is how often SLO counts are added to the shared store.
+const sloFlush = 30 * time.Second
+
+// latencyCompact is how often the latency log is cut back to
+// LatencyRetention.
+const latencyCompact = 6 * time.Hour
+
+// archivePrune is how often the archive is cut back to CacheDirMaxMB.
+const archivePrune = time.Hour
+
+// startJobs schedules background work. Demo mode runs offline, so it has
+// nothing to refresh, but still sweeps the abuse detector.
+func (s *Server) startJobs() {
+	s.jobs.Every("abuse-sweep", abuseSweep, s.abuse.Sweep)
+	s.jobs.Every("slo-flush", sloFlush, s.slo.Flush)
+	s.jobs.Every("latency-compact", latencyCompact, func(context.Context) error {
+		return s.latency.Compact()
+	})
+	if s.archive != nil && s.cfg.CacheDirMaxMB > 0 {
+		s.jobs.Every("archive-prune", archivePrune, func(context.Context) error {
+			return s.archive.Prune(int64(s.cfg.CacheDirMaxMB) << 20)
+		})
+	}
+	if s.cfg.Demo {
+		return
+	}
+	s.jobs.Shared("constructor-streaks", s.cfg.StreaksRefresh, background(s.shareStreaks), s.followStreaks)
+	s.jobs.Shared("driver-ratings", s.cfg.RatingsRefresh, background(s.shareRatings), s.followRatings)
+	s.jobs.Shared("cache-warm", s.cfg.WarmInterval, background(s.warmCache), nil)
+	s.jobs.Shared("schedule-check", s.cfg.ScheduleCheck, background(s.checkSchedule), nil)
+	s.jobs.Every("self-probe", s.cfg.ProbeInterval, s.probe)
+	if len(s.cfg.Regions) > 1 {
+		s.jobs.Every("region-probe", s.cfg.RegionProbeInterval, func(ctx context.Context) error {
+			return s.regions.Probe(ctx, s.direct, "/api/years", 10*time.Second)
+		})
+	}
+	// A socket has no DNS to watch
+	dnsRefresh := s.cfg.UpstreamDNSRefresh
+	if s.cfg.UpstreamSocket != "" {
+		dnsRefresh = 0
+	}
+	s.jobs.Every("upstream-dns", dnsRefresh, func(ctx context.Context) error {
+		var urls []string
+		for _, region := range s.regions.Regions() {
+			urls = append(urls, region.URL)
+		}
+		return s.transport.CheckDNS(ctx, urls)
+	})
+}
+
+// sharedKeep is how long results one replica computes for the others are
+// kept, long past their next refresh.
+const sharedKeep = 7 * 24 * time.Hour
+
+// share stores a job's result under key for the other replicas, apart from
+// the cache so a purge doesn't take it.
+func (s *Server) share(ctx context.Context, key string, body []byte) {
+	s.state.Save(ctx, key, respcache.Entry{Body: body, Stored: time.Now()}, sharedKeep)
+}
+
+// background marks a job's upstream calls as deferrable.
+func background(fn func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return fn(budget.WithPriority(ctx, budget.Background))
+	}
+}
+
+// Close stops background jobs and releases resources held by the server.
+func (s *Server) Close() error {
+	s.jobs.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.slo.Flush(ctx); err != nil {
+		log.Printf("slo flush: %v", err)
+	}
+	s.reporter.Flush(2 * time.Second)
+	return s.latency.Close()
+}
+
+// requireHistory rejects multi-season history routes in demo mode, which has
+// no network access to the historical data provider.
+func (s *Server) requireHistory(c *gin.Context) {
+	if s.cfg.Demo {
+		apierror.DemoUnavailable.Abort(c, "Not available in demo mode")
+		return
+	}
+	c.Next()
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.engine.ServeHTTP(w, r)
+}
+
+func (s *Server) routes() {
+	r := s.engine
+
+	// Every request gets an ID first, so logs, reports and error bodies agree
+	r.Use(requestID, logRequests, s.metrics.middleware, s.recovery, s.reportFailures)
+
+	// Outside everything that shapes the body
+	r.Use(compress(s.cfg.CompressMinSize))
+
+	// The rest depends on the route's tier, CORS policy first
+	t := &tiers{
+		engine: r,
+		chain: []gin.HandlerFunc{
+			s.corsMiddleware(),
+			s.cfg.Headers.middleware(),
+			s.abuse.Middleware(),
+			// Before the limiter, which gives signed clients their own group
+			s.signing.Middleware(),
+			s.limiter.Middleware(rateGroup, rateClient),
+			s.deprecations.middleware,
+			s.sampler.Middleware(),
+			s.slo.Middleware(),
+			negotiate,
+		},
+		preflight: make(map[string]bool),
+	}
+	frontend := t.group(tierFrontend, "")
+
+	// Serve the dashboard itself when configured, otherwise just identify the API
+	if s.cfg.StaticFS != nil {
+		t.noRoute(tierFrontend, staticHandler(s.cfg.StaticFS, s.cfg.StaticBase))
+	} else {
+		t.noRoute(tierFrontend)
+		frontend.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "F1 Dashboard API (Go/Gin)")
+		})
+	}
+	s.wellKnownRoutes(frontend)
+
+	// Data service endpoints, see proxyRoutes
+	for _, pr := range proxyRoutes {
+		h := s.proxy(pr)
+		if native, ok := nativeRoutes[pr.route]; ok && s.cfg.NativeSchedule {
+			h = native(s, h)
+		}
+		frontend.GET(pr.route, h)
+	}
+
+	// Several drivers' races side by side, from the data service's race data
+	frontend.GET("/api/compare/:year/:race_name", s.compareDrivers)
+	// Every driver's stints and stops, with undercuts and overcuts
+	frontend.GET("/api/pitstops/:year/:race_name", s.pitStops)
+
+	// Read-only widget routes, embeddable from any site
+	widgets := t.group(tierWidgets, "/api", jsonp)
+
+	// Catalog of machine-readable error codes
+	widgets.GET("/errors", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"errors": apierror.Catalog()})
+	})
+
+	// Team lineage across rebrands (Racing Point -> Aston Martin, ...)
+	widgets.GET("/constructors/lineage", constructorLineage)
+
+	// Drivers' birthdays, from the reference metadata
+	widgets.GET("/birthdays", birthdays)
+
+	// Multi-season driver history from the historical data provider
+	history := widgets.Group("", s.requireHistory)
+	history.GET("/drivers/transfers", s.driverTransfers)
+	history.GET("/drivers/:year/rookies", s.driverRookies)
+	history.GET("/ratings/drivers", s.driverRatings)
+	history.GET("/ratings/drivers/:driver_id", s.driverRatingTimeline)
+	history.GET("/champions", s.champions)
+	history.GET("/standings/drivers/:year", s.driverStandings)
+	history.GET("/standings/constructors/:year", s.constructorStandings)
+	history.GET("/on-this-day", s.onThisDay)
+	history.GET("/stats/constructor-streaks", s.constructorStreaks)
+	history.GET("/championship/:year/probabilities", s.championshipProbabilities)
+	history.GET("/season/:year/summary", s.seasonSummaryHandler)
+
+	// Prometheus metrics
+	frontend.GET("/metrics", s.metrics.handler())
+
+	// Liveness and readiness probes for the orchestrator
+	frontend.GET("/healthz", healthz)
+	frontend.GET("/readyz", s.readyz)
+
+	// Self-probe results
+	frontend.GET("/api/status", s.status)
+
+	// Which sessions of a season clash with an uploaded ICS calendar
+	frontend.POST("/api/calendar/:year/conflicts", s.requireHistory, s.calendarConflicts)
+
+	// Admin endpoints need an admin API key, sent in a header
+	admin := t.group(tierAdmin, "", s.requireAdmin, csrfProtect)
+
+	// Admin endpoint - clear cache
+	admin.POST("/api/clear-cache", func(c *gin.Context) {
+		s.proxyClearCache(c, "/api/clear-cache")
+	})
+
+	// Admin endpoint - push a results or standings table to Google Sheets
+	admin.POST("/api/admin/export/sheets", s.requireHistory, s.exportSheets)
+
+	// Admin endpoint - SLO and error budget report
+	admin.GET("/api/admin/slo", func(c *gin.Context) {
+		c.JSON(http.StatusOK, s.slo.Report(c.Request.Context()))
+	})
+
+	// Admin endpoints - view and lift temporary bans
+	admin.GET("/api/admin/bans", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"bans": s.abuse.Bans()})
+	})
+	admin.DELETE("/api/admin/bans", func(c *gin.Context) {
+		s.abuse.Clear(c.Request.Context(), "")
+		c.JSON(http.StatusOK, gin.H{"message": "All bans cleared"})
+	})
+	admin.DELETE("/api/admin/bans/:ip", func(c *gin.Context) {
+		if !s.abuse.Clear(c.Request.Context(), c.Param("ip")) {
+			apierror.NotBanned.Respond(c, "IP is not banned")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Ban cleared"})
+	})
+
+	// Admin endpoint - upstream call budgets for the rolling hour
+	admin.GET("/api/admin/budget", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"budgets": []budget.Status{s.dataBudget.Status(), s.historyBudget.Status()}})
+	})
+
+	// Admin endpoint - upstream payload sizes and undersized responses
+	admin.GET("/api/admin/sizes", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"routes": s.sizes.Report()})
+	})
+
+	// Admin endpoint - who is still calling deprecated routes
+	admin.GET("/api/admin/deprecations", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"usage": s.deprecations.report()})
+	})
+
+	// Admin endpoints - debugging sampler, e.g. ?route=/api/race
+	admin.GET("/api/admin/samples", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"enabled": s.sampler.Enabled(),
+			"samples": s.sampler.Samples(c.Query("route")),
+		})
+	})
+	admin.PUT("/api/admin/samples", func(c *gin.Context) {
+		var body struct {
+			Enabled *bool `json:"enabled"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil || body.Enabled == nil {
+			apierror.InvalidParameter.Respond(c, `body must be {"enabled": true|false}`)
+			return
+		}
+		s.sampler.Enable(*body.Enabled)
+		c.JSON(http.StatusOK, gin.H{"enabled": *body.Enabled})
+	})
+	admin.DELETE("/api/admin/samples", func(c *gin.Context) {
+		s.sampler.Clear()
+		c.JSON(http.StatusOK, gin.H{"message": "Samples cleared"})
+	})
+
+	// Admin endpoint - data service regions and how requests are routed
+	admin.GET("/api/admin/regions", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"regions":     s.regions.Status(),
+			"breakers":    s.breaker.Status(),
+			"concurrency": s.concurrency.Status(),
+		})
+	})
+
+	// Admin endpoint - upstream latency percentiles, e.g. ?route=/api/race&window=7d
+	admin.GET("/api/admin/latency", func(c *gin.Context) {
+		window, err := latency.ParseDuration(c.DefaultQuery("window", "24h"))
+		if err != nil {
+			apierror.InvalidParameter.Respond(c, err.Error())
+			return
+		}
+		bucket := time.Hour
+		if window > 48*time.Hour {
+			bucket = 24 * time.Hour
+		}
+		if b := c.Query("bucket"); b != "" {
+			if bucket, err = latency.ParseDuration(b); err != nil {
+				apierror.InvalidParameter.Respond(c, err.Error())
+				return
+			}
+		}
+		if bucket < latency.MinBucket || window/bucket > latency.MaxBuckets {
+			apierror.InvalidParameter.Respond(c, fmt.Sprintf("bucket must be at least %v and split window into at most %d buckets", latency.MinBucket, latency.MaxBuckets))
+			return
+		}
+		c.JSON(http.StatusOK, s.latency.Report(c.Query("route"), window, bucket))
+	})
+
+	// Admin endpoint - drop cached responses by tag, e.g. ?tag=2025/3
+	admin.DELETE("/api/admin/cache", s.invalidateTags)
+
+	// Admin endpoints - suspect responses held for inspection: list them or
+	// view one (?key=), quarantine a cached one, put one back in the cache,
+	// and purge one (?key=) or all
+	admin.GET("/api/admin/quarantine", s.viewQuarantine)
+	admin.POST("/api/admin/quarantine", s.quarantineCached)
+	admin.POST("/api/admin/quarantine/restore", s.restoreQuarantined)
+	admin.DELETE("/api/admin/quarantine", s.purgeQuarantine)
+
+	// Admin endpoint - what the response cache saves and which entries earn
+	// their space, e.g. ?window=7d&top=20
+	admin.GET("/api/admin/cache/report", func(c *gin.Context) {
+		window, err := latency.ParseDuration(c.DefaultQuery("window", "24h"))
+		if err != nil || window <= 0 || window > 7*24*time.Hour {
+			apierror.InvalidParameter.Respond(c, "window must be a duration of at most 7d")
+			return
+		}
+		top, err := strconv.Atoi(c.DefaultQuery("top", "10"))
+		if err != nil || top < 1 || top > 100 {
+			apierror.InvalidParameter.Respond(c, "top must be a number between 1 and 100")
+			return
+		}
+		report := s.cache.Report(window, top)
+		report.Window = latency.FormatDuration(window)
+		c.JSON(http.StatusOK, report)
+	})
+}
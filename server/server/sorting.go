@@ -0,0 +1,69 @@
+package server
+
+import "github.com/ekjyotshinh/f1-server/transform"
+
+// resultsSort handles ?sort= on the results list of /api/race and
+// /api/sprint.
+var resultsSort = transform.Sort{
+	Field: "results",
+	Keys: map[string]transform.Key{
+		"position": transform.Field("Position"),
+		"grid":     transform.Field("GridPosition"),
+		"driver":   transform.Field("Abbreviation"),
+		"team":     transform.Field("TeamName"),
+	},
+	Tiebreak: []string{"position", "grid", "driver"},
+}
+
+// qualifyingSort handles ?sort= on the results list of /api/qualifying.
+// Drivers knocked out before a part have no time in it and sort last.
+var qualifyingSort = transform.Sort{
+	Field: "results",
+	Keys: map[string]transform.Key{
+		"position": transform.Field("Position"),
+		"q1":       transform.Field("Q1"),
+		"q2":       transform.Field("Q2"),
+		"q3":       transform.Field("Q3"),
+		"driver":   transform.Field("Abbreviation"),
+		"team":     transform.Field("TeamName"),
+	},
+	Tiebreak: []string{"position", "driver"},
+}
+
+// standingsSort handles ?sort= on the standings list of the drivers' and
+// constructors' championships.
+var standingsSort = transform.Sort{
+	Field: "standings",
+	Keys: map[string]transform.Key{
+		"position": transform.Field("position"),
+		"points":   transform.Field("points"),
+		"wins":     transform.Field("wins"),
+	},
+	Tiebreak: []string{"position"},
+}
+
+// lapsSort handles ?sort= on the laps list of /api/laps. Lap times are
+// FastF1's fixed-width strings, so they sort as text; laps without one
+// come last. "lap_time" is kept as another name for "laptime".
+var lapsSort = transform.Sort{
+	Field: "laps",
+	Keys: map[string]transform.Key{
+		"lap":      transform.Field("LapNumber"),
+		"laptime":  transform.Field("LapTime"),
+		"lap_time": transform.Field("LapTime"),
+	},
+	Tiebreak: []string{"lap"},
+}
+
+// lapFields are the per-lap fields of /api/laps that ?fields= can select.
+var lapFields = []string{
+	"LapNumber", "LapTime", "Sector1Time", "Sector2Time", "Sector3Time",
+	"Compound", "TyreLife", "Stint", "Position", "PitIn", "PitOut",
+}
+
+// lapsPage and lapsFields page and trim the laps list of /api/laps; a whole
+// race of laps is more than a chart needs at once.
+var (
+	lapsPage   = transform.Page{Field: "laps", DefaultLimit: 20, MaxLimit: 100}
+	lapsFields = transform.Fields{Field: "laps", Allowed: lapFields}
+)
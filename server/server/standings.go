@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/ekjyotshinh/f1-server/jolpica"
+	"github.com/ekjyotshinh/f1-server/transform"
+	"github.com/gin-gonic/gin"
+)
+
+// roundPoints is what a driver or team scored in one round, sprint included,
+// and their running total after it.
+type roundPoints struct {
+	Round  int     `json:"round"`
+	Race   string  `json:"race"`
+	Points float64 `json:"points"`
+	Total  float64 `json:"total"`
+}
+
+type driverStandingRow struct {
+	Position    int           `json:"position"`
+	DriverID    string        `json:"driver_id"`
+	Code        string        `json:"code"`
+	Name        string        `json:"name"`
+	Nationality string        `json:"nationality"`
+	Team        string        `json:"team"`
+	Points      float64       `json:"points"`
+	Wins        int           `json:"wins"`
+	Progression []roundPoints `json:"progression"`
+}
+
+type constructorStandingRow struct {
+	Position      int           `json:"position"`
+	ConstructorID string        `json:"constructor_id"`
+	Name          string        `json:"name"`
+	Nationality   string        `json:"nationality"`
+	Points        float64       `json:"points"`
+	Wins          int           `json:"wins"`
+	Progression   []roundPoints `json:"progression"`
+}
+
+// driverStandings returns a season's drivers' championship with each
+// driver's points round by round.
+func (s *Server) driverStandings(c *gin.Context) {
+	year, ok := standingsYear(c)
+	if !ok {
+		return
+	}
+	steps, err := transform.Parse(c.Request.URL.Query(), standingsSort.Transform())
+	if err != nil {
+		apierror.InvalidParameter.Respond(c, err.Error())
+		return
+	}
+	ctx := c.Request.Context()
+	standings, err := s.history.DriverStandings(ctx, year)
+	if err != nil {
+		c.Error(err)
+		apierror.HistoryUnavailable.Respond(c, "Failed to reach historical data provider")
+		return
+	}
+	rounds, err := s.seasonRounds(ctx, year)
+	if err != nil {
+		c.Error(err)
+		apierror.HistoryUnavailable.Respond(c, "Failed to reach historical data provider")
+		return
+	}
+	progression := pointsProgression(rounds, func(r jolpica.Result) string { return r.Driver.DriverID })
+
+	rows := make([]driverStandingRow, 0, len(standings))
+	for _, st := range standings {
+		row := driverStandingRow{
+			Position:    st.PositionInt(),
+			DriverID:    st.Driver.DriverID,
+			Code:        st.Driver.Code,
+			Name:        st.Driver.Name(),
+			Nationality: st.Driver.Nationality,
+			Points:      st.PointsFloat(),
+			Wins:        atoi(st.Wins),
+			Progression: progression[st.Driver.DriverID],
+		}
+		if len(st.Constructors) > 0 {
+			row.Team = st.Constructors[len(st.Constructors)-1].Name
+		}
+		rows = append(rows, row)
+	}
+	writeValue(c, gin.H{"year": year, "rounds": len(rounds), "standings": rows}, steps)
+}
+
+// constructorStandings returns a season's constructors' championship with
+// each team's points round by round.
+func (s *Server) constructorStandings(c *gin.Context) {
+	year, ok := standingsYear(c)
+	if !ok {
+		return
+	}
+	steps, err := transform.Parse(c.Request.URL.Query(), standingsSort.Transform())
+	if err != nil {
+		apierror.InvalidParameter.Respond(c, err.Error())
+		return
+	}
+	ctx := c.Request.Context()
+	standings, err := s.history.ConstructorStandings(ctx, year)
+	if err != nil {
+		c.Error(err)
+		apierror.HistoryUnavailable.Respond(c, "Failed to reach historical data provider")
+		return
+	}
+	rounds, err := s.seasonRounds(ctx, year)
+	if err != nil {
+		c.Error(err)
+		apierror.HistoryUnavailable.Respond(c, "Failed to reach historical data provider")
+		return
+	}
+	progression := pointsProgression(rounds, func(r jolpica.Result) string { return r.Constructor.ConstructorID })
+
+	rows := make([]constructorStandingRow, 0, len(standings))
+	for _, st := range standings {
+		rows = append(rows, constructorStandingRow{
+			Position:      atoi(st.Position),
+			ConstructorID: st.Constructor.ConstructorID,
+			Name:          st.Constructor.Name,
+			Nationality:   st.Constructor.Nationality,
+			Points:        st.PointsFloat(),
+			Wins:          atoi(st.Wins),
+			Progression:   progression[st.Constructor.ConstructorID],
+		})
+	}
+	writeValue(c, gin.H{"year": year, "rounds": len(rounds), "standings": rows}, steps)
+}
+
+func standingsYear(c *gin.Context) (int, bool) {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil || year < firstSeason || year > time.Now().Year() {
+		apierror.InvalidParameter.Respond(c, "year must be a season between 1950 and this year")
+		return 0, false
+	}
+	return year, true
+}
+
+// seasonRounds returns the season's races run so far, each with its sprint
+// classification when it had one.
+func (s *Server) seasonRounds(ctx context.Context, year int) ([]jolpica.Race, error) {
+	races, err := s.history.SeasonResults(ctx, year)
+	if err != nil {
+		return nil, err
+	}
+	sprints, err := s.history.SeasonSprints(ctx, year)
+	if err != nil {
+		return nil, err
+	}
+	byRound := make(map[int][]jolpica.Result, len(sprints))
+	for _, sprint := range sprints {
+		byRound[sprint.RoundInt()] = sprint.SprintResults
+	}
+	for i := range races {
+		races[i].SprintResults = byRound[races[i].RoundInt()]
+	}
+	return races, nil
+}
+
+// pointsProgression adds up the points of each key (a driver or a team) over
+// the rounds it took part in. Totals follow the points scored, so they can
+// differ from the standings in seasons that dropped worst results.
+func pointsProgression(rounds []jolpica.Race, key func(jolpica.Result) string) map[string][]roundPoints {
+	totals := map[string]float64{}
+	out := map[string][]roundPoints{}
+	for _, race := range rounds {
+		scored := map[string]float64{}
+		for _, results := range [][]jolpica.Result{race.Results, race.SprintResults} {
+			for _, r := range results {
+				scored[key(r)] += r.PointsFloat()
+			}
+		}
+		for k, points := range scored {
+			totals[k] += points
+			out[k] = append(out[k], roundPoints{Round: race.RoundInt(), Race: race.RaceName, Points: points, Total: totals[k]})
+		}
+	}
+	return out
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/gin-gonic/gin"
+)
+
+// staticHandler serves the built dashboard from fsys under base, falling back
+// to index.html so client-side routes survive a page reload.
+func staticHandler(fsys fs.FS, base string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p := c.Request.URL.Path
+		if strings.HasPrefix(p, "/api/") || (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+			apierror.NotFound.Respond(c, "Not found")
+			return
+		}
+		if !strings.HasPrefix(p, base) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(p, base)), "/")
+		if name == "" || !serveFile(c, fsys, name) {
+			serveFile(c, fsys, "index.html")
+		}
+	}
+}
+
+// serveFile writes name from fsys with caching headers suited to a Vite build,
+// reporting false if it does not exist.
+func serveFile(c *gin.Context, fsys fs.FS, name string) bool {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(name, "assets/"):
+		// Vite fingerprints everything under assets/, so it never changes
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	case name == "index.html":
+		c.Header("Cache-Control", "no-cache")
+	default:
+		c.Header("Cache-Control", "public, max-age=3600")
+	}
+
+	if rs, ok := f.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, name, info.ModTime(), rs)
+		return true
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return false
+	}
+	c.Data(http.StatusOK, mimeType(name), data)
+	return true
+}
+
+func mimeType(name string) string {
+	switch path.Ext(name) {
+	case ".html":
+		return "text/html; charset=utf-8"
+	case ".js":
+		return "text/javascript; charset=utf-8"
+	case ".css":
+		return "text/css; charset=utf-8"
+	case ".svg":
+		return "image/svg+xml"
+	case ".json":
+		return "application/json"
+	}
+	return "application/octet-stream"
+}
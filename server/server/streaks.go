@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/ekjyotshinh/f1-server/jolpica"
+	"github.com/ekjyotshinh/f1-server/jsoncodec"
+	"github.com/gin-gonic/gin"
+)
+
+// maxStreaks caps each list in the streaks report.
+const maxStreaks = 20
+
+type raceRef struct {
+	Season int    `json:"season"`
+	Round  int    `json:"round"`
+	Race   string `json:"race"`
+}
+
+type constructorStreak struct {
+	ConstructorID string  `json:"constructor_id"`
+	Name          string  `json:"name"`
+	Length        int     `json:"length"`
+	Start         raceRef `json:"start"`
+	End           raceRef `json:"end"`
+	Active        bool    `json:"active"` // still running as of the latest race
+}
+
+type streaksReport struct {
+	UpdatedAt     time.Time           `json:"updated_at"`
+	PointsFrom    int                 `json:"points_from"`
+	WinStreaks    []constructorStreak `json:"win_streaks"`
+	PointsStreaks []constructorStreak `json:"points_streaks"`
+}
+
+// streaksKey is where the replica refreshing the report shares it.
+const streaksKey = "jobs:constructor-streaks"
+
+// constructorStreaks serves the latest report computed by the refresh job,
+// computing it on demand if the job hasn't produced one yet.
+func (s *Server) constructorStreaks(c *gin.Context) {
+	s.streaksMu.Lock()
+	report := s.streaks
+	s.streaksMu.Unlock()
+
+	if report == nil {
+		if err := s.coldStreaks(c.Request.Context()); err != nil {
+			c.Error(err)
+			apierror.HistoryUnavailable.Respond(c, "Failed to reach historical data provider")
+			return
+		}
+		s.streaksMu.Lock()
+		report = s.streaks
+		s.streaksMu.Unlock()
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// coldStreaks computes the report for requests arriving before the refresh
+// job has produced one. It takes about a hundred Jolpica calls, so requests
+// arriving together share one computation, which carries on for the others
+// if one of them goes away.
+func (s *Server) coldStreaks(ctx context.Context) error {
+	call := s.joinFlight(ctx, streaksKey)
+	defer s.leaveFlight(ctx, streaksKey, call)
+
+	ch := s.flight.DoChan(streaksKey, func() (any, error) {
+		return nil, s.refreshStreaks(call.ctx)
+	})
+	select {
+	case res := <-ch:
+		return res.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// refreshStreaks recomputes win streaks over all of history and points-finish
+// streaks since cfg.StreaksPointsFrom (full classifications are expensive to
+// page through, so that window is bounded).
+func (s *Server) refreshStreaks(ctx context.Context) error {
+	winners, err := s.history.Winners(ctx)
+	if err != nil {
+		return err
+	}
+
+	var results []jolpica.Race
+	for year := s.cfg.StreaksPointsFrom; year <= time.Now().Year(); year++ {
+		season, err := s.history.SeasonResults(ctx, year)
+		if err != nil {
+			return err
+		}
+		results = append(results, season...)
+	}
+
+	report := &streaksReport{
+		UpdatedAt:  time.Now().UTC(),
+		PointsFrom: s.cfg.StreaksPointsFrom,
+		WinStreaks: computeStreaks(winners, func(r jolpica.Result) bool {
+			return r.PositionInt() == 1
+		}),
+		PointsStreaks: computeStreaks(results, func(r jolpica.Result) bool {
+			return r.PointsFloat() > 0
+		}),
+	}
+
+	s.streaksMu.Lock()
+	s.streaks = report
+	s.streaksMu.Unlock()
+	return nil
+}
+
+// computeStreaks finds runs of consecutive races in which a constructor had
+// at least one car satisfying hit. Any race without a hit ends the streak.
+func computeStreaks(races []jolpica.Race, hit func(jolpica.Result) bool) []constructorStreak {
+	sort.SliceStable(races, func(i, j int) bool {
+		if races[i].SeasonInt() != races[j].SeasonInt() {
+			return races[i].SeasonInt() < races[j].SeasonInt()
+		}
+		return races[i].RoundInt() < races[j].RoundInt()
+	})
+
+	running := map[string]*constructorStreak{}
+	var finished []constructorStreak
+	end := func(id string) {
+		if st, ok := running[id]; ok {
+			finished = append(finished, *st)
+			delete(running, id)
+		}
+	}
+
+	for _, race := range races {
+		ref := raceRef{Season: race.SeasonInt(), Round: race.RoundInt(), Race: race.RaceName}
+		hits := map[string]jolpica.Constructor{}
+		for _, r := range race.Results {
+			if hit(r) {
+				hits[r.Constructor.ConstructorID] = r.Constructor
+			}
+		}
+
+		for id := range running {
+			if _, ok := hits[id]; !ok {
+				end(id)
+			}
+		}
+		for id, team := range hits {
+			st, ok := running[id]
+			if !ok {
+				st = &constructorStreak{ConstructorID: id, Name: team.Name, Start: ref}
+				running[id] = st
+			}
+			st.Length++
+			st.End = ref
+		}
+	}
+
+	for _, st := range running {
+		st.Active = true
+		finished = append(finished, *st)
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		a, b := finished[i], finished[j]
+		if a.Length != b.Length {
+			return a.Length > b.Length
+		}
+		if a.Start.Season != b.Start.Season {
+			return a.Start.Season < b.Start.Season
+		}
+		return a.ConstructorID < b.ConstructorID
+	})
+	if len(finished) > maxStreaks {
+		finished = finished[:maxStreaks]
+	}
+	return finished
+}
+
+// shareStreaks refreshes the report and shares it with the other replicas.
+func (s *Server) shareStreaks(ctx context.Context) error {
+	if err := s.refreshStreaks(ctx); err != nil {
+		return err
+	}
+	s.streaksMu.Lock()
+	report := s.streaks
+	s.streaksMu.Unlock()
+	body, err := jsoncodec.Default.Marshal(report)
+	if err != nil {
+		return err
+	}
+	s.share(ctx, streaksKey, body)
+	return nil
+}
+
+// followStreaks takes the report another replica shared, if it is newer.
+func (s *Server) followStreaks(ctx context.Context) error {
+	entry, ok := s.state.Load(ctx, streaksKey)
+	if !ok {
+		return nil
+	}
+	var report streaksReport
+	if err := jsoncodec.Default.Unmarshal(entry.Body, &report); err != nil {
+		return err
+	}
+	s.streaksMu.Lock()
+	defer s.streaksMu.Unlock()
+	if s.streaks == nil || report.UpdatedAt.After(s.streaks.UpdatedAt) {
+		s.streaks = &report
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/ekjyotshinh/f1-server/apierror"
+	"github.com/ekjyotshinh/f1-server/jolpica"
+	"github.com/gin-gonic/gin"
+)
+
+// seasonTally counts a driver's or team's results over a season. A DNF is
+// any start that wasn't classified as a finish, disqualifications included.
+type seasonTally struct {
+	Starts      int `json:"starts"`
+	Wins        int `json:"wins"`
+	Podiums     int `json:"podiums"`
+	Poles       int `json:"poles"`
+	FastestLaps int `json:"fastest_laps"`
+	DNFs        int `json:"dnfs"`
+}
+
+type driverSummary struct {
+	DriverID string `json:"driver_id"`
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Team     string `json:"team"` // the last one they drove for
+	seasonTally
+}
+
+type teamSummary struct {
+	ConstructorID string `json:"constructor_id"`
+	Name          string `json:"name"`
+	seasonTally
+}
+
+type seasonSummary struct {
+	Year       int             `json:"year"`
+	AfterRound int             `json:"after_round"`
+	Drivers    []driverSummary `json:"drivers"`
+	Teams      []teamSummary   `json:"teams"`
+}
+
+// seasonSummaryHandler tallies wins, podiums, poles, fastest laps and DNFs
+// per driver and team over a season's completed rounds, so the frontend
+// doesn't fetch every race itself. Summaries are cached until a new round
+// is completed.
+func (s *Server) seasonSummaryHandler(c *gin.Context) {
+	year, ok := standingsYear(c)
+	if !ok {
+		return
+	}
+	summary, err := s.seasonSummary(c.Request.Context(), year)
+	if err != nil {
+		c.Error(err)
+		apierror.HistoryUnavailable.Respond(c, "Failed to reach historical data provider")
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+func (s *Server) seasonSummary(ctx context.Context, year int) (*seasonSummary, error) {
+	races, err := s.history.SeasonResults(ctx, year)
+	if err != nil {
+		return nil, err
+	}
+	afterRound := 0
+	for _, race := range races {
+		afterRound = max(afterRound, race.RoundInt())
+	}
+
+	key := fmt.Sprintf("%d/%d", year, afterRound)
+	s.summariesMu.Lock()
+	cached, ok := s.summaries[key]
+	s.summariesMu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	qualifying, err := s.history.SeasonPoles(ctx, year)
+	if err != nil {
+		return nil, err
+	}
+	summary := summarizeSeason(year, afterRound, races, qualifying)
+
+	s.summariesMu.Lock()
+	s.summaries[key] = summary
+	s.summariesMu.Unlock()
+	return summary, nil
+}
+
+// summarizeSeason tallies races. Poles come from qualifying, or from the
+// grid for rounds Jolpica has no qualifying for.
+func summarizeSeason(year, afterRound int, races, qualifying []jolpica.Race) *seasonSummary {
+	poles := map[int]string{} // round -> driver ID
+	for _, q := range qualifying {
+		if len(q.QualifyingResults) > 0 {
+			poles[q.RoundInt()] = q.QualifyingResults[0].Driver.DriverID
+		}
+	}
+
+	drivers := map[string]*driverSummary{}
+	teams := map[string]*teamSummary{}
+	for _, race := range races {
+		pole, qualified := poles[race.RoundInt()]
+		for _, r := range race.Results {
+			d, ok := drivers[r.Driver.DriverID]
+			if !ok {
+				d = &driverSummary{DriverID: r.Driver.DriverID, Code: r.Driver.Code, Name: r.Driver.Name()}
+				drivers[r.Driver.DriverID] = d
+			}
+			d.Team = r.Constructor.Name
+			t, ok := teams[r.Constructor.ConstructorID]
+			if !ok {
+				t = &teamSummary{ConstructorID: r.Constructor.ConstructorID, Name: r.Constructor.Name}
+				teams[r.Constructor.ConstructorID] = t
+			}
+			d.count(r)
+			t.count(r)
+			if (qualified && r.Driver.DriverID == pole) || (!qualified && r.Grid == "1") {
+				d.Poles++
+				t.Poles++
+			}
+		}
+	}
+
+	summary := &seasonSummary{
+		Year:       year,
+		AfterRound: afterRound,
+		Drivers:    make([]driverSummary, 0, len(drivers)),
+		Teams:      make([]teamSummary, 0, len(teams)),
+	}
+	for _, d := range drivers {
+		summary.Drivers = append(summary.Drivers, *d)
+	}
+	for _, t := range teams {
+		summary.Teams = append(summary.Teams, *t)
+	}
+	sort.Slice(summary.Drivers, func(i, j int) bool {
+		a, b := summary.Drivers[i], summary.Drivers[j]
+		if c := a.compare(b.seasonTally); c != 0 {
+			return c < 0
+		}
+		return a.DriverID < b.DriverID
+	})
+	sort.Slice(summary.Teams, func(i, j int) bool {
+		a, b := summary.Teams[i], summary.Teams[j]
+		if c := a.compare(b.seasonTally); c != 0 {
+			return c < 0
+		}
+		return a.ConstructorID < b.ConstructorID
+	})
+	return summary
+}
+
+// count adds a race result; poles are counted separately.
+func (t *seasonTally) count(r jolpica.Result) {
+	t.Starts++
+	switch r.PositionInt() {
+	case 1:
+		t.Wins++
+		t.Podiums++
+	case 2, 3:
+		t.Podiums++
+	}
+	if r.FastestLapRank() == 1 {
+		t.FastestLaps++
+	}
+	if !r.Finished() {
+		t.DNFs++
+	}
+}
+
+// compare orders tallies by wins, then podiums, then poles, most first.
+func (t seasonTally) compare(o seasonTally) int {
+	return cmp.Or(cmp.Compare(o.Wins, t.Wins), cmp.Compare(o.Podiums, t.Podiums), cmp.Compare(o.Poles, t.Poles))
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/ekjyotshinh/f1-server/jolpica"
+	"github.com/ekjyotshinh/f1-server/respcache"
+	"github.com/gin-gonic/gin"
+)
+
+// sessionLength is how long after a session's start its data is expected
+// to be complete at the data service.
+const sessionLength = 2 * time.Hour
+
+// warmSessions are the routes, keyed by round number the way the dashboard
+// asks for them, filled in once each session of a weekend is over.
+var warmSessions = []struct {
+	session func(r jolpica.ScheduledRace) *jolpica.Session
+	routes  []string
+}{
+	{func(r jolpica.ScheduledRace) *jolpica.Session { return r.Qualifying }, []string{"/api/qualifying/:year/:race_name"}},
+	{func(r jolpica.ScheduledRace) *jolpica.Session { return r.Sprint }, []string{"/api/sprint/:year/:race_name"}},
+	{func(r jolpica.ScheduledRace) *jolpica.Session {
+		return &jolpica.Session{Date: r.Date, Time: r.Time}
+	}, []string{"/api/race/:year/:race_name", "/api/analytics/:year/:race_name"}},
+}
+
+// warmCache loads the data of the most recent and the upcoming Grand Prix
+// into the response cache as each of their sessions ends, so the first
+// visitor afterwards isn't the one who waits for FastF1 to load it.
+// Responses already cached are left alone.
+func (s *Server) warmCache(ctx context.Context) error {
+	year := time.Now().UTC().Year()
+	schedule, err := s.history.Schedule(ctx, year)
+	if err != nil {
+		return err
+	}
+	if !s.cfg.NativeSchedule {
+		s.warm(ctx, "/api/schedule/:year", gin.Params{{Key: "year", Value: strconv.Itoa(year)}})
+	}
+
+	now := time.Now()
+	for _, race := range weekendsAround(schedule, now) {
+		params := gin.Params{
+			{Key: "year", Value: strconv.Itoa(year)},
+			{Key: "race_name", Value: race.Round},
+		}
+		for _, ws := range warmSessions {
+			start, ok := sessionStart(ws.session(race))
+			if !ok || now.Before(start.Add(sessionLength)) {
+				continue
+			}
+			for _, route := range ws.routes {
+				s.warm(ctx, route, params)
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// warm fetches route into the response cache unless it is there already.
+func (s *Server) warm(ctx context.Context, route string, params gin.Params) {
+	ttl := s.cfg.CacheTTLs[route]
+	if ttl <= 0 || ctx.Err() != nil {
+		return
+	}
+	key := upstreamPath(route, params)
+	if _, state := s.cache.Get(ctx, key); state != respcache.Miss {
+		return
+	}
+	s.revalidate(route, requestClass(route), key, ttl)
+}
+
+// weekendsAround returns the last race weekend to have started its race
+// before now and the next one after it, either of which may be missing.
+func weekendsAround(schedule []jolpica.ScheduledRace, now time.Time) []jolpica.ScheduledRace {
+	for i, race := range schedule {
+		if start, ok := sessionStart(&jolpica.Session{Date: race.Date, Time: race.Time}); ok && start.After(now) {
+			if i == 0 {
+				return schedule[:1]
+			}
+			return schedule[i-1 : i+1]
+		}
+	}
+	if len(schedule) == 0 {
+		return nil
+	}
+	return schedule[len(schedule)-1:]
+}
+
+// sessionStart is when a session starts. Sessions without a time are taken
+// to start at the end of their day, so they aren't warmed too early.
+func sessionStart(session *jolpica.Session) (time.Time, bool) {
+	if session == nil {
+		return time.Time{}, false
+	}
+	if session.Time == "" {
+		day, err := time.Parse(time.DateOnly, session.Date)
+		return day.Add(24 * time.Hour), err == nil
+	}
+	start, err := time.Parse(time.RFC3339, session.Date+"T"+session.Time)
+	return start, err == nil
+}
@@ -0,0 +1,21 @@
+package server
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/ekjyotshinh/f1-server/transform"
+)
+
+// weatherSessions are the sessions /api/weather has data for.
+var weatherSessions = []string{"FP1", "FP2", "FP3", "SQ", "S", "Q", "R"}
+
+// weatherSession checks ?session= on /api/weather before the data service
+// loads anything; the response itself is passed through.
+func weatherSession(q url.Values) (transform.Step, error) {
+	if s := q.Get("session"); s != "" && !slices.Contains(weatherSessions, strings.ToUpper(s)) {
+		return nil, &transform.ParamError{Param: "session", Message: "must be one of " + strings.Join(weatherSessions, ", ")}
+	}
+	return nil, nil
+}
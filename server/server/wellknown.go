@@ -0,0 +1,38 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultRobots = "User-agent: *\nDisallow: /api/\n"
+
+// Same "F1" glyph the dashboard uses as its inline favicon
+const defaultFavicon = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100"><text y=".9em" font-size="90" fill="#ff1801">F1</text></svg>`
+
+// wellKnownRoutes answers the paths crawlers and browsers probe on every
+// host. Files of the same name at the root of the static assets win over the
+// built-in defaults.
+func (s *Server) wellKnownRoutes(r gin.IRoutes) {
+	expires := time.Now().AddDate(1, 0, 0).UTC().Format(time.RFC3339)
+	securityTxt := fmt.Sprintf("Contact: %s\nExpires: %s\nPreferred-Languages: en\n", s.cfg.SecurityContact, expires)
+
+	r.GET("/robots.txt", s.wellKnown("robots.txt", "text/plain; charset=utf-8", defaultRobots))
+	r.GET("/favicon.ico", s.wellKnown("favicon.ico", "image/svg+xml", defaultFavicon))
+	if s.cfg.SecurityContact != "" {
+		r.GET("/.well-known/security.txt", s.wellKnown(".well-known/security.txt", "text/plain; charset=utf-8", securityTxt))
+	}
+}
+
+func (s *Server) wellKnown(name, contentType, fallback string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.cfg.StaticFS != nil && serveFile(c, s.cfg.StaticFS, name) {
+			return
+		}
+		c.Header("Cache-Control", "public, max-age=86400")
+		c.Data(http.StatusOK, contentType, []byte(fallback))
+	}
+}
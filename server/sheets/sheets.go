@@ -0,0 +1,119 @@
+// Package sheets writes tables to Google Sheets through the Sheets REST API,
+// authenticated as a service account. The spreadsheet must be shared with
+// the service account's email address.
+package sheets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	baseURL = "https://sheets.googleapis.com/v4/spreadsheets/"
+	scope   = "https://www.googleapis.com/auth/spreadsheets"
+)
+
+// Client writes to spreadsheets.
+type Client struct {
+	http *http.Client
+}
+
+// New creates a Client from a service account key file's contents. Token
+// and API requests go through transport.
+func New(credentials []byte, transport http.RoundTripper) (*Client, error) {
+	cfg, err := google.JWTConfigFromJSON(credentials, scope)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: transport})
+	return &Client{http: &http.Client{
+		Transport: &oauth2.Transport{Source: cfg.TokenSource(ctx), Base: transport},
+	}}, nil
+}
+
+// Replace overwrites the tab of spreadsheet with rows, creating the tab if
+// it doesn't exist yet.
+func (c *Client) Replace(ctx context.Context, spreadsheet, tab string, rows [][]string) error {
+	if err := c.ensureTab(ctx, spreadsheet, tab); err != nil {
+		return err
+	}
+	// Quoted A1 notation, so tab names may contain spaces
+	rng := url.PathEscape("'" + strings.ReplaceAll(tab, "'", "''") + "'")
+	if err := c.do(ctx, http.MethodPost, spreadsheet+"/values/"+rng+":clear", struct{}{}, nil); err != nil {
+		return err
+	}
+	body := struct {
+		Values [][]string `json:"values"`
+	}{rows}
+	// USER_ENTERED parses numbers, as if typed into the sheet
+	return c.do(ctx, http.MethodPut, spreadsheet+"/values/"+rng+"?valueInputOption=USER_ENTERED", body, nil)
+}
+
+func (c *Client) ensureTab(ctx context.Context, spreadsheet, tab string) error {
+	var meta struct {
+		Sheets []struct {
+			Properties struct {
+				Title string `json:"title"`
+			} `json:"properties"`
+		} `json:"sheets"`
+	}
+	if err := c.do(ctx, http.MethodGet, spreadsheet+"?fields=sheets.properties.title", nil, &meta); err != nil {
+		return err
+	}
+	for _, sheet := range meta.Sheets {
+		if sheet.Properties.Title == tab {
+			return nil
+		}
+	}
+	add := map[string]any{"requests": []any{
+		map[string]any{"addSheet": map[string]any{"properties": map[string]string{"title": tab}}},
+	}}
+	return c.do(ctx, http.MethodPost, spreadsheet+":batchUpdate", add, nil)
+}
+
+// do sends a JSON request to the API and decodes the response into out.
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&apiErr)
+		return fmt.Errorf("sheets: %s %s: %d %s", method, path, resp.StatusCode, apiErr.Error.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,109 @@
+// Package sizes tracks upstream payload sizes per route and flags responses
+// that are far smaller than usual, which usually means truncated or partial
+// data from the data service.
+package sizes
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// history is how many recent sizes form a route's norm.
+	history = 50
+	// minSamples is how many sizes a route needs before anything is flagged.
+	minSamples = 10
+)
+
+type route struct {
+	recent    []int // ring buffer of normal sizes
+	next      int
+	last      int
+	total     int64
+	anomalies int64
+	lastFlag  time.Time
+}
+
+// Tracker keeps the recent sizes of each route.
+type Tracker struct {
+	mu     sync.Mutex
+	ratio  float64
+	routes map[string]*route
+}
+
+// NewTracker flags responses smaller than ratio times a route's median size.
+func NewTracker(ratio float64) *Tracker {
+	return &Tracker{ratio: ratio, routes: make(map[string]*route)}
+}
+
+// Observe records a payload of n bytes for route and reports whether it is
+// anomalous. Anomalous sizes don't count towards the norm.
+func (t *Tracker) Observe(routeName string, n int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, ok := t.routes[routeName]
+	if !ok {
+		r = &route{}
+		t.routes[routeName] = r
+	}
+	r.last = n
+	r.total++
+
+	if len(r.recent) >= minSamples && float64(n) < t.ratio*float64(median(r.recent)) {
+		r.anomalies++
+		r.lastFlag = time.Now().UTC()
+		return true
+	}
+	if len(r.recent) < history {
+		r.recent = append(r.recent, n)
+	} else {
+		r.recent[r.next] = n
+		r.next = (r.next + 1) % history
+	}
+	return false
+}
+
+// RouteReport summarises one route.
+type RouteReport struct {
+	Route       string     `json:"route"`
+	Responses   int64      `json:"responses"`
+	MedianBytes int        `json:"median_bytes"`
+	LastBytes   int        `json:"last_bytes"`
+	Anomalies   int64      `json:"anomalies"`
+	LastAnomaly *time.Time `json:"last_anomaly,omitempty"`
+}
+
+// Report lists every route seen, sorted by name.
+func (t *Tracker) Report() []RouteReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]RouteReport, 0, len(t.routes))
+	for name, r := range t.routes {
+		rr := RouteReport{
+			Route:       name,
+			Responses:   r.total,
+			MedianBytes: median(r.recent),
+			LastBytes:   r.last,
+			Anomalies:   r.anomalies,
+		}
+		if !r.lastFlag.IsZero() {
+			at := r.lastFlag
+			rr.LastAnomaly = &at
+		}
+		out = append(out, rr)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Route < out[j].Route })
+	return out
+}
+
+func median(values []int) int {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	return sorted[len(sorted)/2]
+}
@@ -0,0 +1,309 @@
+// Package slo tracks per-route success rates and latency against service level
+// objectives and reports how much of the monthly error budget is left.
+package slo
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Objective is the target a route is measured against. A request is "good"
+// when it does not fail with a 5xx status and completes within Latency.
+type Objective struct {
+	Target  float64       // fraction of good requests, e.g. 0.99
+	Latency time.Duration // slower requests are counted against the budget
+}
+
+type counts struct {
+	total  int64
+	errors int64
+	slow   int64
+}
+
+// Counters keeps counts that outlive the process and are shared by every
+// replica, such as in Redis. Add increments the fields of key, which is kept
+// for keep after; Counts reads them.
+type Counters interface {
+	Add(ctx context.Context, key string, deltas map[string]int64, keep time.Duration) error
+	Counts(ctx context.Context, key string) (map[string]int64, error)
+}
+
+// countersKeep is how long a month's counts are kept after their last update.
+const countersKeep = 62 * 24 * time.Hour
+
+// Tracker accumulates request outcomes for the current calendar month (UTC).
+// With Counters, Flush moves them there, so the month is counted across
+// restarts and replicas; otherwise counting starts over with the process.
+type Tracker struct {
+	mu        sync.Mutex
+	def       Objective
+	overrides map[string]Objective
+	month     time.Time
+	routes    map[string]*counts // this process's, this month
+	pending   map[string]*counts // not yet flushed to store
+	store     Counters           // nil without a shared store
+	started   time.Time
+	now       func() time.Time
+}
+
+// NewTracker creates a Tracker using def for every route without an override.
+// Overrides are keyed by the gin route pattern, e.g. "/api/race/:year/:race_name";
+// zero fields in an override inherit from def. store may be nil.
+func NewTracker(def Objective, overrides map[string]Objective, store Counters) *Tracker {
+	merged := make(map[string]Objective, len(overrides))
+	for route, obj := range overrides {
+		if obj.Target == 0 {
+			obj.Target = def.Target
+		}
+		if obj.Latency == 0 {
+			obj.Latency = def.Latency
+		}
+		merged[route] = obj
+	}
+	t := &Tracker{
+		def:       def,
+		overrides: merged,
+		routes:    make(map[string]*counts),
+		pending:   make(map[string]*counts),
+		store:     store,
+		now:       time.Now,
+	}
+	t.started = t.now().UTC()
+	t.month = monthStart(t.started)
+	return t
+}
+
+// Middleware records the outcome of every request that matched a route.
+func (t *Tracker) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			return // unmatched paths (404s) are not part of any objective
+		}
+		t.Record(route, c.Writer.Status(), time.Since(start))
+	}
+}
+
+// Record adds a single request outcome for route.
+func (t *Tracker) Record(route string, status int, elapsed time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollover()
+	var rc counts
+	rc.total = 1
+	switch {
+	case status >= 500:
+		rc.errors = 1
+	case elapsed > t.objective(route).Latency:
+		rc.slow = 1
+	}
+	add(t.routes, route, rc)
+	if t.store != nil {
+		add(t.pending, route, rc)
+	}
+}
+
+func add(m map[string]*counts, route string, c counts) {
+	rc, ok := m[route]
+	if !ok {
+		rc = &counts{}
+		m[route] = rc
+	}
+	rc.total += c.total
+	rc.errors += c.errors
+	rc.slow += c.slow
+}
+
+// Flush adds the counts recorded since the last Flush to the store. Counts
+// it fails to store are kept for the next try.
+func (t *Tracker) Flush(ctx context.Context) error {
+	if t.store == nil {
+		return nil
+	}
+	t.mu.Lock()
+	t.rollover()
+	month, pending := t.month, t.pending
+	t.pending = make(map[string]*counts)
+	t.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	deltas := make(map[string]int64, 3*len(pending))
+	for route, rc := range pending {
+		deltas["total:"+route] = rc.total
+		deltas["errors:"+route] = rc.errors
+		deltas["slow:"+route] = rc.slow
+	}
+	if err := t.store.Add(ctx, countersKey(month), deltas, countersKeep); err != nil {
+		t.mu.Lock()
+		if t.month.Equal(month) {
+			for route, rc := range pending {
+				add(t.pending, route, *rc)
+			}
+		}
+		t.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// stored reads the month's counts from the store, with what hasn't been
+// flushed yet added.
+func (t *Tracker) stored(ctx context.Context, month time.Time) (map[string]*counts, error) {
+	fields, err := t.store.Counts(ctx, countersKey(month))
+	if err != nil {
+		return nil, err
+	}
+	routes := make(map[string]*counts)
+	for field, n := range fields {
+		kind, route, _ := strings.Cut(field, ":")
+		var c counts
+		switch kind {
+		case "total":
+			c.total = n
+		case "errors":
+			c.errors = n
+		case "slow":
+			c.slow = n
+		default:
+			continue
+		}
+		add(routes, route, c)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.month.Equal(month) {
+		for route, rc := range t.pending {
+			add(routes, route, *rc)
+		}
+	}
+	return routes, nil
+}
+
+func countersKey(month time.Time) string {
+	return "slo:" + month.Format("2006-01")
+}
+
+// RouteReport is the SLO state of a single route for the current window.
+type RouteReport struct {
+	Route             string  `json:"route"`
+	Target            float64 `json:"target"`
+	LatencyTargetMs   int64   `json:"latency_target_ms"`
+	Total             int64   `json:"total"`
+	Errors            int64   `json:"errors"`
+	Slow              int64   `json:"slow"`
+	SuccessRate       float64 `json:"success_rate"`
+	ErrorBudget       float64 `json:"error_budget"`
+	BudgetRemaining   float64 `json:"budget_remaining"`
+	BudgetRemainingPc float64 `json:"budget_remaining_percent"`
+}
+
+// Report is the SLO state of all routes seen in the current window.
+// CountedSince is when counting began, later than WindowStart when only
+// this replica's requests since it started are counted; Scope says which.
+type Report struct {
+	WindowStart  time.Time     `json:"window_start"`
+	WindowEnd    time.Time     `json:"window_end"`
+	CountedSince time.Time     `json:"counted_since"`
+	Scope        string        `json:"scope"`
+	Routes       []RouteReport `json:"routes"`
+}
+
+// Report scopes.
+const (
+	ScopeAll     = "all replicas"
+	ScopeReplica = "this replica since it started"
+)
+
+// Report summarises the current month. Routes are sorted by name. Counts
+// come from the store when there is one; if it can't be read, from this
+// replica.
+func (t *Tracker) Report(ctx context.Context) Report {
+	t.mu.Lock()
+	t.rollover()
+	month := t.month
+	t.mu.Unlock()
+
+	var routes map[string]*counts
+	if t.store != nil {
+		routes, _ = t.stored(ctx, month)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	report := Report{
+		WindowStart:  month,
+		WindowEnd:    month.AddDate(0, 1, 0),
+		CountedSince: month,
+		Scope:        ScopeAll,
+	}
+	if routes == nil {
+		routes = t.routes
+		report.Scope = ScopeReplica
+		if t.started.After(month) {
+			report.CountedSince = t.started
+		}
+	}
+	report.Routes = make([]RouteReport, 0, len(routes))
+	for route, rc := range routes {
+		obj := t.objective(route)
+		bad := rc.errors + rc.slow
+		budget := (1 - obj.Target) * float64(rc.total)
+
+		rr := RouteReport{
+			Route:           route,
+			Target:          obj.Target,
+			LatencyTargetMs: obj.Latency.Milliseconds(),
+			Total:           rc.total,
+			Errors:          rc.errors,
+			Slow:            rc.slow,
+			SuccessRate:     1,
+			ErrorBudget:     budget,
+			BudgetRemaining: budget - float64(bad),
+		}
+		if rc.total > 0 {
+			rr.SuccessRate = float64(rc.total-bad) / float64(rc.total)
+		}
+		if budget > 0 {
+			rr.BudgetRemainingPc = rr.BudgetRemaining / budget * 100
+		} else if bad == 0 {
+			rr.BudgetRemainingPc = 100
+		}
+		report.Routes = append(report.Routes, rr)
+	}
+	sort.Slice(report.Routes, func(i, j int) bool {
+		return report.Routes[i].Route < report.Routes[j].Route
+	})
+	return report
+}
+
+func (t *Tracker) objective(route string) Objective {
+	if obj, ok := t.overrides[route]; ok {
+		return obj
+	}
+	return t.def
+}
+
+// rollover resets the counters when a new month starts. Callers hold t.mu.
+func (t *Tracker) rollover() {
+	if month := monthStart(t.now()); !month.Equal(t.month) {
+		t.month = month
+		t.routes = make(map[string]*counts)
+		t.pending = make(map[string]*counts)
+	}
+}
+
+func monthStart(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,101 @@
+package transform
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// dateLayouts are the timestamp shapes found in responses: RFC 3339 from Go
+// handlers, naive ISO datetimes from pandas, and plain dates from Ergast.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// DateFormat rewrites every date or timestamp string according to
+// ?date_format=iso|unix|relative. iso (the default) leaves them as they are;
+// unix gives epoch seconds and relative gives phrases like "3 days ago".
+// Naive timestamps are taken as UTC.
+func DateFormat(q url.Values) (Step, error) {
+	format := q.Get("date_format")
+	var render func(time.Time) any
+	switch format {
+	case "", "iso":
+		return nil, nil
+	case "unix":
+		render = func(t time.Time) any { return t.Unix() }
+	case "relative":
+		now := time.Now()
+		render = func(t time.Time) any { return relative(now.Sub(t)) }
+	default:
+		return nil, &ParamError{Param: "date_format", Message: "must be iso, unix or relative"}
+	}
+
+	var walk func(v any) any
+	walk = func(v any) any {
+		switch v := v.(type) {
+		case map[string]any:
+			for k, child := range v {
+				v[k] = walk(child)
+			}
+		case []any:
+			for i, child := range v {
+				v[i] = walk(child)
+			}
+		case string:
+			if t, ok := parseDate(v); ok {
+				return render(t)
+			}
+		}
+		return v
+	}
+	return walk, nil
+}
+
+func parseDate(s string) (time.Time, bool) {
+	// Cheap shape check before trying layouts: "YYYY-MM-DD..."
+	if len(s) < 10 || s[4] != '-' || s[7] != '-' {
+		return time.Time{}, false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// relative phrases a duration since now, e.g. "2 hours ago" or "in 5 days".
+func relative(d time.Duration) string {
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	var n int
+	var unit string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		n, unit = int(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		n, unit = int(d/time.Hour), "hour"
+	case d < 30*24*time.Hour:
+		n, unit = int(d/(24*time.Hour)), "day"
+	case d < 365*24*time.Hour:
+		n, unit = int(d/(30*24*time.Hour)), "month"
+	default:
+		n, unit = int(d/(365*24*time.Hour)), "year"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	if future {
+		return fmt.Sprintf("in %d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %s ago", n, unit)
+}
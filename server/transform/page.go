@@ -0,0 +1,97 @@
+package transform
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Page handles ?limit=&offset= on a list endpoint. The list is cut to the
+// page and a "page" object beside it gives the offset, limit and total, so
+// clients know whether to ask for more. Page needs Field to be set.
+type Page struct {
+	Field        string // JSON field holding the list
+	DefaultLimit int
+	MaxLimit     int
+}
+
+// Transform returns the Transform for this paging configuration. Every
+// request is paged, defaulting to the first DefaultLimit items.
+func (p Page) Transform() Transform {
+	return func(q url.Values) (Step, error) {
+		limit, offset := p.DefaultLimit, 0
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > p.MaxLimit {
+				return nil, &ParamError{Param: "limit", Message: "must be a number between 1 and " + strconv.Itoa(p.MaxLimit)}
+			}
+			limit = n
+		}
+		if v := q.Get("offset"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return nil, &ParamError{Param: "offset", Message: "must be a non-negative number"}
+			}
+			offset = n
+		}
+
+		return func(doc any) any {
+			obj, ok := doc.(map[string]any)
+			if !ok {
+				return doc
+			}
+			items, ok := obj[p.Field].([]any)
+			if !ok {
+				return doc
+			}
+			start := min(offset, len(items))
+			end := min(start+limit, len(items))
+			obj[p.Field] = append([]any{}, items[start:end]...)
+			obj["page"] = map[string]any{"offset": offset, "limit": limit, "total": len(items)}
+			return obj
+		}, nil
+	}
+}
+
+// Fields handles ?fields=a,b on a list endpoint, keeping only the named
+// fields of each item. Names must be among Allowed.
+type Fields struct {
+	Field   string // JSON field holding the list; "" for a top-level array
+	Allowed []string
+}
+
+// Transform returns the Transform for this field selection.
+func (f Fields) Transform() Transform {
+	return func(q url.Values) (Step, error) {
+		v := q.Get("fields")
+		if v == "" {
+			return nil, nil
+		}
+		keep := map[string]bool{}
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if !slices.Contains(f.Allowed, name) {
+				return nil, &ParamError{Param: "fields", Message: "must be a list of " + strings.Join(f.Allowed, ", ")}
+			}
+			keep[name] = true
+		}
+
+		return func(doc any) any {
+			items, set := list(doc, f.Field)
+			if items == nil {
+				return doc
+			}
+			for _, item := range items {
+				if m, ok := item.(map[string]any); ok {
+					for k := range m {
+						if !keep[k] {
+							delete(m, k)
+						}
+					}
+				}
+			}
+			return set(items)
+		}, nil
+	}
+}
@@ -0,0 +1,57 @@
+package transform
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Redact removes fields from every response, whatever the query, for
+// deployments that mustn't publish them. A path names a field by its keys
+// from the top of the document, separated by dots; * matches every element
+// of an array or every key of an object. For example results.*.Time drops
+// each result's time, and laps drops the whole lap list.
+func Redact(paths []string) Transform {
+	split := make([][]string, len(paths))
+	for i, p := range paths {
+		split[i] = strings.Split(p, ".")
+	}
+	return func(url.Values) (Step, error) {
+		if len(split) == 0 {
+			return nil, nil
+		}
+		return func(doc any) any {
+			for _, path := range split {
+				doc = redact(doc, path)
+			}
+			return doc
+		}, nil
+	}
+}
+
+func redact(v any, path []string) any {
+	key, rest := path[0], path[1:]
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if key != "*" && k != key {
+				continue
+			}
+			if len(rest) == 0 {
+				delete(v, k)
+			} else {
+				v[k] = redact(child, rest)
+			}
+		}
+	case []any:
+		if key != "*" {
+			return v
+		}
+		if len(rest) == 0 {
+			return []any{}
+		}
+		for i, child := range v {
+			v[i] = redact(child, rest)
+		}
+	}
+	return v
+}
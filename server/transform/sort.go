@@ -0,0 +1,147 @@
+package transform
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Key extracts the value a list item is sorted by; ok is false when missing.
+// Values are compared numerically when both are numbers, otherwise as strings.
+type Key func(item map[string]any) (v any, ok bool)
+
+// Field sorts by the named JSON field.
+func Field(name string) Key {
+	return func(item map[string]any) (any, bool) {
+		v, ok := item[name]
+		return v, ok && v != nil && v != ""
+	}
+}
+
+// Sort handles ?sort=<key>&order=asc|desc on a list endpoint. Items missing the
+// sort key always come last, and ties are broken by Tiebreak keys (ascending)
+// so that repeated requests return the same order.
+type Sort struct {
+	Field    string         // JSON field holding the list; "" for a top-level array
+	Keys     map[string]Key // allowed values of the sort parameter
+	Tiebreak []string
+}
+
+// Transform returns the Transform for this sort configuration.
+func (s Sort) Transform() Transform {
+	return func(q url.Values) (Step, error) {
+		name := q.Get("sort")
+		order := strings.ToLower(q.Get("order"))
+		if name == "" {
+			if order != "" {
+				return nil, &ParamError{Param: "order", Message: "requires a sort parameter"}
+			}
+			return nil, nil
+		}
+
+		key, ok := s.Keys[name]
+		if !ok {
+			return nil, &ParamError{Param: "sort", Message: "must be one of " + strings.Join(s.keyNames(), ", ")}
+		}
+		var desc bool
+		switch order {
+		case "", "asc":
+		case "desc":
+			desc = true
+		default:
+			return nil, &ParamError{Param: "order", Message: "must be asc or desc"}
+		}
+
+		return func(doc any) any {
+			items, set := list(doc, s.Field)
+			if items == nil {
+				return doc
+			}
+			sort.SliceStable(items, func(i, j int) bool {
+				return s.less(items[i], items[j], key, desc)
+			})
+			return set(items)
+		}, nil
+	}
+}
+
+func (s Sort) less(a, b any, key Key, desc bool) bool {
+	am, _ := a.(map[string]any)
+	bm, _ := b.(map[string]any)
+
+	if c := compareKey(am, bm, key); c != 0 {
+		if desc && c != missingLast && c != -missingLast {
+			c = -c
+		}
+		return c < 0
+	}
+	for _, name := range s.Tiebreak {
+		if k, ok := s.Keys[name]; ok {
+			if c := compareKey(am, bm, k); c != 0 {
+				return c < 0
+			}
+		}
+	}
+	return false
+}
+
+func (s Sort) keyNames() []string {
+	names := make([]string, 0, len(s.Keys))
+	for name := range s.Keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// missingLast is returned when only the first item lacks the key; it is never
+// inverted so missing values stay at the end in either order.
+const missingLast = 2
+
+func compareKey(a, b map[string]any, key Key) int {
+	av, aok := key(a)
+	bv, bok := key(b)
+	switch {
+	case !aok && !bok:
+		return 0
+	case !aok:
+		return missingLast
+	case !bok:
+		return -missingLast
+	}
+
+	if af, ok := number(av); ok {
+		if bf, ok := number(bv); ok {
+			switch {
+			case af < bf:
+				return -1
+			case af > bf:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(strings.ToLower(toString(av)), strings.ToLower(toString(bv)))
+}
+
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func toString(v any) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case json.Number:
+		return s.String()
+	}
+	return ""
+}
@@ -0,0 +1,100 @@
+// Package transform rewrites proxied JSON responses according to query
+// parameters (sorting, filtering, formatting) before they reach the client.
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/ekjyotshinh/f1-server/jsoncodec"
+)
+
+// A Step rewrites a decoded JSON document and returns the result.
+type Step func(doc any) any
+
+// A Transform inspects the request query and returns the Step to apply to the
+// response, or nil when the request doesn't ask for it. Parameter errors are
+// reported before the upstream is called.
+type Transform func(q url.Values) (Step, error)
+
+// ParamError reports an invalid query parameter.
+type ParamError struct {
+	Param   string
+	Message string
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("invalid %s parameter: %s", e.Param, e.Message)
+}
+
+// Parse runs each transform against q and collects the steps requested.
+func Parse(q url.Values, transforms ...Transform) ([]Step, error) {
+	var steps []Step
+	for _, t := range transforms {
+		step, err := t(q)
+		if err != nil {
+			return nil, err
+		}
+		if step != nil {
+			steps = append(steps, step)
+		}
+	}
+	return steps, nil
+}
+
+// Apply decodes body, runs steps in order, and re-encodes the document.
+// Numbers keep their original representation.
+func Apply(body []byte, steps []Step) ([]byte, error) {
+	doc, err := Run(body, steps)
+	if err != nil {
+		return nil, err
+	}
+	if raw, ok := doc.(json.RawMessage); ok {
+		return raw, nil
+	}
+	return jsoncodec.Default.Marshal(doc)
+}
+
+// Run is Apply without the encoding, for documents too large to encode in
+// one piece: the result is for Write. Without steps it is body untouched.
+func Run(body []byte, steps []Step) (any, error) {
+	if len(steps) == 0 {
+		return json.RawMessage(body), nil
+	}
+	doc, err := jsoncodec.Default.UnmarshalNumber(body)
+	if err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	for _, step := range steps {
+		doc = step(doc)
+	}
+	return doc, nil
+}
+
+// Write encodes a document from Run to w, streaming its long arrays.
+func Write(w io.Writer, doc any) error {
+	if raw, ok := doc.(json.RawMessage); ok {
+		_, err := w.Write(raw)
+		return err
+	}
+	return jsoncodec.Encode(jsoncodec.Default, w, doc)
+}
+
+// list returns the array stored under field, or doc itself when field is "".
+func list(doc any, field string) ([]any, func([]any) any) {
+	if field == "" {
+		items, _ := doc.([]any)
+		return items, func(items []any) any { return items }
+	}
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return nil, func([]any) any { return doc }
+	}
+	items, _ := obj[field].([]any)
+	return items, func(items []any) any {
+		obj[field] = items
+		return obj
+	}
+}
@@ -0,0 +1,81 @@
+package transform_test
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ekjyotshinh/f1-server/jsoncodec"
+	"github.com/ekjyotshinh/f1-server/transform"
+)
+
+// laps is a driver's race in the shape of /api/laps.
+func laps(n int) []byte {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"LapNumber":%d,"LapTime":"0 days 00:01:%02d.%03d000","Sector1Time":"0 days 00:00:28.512000",`+
+			`"Sector2Time":"0 days 00:00:31.004000","Sector3Time":"0 days 00:00:27.387000","Compound":"MEDIUM",`+
+			`"TyreLife":%d.0,"Stint":%d.0,"Position":%d.0,"PitIn":null,"PitOut":null}`,
+			i+1, 26+i%7, i*37%1000, i%25+1, i/25+1, i%20+1)
+	}
+	return []byte(`{"driver":"VER","laps":[` + strings.Join(items, ",") + `]}`)
+}
+
+var (
+	lapsSort = transform.Sort{
+		Field: "laps",
+		Keys: map[string]transform.Key{
+			"lap":     transform.Field("LapNumber"),
+			"laptime": transform.Field("LapTime"),
+		},
+		Tiebreak: []string{"lap"},
+	}
+	lapsFields = transform.Fields{Field: "laps", Allowed: []string{"LapNumber", "LapTime", "Compound"}}
+	lapsPage   = transform.Page{Field: "laps", DefaultLimit: 20, MaxLimit: 100}
+)
+
+// BenchmarkTransform runs a request for a page of the fastest laps from
+// query string to encoded response.
+func BenchmarkTransform(b *testing.B) {
+	body := laps(70)
+	q, _ := url.ParseQuery("sort=laptime&fields=LapNumber,LapTime&limit=10")
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	for range b.N {
+		steps, err := transform.Parse(q, lapsSort.Transform(), lapsFields.Transform(), lapsPage.Transform())
+		if err != nil {
+			b.Fatal(err)
+		}
+		doc, err := transform.Run(body, steps)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := transform.Write(io.Discard, doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSort sorts a decoded document, without the decoding.
+func BenchmarkSort(b *testing.B) {
+	body := laps(70)
+	q, _ := url.ParseQuery("sort=laptime&order=desc")
+	steps, err := transform.Parse(q, lapsSort.Transform())
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for range b.N {
+		b.StopTimer()
+		doc, err := jsoncodec.Default.UnmarshalNumber(body)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		for _, step := range steps {
+			doc = step(doc)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package transform
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// YAMLTypes are the media types accepted as a request for YAML output.
+var YAMLTypes = []string{"application/yaml", "application/x-yaml", "text/yaml"}
+
+// PrefersYAML reports whether an Accept header ranks a YAML type above JSON.
+func PrefersYAML(accept string) bool {
+	var yamlQ, jsonQ float64 = -1, -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		switch {
+		case mediaType == "application/json" || mediaType == "*/*" || mediaType == "application/*":
+			jsonQ = max(jsonQ, q)
+		case isYAML(mediaType):
+			yamlQ = max(yamlQ, q)
+		}
+	}
+	return yamlQ > 0 && yamlQ >= jsonQ
+}
+
+func isYAML(mediaType string) bool {
+	for _, t := range YAMLTypes {
+		if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// YAML converts a JSON document to YAML, keeping object keys in order.
+func YAML(body []byte) ([]byte, error) {
+	return yaml.JSONToYAML(body)
+}
@@ -0,0 +1,148 @@
+package upstream
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BreakerPolicy opens a host's circuit after Failures failed requests in a
+// row. While open, requests fail at once; after Cooldown one request is let
+// through to see whether the host has recovered. Failures <= 0 disables the
+// breaker.
+type BreakerPolicy struct {
+	Failures int
+	Cooldown time.Duration
+}
+
+// CircuitOpenError is returned instead of calling a host whose circuit is
+// open.
+type CircuitOpenError struct {
+	Host string
+	// RetryAfter is when the next probe of the host will be allowed.
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit open for %s, retry in %s", e.Host, e.RetryAfter.Round(time.Second))
+}
+
+type circuit struct {
+	failures int
+	open     bool
+	openedAt time.Time
+	probing  bool // a half-open probe is in flight
+}
+
+// Breaker is an http.RoundTripper that stops calling hosts that keep
+// failing. A failure is a connection error or a 502, 503 or 504 response;
+// other statuses mean the host is up.
+type Breaker struct {
+	policy BreakerPolicy
+	base   http.RoundTripper
+
+	mu    sync.Mutex
+	hosts map[string]*circuit
+	now   func() time.Time
+}
+
+// NewBreaker creates a Breaker in front of base.
+func NewBreaker(p BreakerPolicy, base http.RoundTripper) *Breaker {
+	return &Breaker{policy: p, base: base, hosts: make(map[string]*circuit), now: time.Now}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (b *Breaker) RoundTrip(req *http.Request) (*http.Response, error) {
+	if b.policy.Failures <= 0 {
+		return b.base.RoundTrip(req)
+	}
+	host := req.URL.Host
+	if err := b.allow(host); err != nil {
+		return nil, err
+	}
+	resp, err := b.base.RoundTrip(req)
+	// A caller giving up says nothing about the host
+	b.record(host, retryable(resp, err), req.Context().Err() != nil)
+	return resp, err
+}
+
+// allow admits a request to host, or one probe once the cooldown is over.
+func (b *Breaker) allow(host string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(host)
+	if !c.open {
+		return nil
+	}
+	if wait := c.openedAt.Add(b.policy.Cooldown).Sub(b.now()); wait > 0 || c.probing {
+		return &CircuitOpenError{Host: host, RetryAfter: max(wait, time.Second)}
+	}
+	c.probing = true
+	return nil
+}
+
+func (b *Breaker) record(host string, failed, cancelled bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(host)
+	wasProbe := c.probing
+	c.probing = false
+	switch {
+	case cancelled:
+	case !failed:
+		if c.open {
+			log.Printf("upstream: circuit for %s closed", host)
+		}
+		*c = circuit{}
+	case wasProbe:
+		c.openedAt = b.now()
+	default:
+		c.failures++
+		if !c.open && c.failures >= b.policy.Failures {
+			log.Printf("upstream: circuit for %s opened after %d failures", host, c.failures)
+			c.open, c.openedAt = true, b.now()
+		}
+	}
+}
+
+func (b *Breaker) circuit(host string) *circuit {
+	c, ok := b.hosts[host]
+	if !ok {
+		c = &circuit{}
+		b.hosts[host] = c
+	}
+	return c
+}
+
+// BreakerStatus describes one host's circuit for the admin API.
+type BreakerStatus struct {
+	Host     string     `json:"host"`
+	State    string     `json:"state"` // closed, open or half-open
+	Failures int        `json:"consecutive_failures"`
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+}
+
+// Status reports every host called so far.
+func (b *Breaker) Status() []BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := make([]BreakerStatus, 0, len(b.hosts))
+	for host, c := range b.hosts {
+		st := BreakerStatus{Host: host, State: "closed", Failures: c.failures}
+		if c.open {
+			st.State = "open"
+			if !b.now().Before(c.openedAt.Add(b.policy.Cooldown)) {
+				st.State = "half-open"
+			}
+			at := c.openedAt
+			st.OpenedAt = &at
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Host < out[j].Host })
+	return out
+}
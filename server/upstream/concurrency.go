@@ -0,0 +1,112 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ConcurrencyPolicy caps the calls in flight to each data service endpoint,
+// keyed by gateway route. Routes without a limit aren't capped. Calls over a
+// limit wait their turn: at most QueueSize of them per route, and for at most
+// QueueTimeout each.
+type ConcurrencyPolicy struct {
+	Limits       map[string]int
+	QueueSize    int
+	QueueTimeout time.Duration
+}
+
+// ErrQueueFull is returned when too many calls are already waiting for a
+// route, and ErrQueueTimeout when a call waited too long.
+var (
+	ErrQueueFull    = errors.New("upstream: too many calls waiting for this endpoint")
+	ErrQueueTimeout = errors.New("upstream: timed out waiting for a free slot for this endpoint")
+)
+
+type slots struct {
+	sem    chan struct{}
+	queued int
+}
+
+// Limiter holds the slots of each capped route.
+type Limiter struct {
+	policy ConcurrencyPolicy
+
+	mu     sync.Mutex
+	routes map[string]*slots
+}
+
+// NewLimiter creates a Limiter with a slot per allowed call of each route.
+func NewLimiter(p ConcurrencyPolicy) *Limiter {
+	l := &Limiter{policy: p, routes: make(map[string]*slots)}
+	for route, n := range p.Limits {
+		if n > 0 {
+			l.routes[route] = &slots{sem: make(chan struct{}, n)}
+		}
+	}
+	return l
+}
+
+// Acquire waits for a slot to call route and returns the function that
+// frees it. queued is called when the call has to wait, and again with false
+// when it stops waiting, so callers can count the queue.
+func (l *Limiter) Acquire(ctx context.Context, route string, queued func(bool)) (func(), error) {
+	s, ok := l.routes[route]
+	if !ok {
+		return func() {}, nil
+	}
+	release := func() { <-s.sem }
+	select {
+	case s.sem <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	l.mu.Lock()
+	if s.queued >= l.policy.QueueSize {
+		l.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+	s.queued++
+	l.mu.Unlock()
+	queued(true)
+	defer func() {
+		l.mu.Lock()
+		s.queued--
+		l.mu.Unlock()
+		queued(false)
+	}()
+
+	timer := time.NewTimer(l.policy.QueueTimeout)
+	defer timer.Stop()
+	select {
+	case s.sem <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, ErrQueueTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// LimitStatus is a route's slots for the admin API.
+type LimitStatus struct {
+	Route    string `json:"route"`
+	Limit    int    `json:"limit"`
+	InFlight int    `json:"in_flight"`
+	Queued   int    `json:"queued"`
+}
+
+// Status lists the capped routes.
+func (l *Limiter) Status() []LimitStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]LimitStatus, 0, len(l.routes))
+	for route, s := range l.routes {
+		out = append(out, LimitStatus{Route: route, Limit: cap(s.sem), InFlight: len(s.sem), Queued: s.queued})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Route < out[j].Route })
+	return out
+}
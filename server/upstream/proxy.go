@@ -0,0 +1,28 @@
+package upstream
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ProxyFunc picks the egress proxy for upstream requests. By default it
+// follows HTTPS_PROXY, HTTP_PROXY and NO_PROXY like any Go client; an explicit
+// proxy URL takes the place of the first two while NO_PROXY still applies.
+func ProxyFunc(explicit string) (func(*http.Request) (*url.URL, error), error) {
+	cfg := httpproxy.FromEnvironment()
+	if explicit != "" {
+		u, err := url.Parse(explicit)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid outbound proxy %q", explicit)
+		}
+		cfg.HTTPProxy = explicit
+		cfg.HTTPSProxy = explicit
+	}
+	proxy := cfg.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}, nil
+}
@@ -0,0 +1,88 @@
+package upstream
+
+import (
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy retries idempotent requests that failed in ways that are
+// usually transient: connection errors, and 502, 503 and 504 from the
+// platform's edge while an instance restarts.
+type RetryPolicy struct {
+	// Attempts counts the first try; 1 or less disables retries.
+	Attempts int
+	// Backoff is the wait before the first retry, doubling for each one
+	// after up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Jitter is the fraction of each wait that is randomized, 0 to 1, so
+	// replicas don't retry in lockstep.
+	Jitter float64
+}
+
+// Wrap returns base retrying under p.
+func (p RetryPolicy) Wrap(base http.RoundTripper) http.RoundTripper {
+	if p.Attempts <= 1 {
+		return base
+	}
+	return &retryTransport{policy: p, base: base}
+}
+
+// wait is the backoff before retry n (from 1).
+func (p RetryPolicy) wait(n int) time.Duration {
+	d := p.Backoff << (n - 1)
+	if p.MaxBackoff > 0 && (d > p.MaxBackoff || d <= 0) {
+		d = p.MaxBackoff
+	}
+	return d - time.Duration(p.Jitter*rand.Float64()*float64(d))
+}
+
+type retryTransport struct {
+	policy RetryPolicy
+	base   http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Only bodiless GETs and HEADs can be sent again as they are
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead
+	if !idempotent || (req.Body != nil && req.Body != http.NoBody) {
+		return t.base.RoundTrip(req)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.policy.Attempts || req.Context().Err() != nil || !retryable(resp, err) {
+			return resp, err
+		}
+		reason := "connection error"
+		if err == nil {
+			reason = resp.Status
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+			resp.Body.Close()
+		}
+		wait := t.policy.wait(attempt)
+		log.Printf("upstream: %s %s: %s, retrying in %s", req.Method, req.URL.Path, reason, wait.Round(time.Millisecond))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+// Package upstream chooses which deployment of the data service serves a
+// request when it runs in more than one region.
+package upstream
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Region is one deployment of the data service.
+type Region struct {
+	Name string
+	URL  string
+}
+
+// smoothing is the weight of the newest sample in a latency average.
+const smoothing = 0.3
+
+type regionState struct {
+	Region
+	healthy bool
+	probeMs float64            // from the latest health probe
+	classMs map[string]float64 // moving average per request class
+	checked time.Time
+}
+
+// Router picks the fastest healthy region for each class of request (e.g.
+// "data" or "telemetry"), unless the class is pinned to a region.
+type Router struct {
+	mu      sync.Mutex
+	regions []*regionState
+	pins    map[string]string
+}
+
+// NewRouter creates a Router. All regions start healthy; pins map a request
+// class to the region name that always serves it.
+func NewRouter(regions []Region, pins map[string]string) *Router {
+	r := &Router{pins: pins}
+	for _, region := range regions {
+		r.regions = append(r.regions, &regionState{
+			Region:  region,
+			healthy: true,
+			classMs: make(map[string]float64),
+		})
+	}
+	return r
+}
+
+// Regions returns the configured regions.
+func (r *Router) Regions() []Region {
+	out := make([]Region, len(r.regions))
+	for i, st := range r.regions {
+		out[i] = st.Region
+	}
+	return out
+}
+
+// Pick returns the region to use for class. Regions without real requests
+// of that class yet are judged by their probe latency.
+func (r *Router) Pick(class string) Region {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if name, ok := r.pins[class]; ok {
+		for _, st := range r.regions {
+			if st.Name == name {
+				return st.Region
+			}
+		}
+	}
+
+	var best *regionState
+	bestMs := 0.0
+	for _, st := range r.regions {
+		if !st.healthy {
+			continue
+		}
+		ms, ok := st.classMs[class]
+		if !ok {
+			ms = st.probeMs
+		}
+		if best == nil || ms < bestMs {
+			best, bestMs = st, ms
+		}
+	}
+	if best == nil {
+		// Everything looks down; the first region is as good a guess as any
+		return r.regions[0].Region
+	}
+	return best.Region
+}
+
+// Observe records how long a real request of class took in region.
+func (r *Router) Observe(class, region string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, st := range r.regions {
+		if st.Name != region {
+			continue
+		}
+		ms := float64(d.Microseconds()) / 1000
+		if prev, ok := st.classMs[class]; ok {
+			ms = prev + smoothing*(ms-prev)
+		}
+		st.classMs[class] = ms
+	}
+}
+
+// Probe checks every region's health by requesting path on it, giving each
+// region up to timeout to answer.
+func (r *Router) Probe(ctx context.Context, client *http.Client, path string, timeout time.Duration) error {
+	for _, region := range r.Regions() {
+		if err := r.probe(ctx, client, region, path, timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Router) probe(ctx context.Context, client *http.Client, region Region, path string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	healthy := false
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, region.URL+path, nil)
+	if err != nil {
+		return err
+	}
+	if resp, err := client.Do(req); err == nil {
+		resp.Body.Close()
+		healthy = resp.StatusCode == http.StatusOK
+	}
+	elapsed := time.Since(start)
+
+	r.mu.Lock()
+	for _, st := range r.regions {
+		if st.Name == region.Name {
+			st.healthy = healthy
+			st.probeMs = float64(elapsed.Microseconds()) / 1000
+			st.checked = time.Now().UTC()
+		}
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+// RegionStatus describes a region for the admin API.
+type RegionStatus struct {
+	Name      string             `json:"name"`
+	URL       string             `json:"url"`
+	Healthy   bool               `json:"healthy"`
+	ProbeMs   float64            `json:"probe_ms"`
+	ClassMs   map[string]float64 `json:"class_ms"`
+	CheckedAt time.Time          `json:"checked_at"`
+	PinnedFor []string           `json:"pinned_for,omitempty"`
+}
+
+// Status reports every region.
+func (r *Router) Status() []RegionStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]RegionStatus, 0, len(r.regions))
+	for _, st := range r.regions {
+		rs := RegionStatus{
+			Name:      st.Name,
+			URL:       st.URL,
+			Healthy:   st.healthy,
+			ProbeMs:   st.probeMs,
+			ClassMs:   make(map[string]float64, len(st.classMs)),
+			CheckedAt: st.checked,
+		}
+		for class, ms := range st.classMs {
+			rs.ClassMs[class] = ms
+		}
+		for class, name := range r.pins {
+			if name == st.Name {
+				rs.PinnedFor = append(rs.PinnedFor, class)
+			}
+		}
+		sort.Strings(rs.PinnedFor)
+		out = append(out, rs)
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Transport is an http.RoundTripper that keeps upstream connections fresh.
+// Railway can move a service behind the same hostname, leaving pooled
+// connections pointed at a dead instance, so Transport starts over with a
+// new connection pool (and so new DNS lookups) when the pool gets old, when
+// the hostname resolves somewhere else, or after consecutive failures.
+type Transport struct {
+	maxAge      time.Duration
+	maxFailures int
+	base        *http.Transport
+
+	mu       sync.Mutex
+	current  *http.Transport
+	born     time.Time
+	failures int
+	addrs    map[string][]string // host -> sorted addresses at last check
+	resets   int
+}
+
+// NewTransport creates a Transport whose pool is replaced after maxAge and
+// after maxFailures failures in a row. Zero disables either trigger. Each
+// pool is a clone of base (proxy, TLS and timeout settings); nil uses
+// http.DefaultTransport.
+func NewTransport(maxAge time.Duration, maxFailures int, base *http.Transport) *Transport {
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	return &Transport{
+		maxAge:      maxAge,
+		maxFailures: maxFailures,
+		base:        base,
+		current:     base.Clone(),
+		born:        time.Now(),
+		addrs:       make(map[string][]string),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	if t.maxAge > 0 && time.Since(t.born) > t.maxAge {
+		t.resetLocked("connection max age reached")
+	}
+	pool := t.current
+	t.mu.Unlock()
+
+	resp, err := pool.RoundTrip(req)
+	failed := err != nil && !errors.Is(err, context.Canceled)
+	if err == nil {
+		switch resp.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			failed = true // the platform's edge couldn't reach the instance
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !failed {
+		t.failures = 0
+	} else if t.failures++; t.maxFailures > 0 && t.failures >= t.maxFailures && pool == t.current {
+		t.resetLocked("consecutive upstream failures")
+	}
+	return resp, err
+}
+
+// Reset replaces the connection pool. In-flight requests finish on the old one.
+func (t *Transport) Reset(reason string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.resetLocked(reason)
+}
+
+func (t *Transport) resetLocked(reason string) {
+	old := t.current
+	t.current = t.base.Clone()
+	t.born = time.Now()
+	t.failures = 0
+	t.resets++
+	old.CloseIdleConnections()
+	log.Printf("upstream: reset connection pool: %s", reason)
+}
+
+// CheckDNS resolves the hosts of baseURLs and resets the pool if any of them
+// now points somewhere new.
+func (t *Transport) CheckDNS(ctx context.Context, baseURLs []string) error {
+	changed := false
+	for _, base := range baseURLs {
+		u, err := url.Parse(base)
+		if err != nil {
+			return err
+		}
+		host := u.Hostname()
+		if net.ParseIP(host) != nil {
+			continue
+		}
+		addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+		if err != nil {
+			return err
+		}
+		slices.Sort(addrs)
+
+		t.mu.Lock()
+		prev, seen := t.addrs[host]
+		t.addrs[host] = addrs
+		t.mu.Unlock()
+		if seen && !slices.Equal(prev, addrs) {
+			log.Printf("upstream: %s moved from %v to %v", host, prev, addrs)
+			changed = true
+		}
+	}
+	if changed {
+		t.Reset("DNS change")
+	}
+	return nil
+}
+
+// Resets reports how many times the pool has been replaced.
+func (t *Transport) Resets() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.resets
+}